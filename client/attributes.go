@@ -0,0 +1,104 @@
+package client
+
+import (
+	"log"
+	"math"
+	"sort"
+	"syncServerDemo/gamesync"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+)
+
+// maxMovementPhases 每个玩家保留的移动属性段数
+const maxMovementPhases = 16
+
+// movementPhase 从指定游戏时间开始生效的移动属性
+type movementPhase struct {
+	from  int64
+	model gamesync.MovementModel
+}
+
+// setPlayerMovement 记录玩家从指定游戏时间开始使用的移动属性（调用方需持有 c.mu 写锁）
+// 玩家的第一段属性对之前的所有时间生效
+func (c *GameClient) setPlayerMovement(player *LocalPlayerState, params protocol.MovementParams, from int64) error {
+	model, err := gamesync.NewMovementModel(params)
+	if err != nil {
+		return err
+	}
+
+	if len(player.movement) == 0 {
+		from = math.MinInt64
+	}
+	phase := movementPhase{from: from, model: model}
+
+	i := sort.Search(len(player.movement), func(i int) bool {
+		return player.movement[i].from >= from
+	})
+	if i < len(player.movement) && player.movement[i].from == from {
+		player.movement[i] = phase
+		return nil
+	}
+	player.movement = append(player.movement, movementPhase{})
+	copy(player.movement[i+1:], player.movement[i:])
+	player.movement[i] = phase
+
+	// 超出上限时最早的几段合并为对之前所有时间生效的基础段，取其中最后一段的属性，
+	// 保留段之前紧邻的时间仍然使用正确的属性，而不是退回服务器的默认属性
+	if len(player.movement) > maxMovementPhases {
+		over := len(player.movement) - maxMovementPhases + 1
+		base := movementPhase{from: math.MinInt64, model: player.movement[over-1].model}
+		player.movement = append(player.movement[:1], player.movement[over:]...)
+		player.movement[0] = base
+	}
+	return nil
+}
+
+// modelAt 获取玩家在指定游戏时间使用的移动模型（调用方需持有 c.mu）
+func (c *GameClient) modelAt(player *LocalPlayerState, gameTime int64) gamesync.MovementModel {
+	model := c.movement
+	for _, phase := range player.movement {
+		if phase.from > gameTime {
+			break
+		}
+		model = phase.model
+	}
+	return model
+}
+
+// handleAttributes 处理玩家属性变化，在指定的游戏时间生效
+func (c *GameClient) handleAttributes(msg transport.Message) {
+	data, err := c.parseData(msg, &protocol.AttributesData{})
+	if err != nil {
+		return
+	}
+
+	attrData := data.(*protocol.AttributesData)
+
+	c.mu.Lock()
+	player, exists := c.localPlayers[attrData.PlayerID]
+	if !exists {
+		c.mu.Unlock()
+		return
+	}
+	err = c.setPlayerMovement(player, attrData.Movement, attrData.GameTime)
+	c.mu.Unlock()
+
+	if err != nil {
+		log.Printf("[Client %s] Invalid movement attributes for %s: %v", c.clientID, attrData.PlayerID, err)
+		return
+	}
+	log.Printf("[Client %s] Player %s movement set to %s (max speed %.2f) at game time %d",
+		c.clientID, attrData.PlayerID, attrData.Movement.Model, attrData.Movement.MaxSpeed, attrData.GameTime)
+}
+
+// GetPlayerMovement 获取玩家当前生效的移动属性
+func (c *GameClient) GetPlayerMovement(playerID string) (protocol.MovementParams, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	player, exists := c.localPlayers[playerID]
+	if !exists {
+		return protocol.MovementParams{}, false
+	}
+	return c.modelAt(player, c.timeSyncer.GetGameTime()).Params(), true
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"math"
+	"syncServerDemo/protocol"
+	"testing"
+)
+
+func TestMovementPhasesTrimmed(t *testing.T) {
+	c, _ := newTestClient(t)
+	player := &LocalPlayerState{PlayerID: "bob"}
+
+	// 20 次属性变化，第 i 次在 i*100 生效，最大速度为 i+1
+	for i := 0; i < 20; i++ {
+		params := protocol.MovementParams{Model: protocol.MovementModelInstant, MaxSpeed: float64(i + 1)}
+		if err := c.setPlayerMovement(player, params, int64(i*100)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(player.movement) != maxMovementPhases {
+		t.Fatalf("%d phases, want %d", len(player.movement), maxMovementPhases)
+	}
+	if player.movement[0].from != math.MinInt64 {
+		t.Errorf("first phase starts at %d, want the base phase", player.movement[0].from)
+	}
+
+	tests := []struct {
+		gameTime int64
+		want     float64
+	}{
+		{-1000, 5}, // 早于所有保留段，使用合并后的基础段
+		{450, 5},   // 第 4 段（400 开始）仍然生效
+		{500, 6},
+		{1950, 20},
+	}
+	for _, tt := range tests {
+		if got := c.modelAt(player, tt.gameTime).Params().MaxSpeed; got != tt.want {
+			t.Errorf("max speed at %d = %v, want %v", tt.gameTime, got, tt.want)
+		}
+	}
+}
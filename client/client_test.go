@@ -0,0 +1,56 @@
+package client
+
+import (
+	"io"
+	"log"
+	"os"
+	"syncServerDemo/gamesync"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// 客户端日志在测试中没有意义，失败信息由断言给出
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testEpoch 测试时钟的起点
+var testEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestClient 创建使用手动时钟、不启动后台循环的客户端，消息直接交给 handleMessage 处理
+func newTestClient(t *testing.T, opts ...Option) (*GameClient, *gamesync.ManualClock) {
+	t.Helper()
+	clock := gamesync.NewManualClock(testEpoch)
+	lt := transport.NewLocalTransport()
+	t.Cleanup(func() { lt.Close() })
+	lt.Register("alice")
+	opts = append([]Option{WithClock(clock)}, opts...)
+	c := NewGameClient("alice", "alice", lt, opts...)
+	t.Cleanup(c.Stop) // 结束事件协程
+	return c, clock
+}
+
+// instantMovement 速度立即变为目标速度的移动模型，便于计算期望位置
+var instantMovement = protocol.MovementParams{Model: protocol.MovementModelInstant, MaxSpeed: 10}
+
+// welcome 投递欢迎消息，players 为各玩家在 gameTime 的位置
+func welcome(c *GameClient, gameTime int64, players map[string][2]float64) {
+	data := protocol.WelcomeData{PlayerID: c.playerID, RoomID: "room", GameTime: gameTime, Movement: instantMovement}
+	for playerID, pos := range players {
+		data.Players = append(data.Players, playerID)
+		data.Positions = append(data.Positions, protocol.PositionData{PlayerID: playerID, X: pos[0], Y: pos[1], GameTime: gameTime})
+	}
+	c.handleMessage(transport.NewMessage(protocol.MsgTypeWelcome, data))
+}
+
+// positionBatch 投递一轮仲裁结果，positions 为各玩家在 gameTime 的仲裁位置
+func positionBatch(c *GameClient, gameTime int64, positions map[string][2]float64) {
+	data := protocol.PositionBatchData{GameTime: gameTime}
+	for playerID, pos := range positions {
+		data.Positions = append(data.Positions, protocol.PositionUpdateData{PlayerID: playerID, X: pos[0], Y: pos[1], GameTime: gameTime})
+	}
+	c.handleMessage(transport.NewMessage(protocol.MsgTypePositionBatch, data))
+}
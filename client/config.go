@@ -0,0 +1,156 @@
+package client
+
+import (
+	"fmt"
+	"log"
+	"syncServerDemo/gamesync"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"time"
+)
+
+// Config 客户端配置
+type Config struct {
+	ReportInterval      time.Duration // 位置上报间隔，加入后以服务器下发的同步参数为准
+	TimeSyncThreshold   time.Duration // 本地时间与服务器时间相差超过该值才校正
+	CorrectionThreshold float64       // 模拟位置与仲裁结果相差超过该值才校正
+
+	Clock gamesync.Clock // 时间来源，为空时使用系统时钟；只在创建时生效
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() Config {
+	return Config{
+		ReportInterval:      200 * time.Millisecond,
+		TimeSyncThreshold:   100 * time.Millisecond,
+		CorrectionThreshold: 0.5,
+	}
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	if c.ReportInterval <= 0 {
+		return fmt.Errorf("report interval must be positive, got %v", c.ReportInterval)
+	}
+	if c.TimeSyncThreshold < 0 {
+		return fmt.Errorf("time sync threshold must not be negative, got %v", c.TimeSyncThreshold)
+	}
+	if c.CorrectionThreshold < 0 {
+		return fmt.Errorf("correction threshold must not be negative, got %v", c.CorrectionThreshold)
+	}
+	return nil
+}
+
+// Option 客户端配置项
+type Option func(*Config)
+
+// WithConfig 使用完整配置替换当前配置
+func WithConfig(cfg Config) Option {
+	return func(c *Config) {
+		*c = cfg
+	}
+}
+
+// WithReportInterval 设置加入前的位置上报间隔
+func WithReportInterval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.ReportInterval = interval
+	}
+}
+
+// WithTimeSyncThreshold 设置时间校正阈值
+func WithTimeSyncThreshold(threshold time.Duration) Option {
+	return func(c *Config) {
+		c.TimeSyncThreshold = threshold
+	}
+}
+
+// WithCorrectionThreshold 设置位置校正阈值
+func WithCorrectionThreshold(threshold float64) Option {
+	return func(c *Config) {
+		c.CorrectionThreshold = threshold
+	}
+}
+
+// WithClock 设置时间来源，用于模拟和测试
+func WithClock(clock gamesync.Clock) Option {
+	return func(c *Config) {
+		c.Clock = clock
+	}
+}
+
+// UpdateConfig 在运行时修改配置，校验失败时保持原配置不变
+func (c *GameClient) UpdateConfig(opts ...Option) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cfg := c.config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.Clock = c.config.Clock
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	c.config = cfg
+	c.notifyConfigUpdated()
+	return nil
+}
+
+// Config 获取当前配置
+func (c *GameClient) Config() Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+// GetSyncParams 获取服务器下发的同步参数，加入前为零值
+func (c *GameClient) GetSyncParams() protocol.SyncParams {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.syncParams
+}
+
+// setSyncParams 记录服务器下发的同步参数（调用方需持有 c.mu 写锁）
+func (c *GameClient) setSyncParams(params protocol.SyncParams) {
+	if params == c.syncParams {
+		return
+	}
+	c.syncParams = params
+	c.notifyConfigUpdated()
+}
+
+// notifyConfigUpdated 唤醒上报循环重新读取间隔（调用方需持有 c.mu 写锁）
+func (c *GameClient) notifyConfigUpdated() {
+	close(c.configUpdated)
+	c.configUpdated = make(chan struct{})
+}
+
+// reportInterval 当前的上报间隔和配置变化通知
+// 服务器下发了上报间隔时以服务器为准，保证每轮仲裁都能收到上报
+func (c *GameClient) reportInterval() (time.Duration, <-chan struct{}) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	interval := c.config.ReportInterval
+	if c.syncParams.ReportInterval > 0 {
+		interval = time.Duration(c.syncParams.ReportInterval) * time.Millisecond
+	}
+	return interval, c.configUpdated
+}
+
+// handleSyncParams 处理同步参数变化
+func (c *GameClient) handleSyncParams(msg transport.Message) {
+	data, err := c.parseData(msg, &protocol.SyncParams{})
+	if err != nil {
+		return
+	}
+
+	params := data.(*protocol.SyncParams)
+
+	c.mu.Lock()
+	c.setSyncParams(*params)
+	c.mu.Unlock()
+
+	log.Printf("[Client %s] Sync params updated: report interval %dms", c.clientID, params.ReportInterval)
+}
@@ -0,0 +1,253 @@
+package client
+
+import (
+	"sync"
+)
+
+// ConnectionState 客户端连接状态
+type ConnectionState int
+
+const (
+	ConnectionIdle         ConnectionState = iota // 尚未启动
+	ConnectionQueued                              // 在匹配队列中
+	ConnectionJoining                             // 已发送加入请求，等待欢迎消息
+	ConnectionConnected                           // 已加入房间
+	ConnectionReconnecting                        // 正在恢复会话
+	ConnectionDisconnected                        // 已离开或连接断开
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionIdle:
+		return "idle"
+	case ConnectionQueued:
+		return "queued"
+	case ConnectionJoining:
+		return "joining"
+	case ConnectionConnected:
+		return "connected"
+	case ConnectionReconnecting:
+		return "reconnecting"
+	case ConnectionDisconnected:
+		return "disconnected"
+	default:
+		return "unknown"
+	}
+}
+
+// PlayerJoinedEvent 玩家出现在本地世界中（新加入或欢迎快照中的玩家）
+type PlayerJoinedEvent struct {
+	PlayerID string
+	X        float64
+	Y        float64
+	GameTime int64
+}
+
+// PlayerLeftEvent 玩家从本地世界中移除
+type PlayerLeftEvent struct {
+	PlayerID string
+	GameTime int64
+}
+
+// PlayerMovedEvent 玩家的输入方向改变
+type PlayerMovedEvent struct {
+	PlayerID string
+	VectorX  float64
+	VectorY  float64
+	GameTime int64 // 输入生效的游戏时间
+	Local    bool  // 本地玩家的输入（已立即预测）
+}
+
+// PlayerCorrectedEvent 玩家的模拟状态被服务器仲裁结果校正
+type PlayerCorrectedEvent struct {
+	PlayerID string
+	X        float64 // 校正后的模拟位置
+	Y        float64
+	Error    float64 // 校正前后的位置误差
+	GameTime int64
+	Local    bool // 本地玩家的重放校正
+}
+
+// PlayerStatusEvent 远程玩家连接状态变化
+type PlayerStatusEvent struct {
+	PlayerID string
+	Status   string
+}
+
+// PlayerStaleEvent 远程玩家进入或离开过期状态，可用于在界面上淡出实体
+type PlayerStaleEvent struct {
+	PlayerID string
+	Stale    bool
+}
+
+// TimeResyncedEvent 本地游戏时间被服务器时间校正
+type TimeResyncedEvent struct {
+	GameTime int64 // 校正后的游戏时间
+	Diff     int64 // 校正量（毫秒），正数表示本地时间落后
+}
+
+// ConnectionStateEvent 客户端连接状态变化
+type ConnectionStateEvent struct {
+	State    ConnectionState
+	Previous ConnectionState
+	RoomID   string
+	Resumed  bool // 进入已连接状态时是否恢复了原有会话
+}
+
+// EventListener 客户端事件监听接口
+// 事件在独立的协程中按发生顺序回调，回调内可以调用客户端的任意方法，但不应长时间阻塞
+type EventListener interface {
+	OnPlayerJoined(event PlayerJoinedEvent)
+	OnPlayerLeft(event PlayerLeftEvent)
+	OnPlayerMoved(event PlayerMovedEvent)
+	OnPlayerCorrected(event PlayerCorrectedEvent)
+	OnPlayerStatus(event PlayerStatusEvent)
+	OnPlayerStale(event PlayerStaleEvent)
+	OnTimeResynced(event TimeResyncedEvent)
+	OnConnectionState(event ConnectionStateEvent)
+}
+
+// BaseListener 空实现，嵌入后只需实现关心的事件
+type BaseListener struct{}
+
+func (BaseListener) OnPlayerJoined(PlayerJoinedEvent)       {}
+func (BaseListener) OnPlayerLeft(PlayerLeftEvent)           {}
+func (BaseListener) OnPlayerMoved(PlayerMovedEvent)         {}
+func (BaseListener) OnPlayerCorrected(PlayerCorrectedEvent) {}
+func (BaseListener) OnPlayerStatus(PlayerStatusEvent)       {}
+func (BaseListener) OnPlayerStale(PlayerStaleEvent)         {}
+func (BaseListener) OnTimeResynced(TimeResyncedEvent)       {}
+func (BaseListener) OnConnectionState(ConnectionStateEvent) {}
+
+// eventBus 事件分发
+// 事件先进入队列，由单独的协程依次回调，发出事件的一方不会被监听者阻塞
+type eventBus struct {
+	listeners map[int]EventListener
+	nextID    int
+	queue     []interface{}
+	mu        sync.Mutex
+	wake      chan struct{}
+	once      sync.Once
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		listeners: make(map[int]EventListener),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// AddListener 添加事件监听者，返回用于移除的函数
+func (c *GameClient) AddListener(listener EventListener) (remove func()) {
+	bus := c.events
+	bus.once.Do(func() {
+		go bus.run(c.stopChan)
+	})
+
+	bus.mu.Lock()
+	id := bus.nextID
+	bus.nextID++
+	bus.listeners[id] = listener
+	bus.mu.Unlock()
+
+	return func() {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		delete(bus.listeners, id)
+	}
+}
+
+// emit 发出事件，可以在持有 c.mu 时调用
+func (c *GameClient) emit(event interface{}) {
+	bus := c.events
+	bus.mu.Lock()
+	if len(bus.listeners) == 0 {
+		bus.mu.Unlock()
+		return
+	}
+	bus.queue = append(bus.queue, event)
+	bus.mu.Unlock()
+
+	select {
+	case bus.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run 分发循环
+func (bus *eventBus) run(stopChan chan struct{}) {
+	for {
+		select {
+		case <-bus.wake:
+		case <-stopChan:
+			// 停止前送出剩余事件（包括断开连接事件）
+			bus.deliver()
+			return
+		}
+		bus.deliver()
+	}
+}
+
+// deliver 回调队列中的所有事件
+func (bus *eventBus) deliver() {
+	bus.mu.Lock()
+	queue := bus.queue
+	bus.queue = nil
+	listeners := make([]EventListener, 0, len(bus.listeners))
+	for id := 0; id < bus.nextID; id++ {
+		if listener, ok := bus.listeners[id]; ok {
+			listeners = append(listeners, listener)
+		}
+	}
+	bus.mu.Unlock()
+
+	for _, event := range queue {
+		for _, listener := range listeners {
+			dispatch(listener, event)
+		}
+	}
+}
+
+// dispatch 按事件类型回调
+func dispatch(listener EventListener, event interface{}) {
+	switch e := event.(type) {
+	case PlayerJoinedEvent:
+		listener.OnPlayerJoined(e)
+	case PlayerLeftEvent:
+		listener.OnPlayerLeft(e)
+	case PlayerMovedEvent:
+		listener.OnPlayerMoved(e)
+	case PlayerCorrectedEvent:
+		listener.OnPlayerCorrected(e)
+	case PlayerStatusEvent:
+		listener.OnPlayerStatus(e)
+	case PlayerStaleEvent:
+		listener.OnPlayerStale(e)
+	case TimeResyncedEvent:
+		listener.OnTimeResynced(e)
+	case ConnectionStateEvent:
+		listener.OnConnectionState(e)
+	}
+}
+
+// setConnectionState 切换连接状态并发出事件（调用方需持有 c.mu 写锁）
+func (c *GameClient) setConnectionState(state ConnectionState, resumed bool) {
+	if c.connState == state {
+		return
+	}
+	previous := c.connState
+	c.connState = state
+	c.emit(ConnectionStateEvent{
+		State:    state,
+		Previous: previous,
+		RoomID:   c.roomID,
+		Resumed:  resumed,
+	})
+}
+
+// GetConnectionState 获取客户端连接状态
+func (c *GameClient) GetConnectionState() ConnectionState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connState
+}
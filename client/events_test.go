@@ -0,0 +1,93 @@
+package client
+
+import (
+	"reflect"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+	"time"
+)
+
+// recorder 按回调顺序记录所有事件
+type recorder chan interface{}
+
+func (r recorder) OnPlayerJoined(e PlayerJoinedEvent)       { r <- e }
+func (r recorder) OnPlayerLeft(e PlayerLeftEvent)           { r <- e }
+func (r recorder) OnPlayerMoved(e PlayerMovedEvent)         { r <- e }
+func (r recorder) OnPlayerCorrected(e PlayerCorrectedEvent) { r <- e }
+func (r recorder) OnPlayerStatus(e PlayerStatusEvent)       { r <- e }
+func (r recorder) OnPlayerStale(e PlayerStaleEvent)         { r <- e }
+func (r recorder) OnTimeResynced(e TimeResyncedEvent)       { r <- e }
+func (r recorder) OnConnectionState(e ConnectionStateEvent) { r <- e }
+
+// next 等待 n 个事件
+func (r recorder) next(t *testing.T, n int) []interface{} {
+	t.Helper()
+	var events []interface{}
+	for len(events) < n {
+		select {
+		case e := <-r:
+			events = append(events, e)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d of %d events: %+v", len(events), n, events)
+		}
+	}
+	return events
+}
+
+func TestEventOrder(t *testing.T) {
+	c, clock := newTestClient(t)
+	events := make(recorder, 32)
+	c.AddListener(events)
+
+	welcome(c, 0, map[string][2]float64{"alice": {0, 0}})
+	clock.Advance(100 * time.Millisecond)
+	for _, msg := range []transport.Message{
+		transport.NewMessage(protocol.MsgTypePlayerJoined, protocol.PlayerJoinedData{
+			PlayerID: "bob", Movement: instantMovement, X: 5, GameTime: 100,
+		}),
+		transport.NewMessage(protocol.MsgTypeMoveCommand, protocol.MoveData{PlayerID: "bob", VectorY: 1, GameTime: 100}),
+		transport.NewMessage(protocol.MsgTypePositionBatch, protocol.PositionBatchData{
+			GameTime:  100,
+			Positions: []protocol.PositionUpdateData{{PlayerID: "bob", X: 8, GameTime: 100}},
+		}),
+		transport.NewMessage(protocol.MsgTypePlayerStatus, protocol.PlayerStatusData{
+			PlayerID: "bob", Status: protocol.PlayerStatusReconnecting,
+		}),
+		transport.NewMessage(protocol.MsgTypeTimeSync, protocol.TimeSyncData{GameTime: 600}),
+		transport.NewMessage(protocol.MsgTypePlayerLeft, protocol.PlayerLeftData{PlayerID: "bob"}),
+	} {
+		c.handleMessage(msg)
+	}
+	c.Move(1, 0)
+
+	want := []interface{}{
+		PlayerJoinedEvent{PlayerID: "alice"},
+		ConnectionStateEvent{State: ConnectionConnected, Previous: ConnectionIdle, RoomID: "room"},
+		PlayerJoinedEvent{PlayerID: "bob", X: 5, GameTime: 100},
+		PlayerMovedEvent{PlayerID: "bob", VectorY: 1, GameTime: 100},
+		PlayerCorrectedEvent{PlayerID: "bob", X: 8, Error: 3, GameTime: 100},
+		PlayerStatusEvent{PlayerID: "bob", Status: protocol.PlayerStatusReconnecting},
+		TimeResyncedEvent{GameTime: 600, Diff: 500},
+		PlayerLeftEvent{PlayerID: "bob", GameTime: 600},
+		PlayerMovedEvent{PlayerID: "alice", VectorX: 1, GameTime: 600, Local: true},
+	}
+	if got := events.next(t, len(want)); !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestRemovedListener(t *testing.T) {
+	c, _ := newTestClient(t)
+	kept, removed := make(recorder, 32), make(recorder, 32)
+	// 被移除的监听者先注册，若仍收到事件会先于 kept 收到
+	remove := c.AddListener(removed)
+	c.AddListener(kept)
+	remove()
+
+	welcome(c, 0, map[string][2]float64{"alice": {0, 0}})
+	kept.next(t, 2)
+	if len(removed) != 0 {
+		t.Errorf("removed listener received %d events", len(removed))
+	}
+}
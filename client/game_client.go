@@ -2,6 +2,7 @@ package client
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"math"
 	"sync"
@@ -13,20 +14,50 @@ import (
 
 // GameClient 游戏客户端
 type GameClient struct {
-	clientID       string
-	playerID       string
-	localTransport *transport.LocalTransport
-	timeSyncer     *gamesync.TimeSynchronizer
+	clientID     string
+	playerID     string
+	roomID       string
+	sessionToken string // 服务器下发的会话令牌，用于断线重连
+	transport    transport.ClientTransport
+	timeSyncer   *gamesync.TimeSynchronizer
 
 	// 本地游戏状态
 	localPlayers map[string]*LocalPlayerState
 	mu           sync.RWMutex
 
 	running  bool
+	manual   bool // 由调用方驱动，不启动后台循环
 	stopChan chan struct{}
 
-	// 移动速度（单位/秒）
-	moveSpeed float64
+	config        Config
+	configErr     error               // 创建时的配置错误，由 Start 返回
+	configUpdated chan struct{}       // 配置或同步参数变化时关闭并替换
+	syncParams    protocol.SyncParams // 服务器下发的同步参数
+
+	// 移动模型，加入时使用服务器下发的参数
+	movement gamesync.MovementModel
+
+	// 校正平滑配置
+	smoothing smoothingConfig
+
+	// 远程玩家插值配置
+	interpolation interpolationConfig
+
+	// 本地玩家的输入预测
+	inputSeq  uint32
+	inputs    []pendingInput // 按游戏时间排序
+	baseState stateSample    // 最早一条输入之前的运动状态，只使用速度和输入
+
+	// 远程玩家外推上限
+	extrapolationLimit time.Duration
+	removeStaleHandler func() // 移除 SetStaleHandler 添加的监听者
+
+	// 事件分发和连接状态
+	events    *eventBus
+	connState ConnectionState
+
+	// 传输层拒绝发送的消息数，按类型
+	sendFailures map[string]int
 }
 
 // LocalPlayerState 本地玩家状态
@@ -34,33 +65,77 @@ type LocalPlayerState struct {
 	PlayerID       string
 	X              float64
 	Y              float64
-	VelocityX      float64 // 当前速度向量
+	VelocityX      float64 // 最后更新时间的速度向量
 	VelocityY      float64
-	LastUpdateTime int64 // 最后更新的游戏时间
+	InputX         float64 // 当前输入方向
+	InputY         float64
+	LastUpdateTime int64  // 最后更新的游戏时间
+	Status         string // 连接状态
+
+	CorrectionX    float64 // 校正时渲染位置相对模拟位置的偏移，随时间衰减
+	CorrectionY    float64
+	CorrectionTime int64 // 产生偏移的游戏时间
+
+	samples  []stateSample   // 权威状态缓冲，用于远程玩家插值
+	movement []movementPhase // 移动属性，按生效时间排序
+
+	LastAuthoritativeTime int64 // 最后一次收到权威状态的游戏时间
+	Stale                 bool  // 超过外推上限未收到权威状态
 }
 
 // NewGameClient 创建游戏客户端
-func NewGameClient(clientID, playerID string, localTransport *transport.LocalTransport) *GameClient {
-	return &GameClient{
-		clientID:       clientID,
-		playerID:       playerID,
-		localTransport: localTransport,
-		timeSyncer:     gamesync.NewTimeSynchronizer(),
-		localPlayers:   make(map[string]*LocalPlayerState),
-		stopChan:       make(chan struct{}),
-		moveSpeed:      10.0, // 10单位/秒
+// 未指定的配置项使用 DefaultConfig 中的默认值；配置无效时 Start 返回错误
+func NewGameClient(clientID, playerID string, clientTransport transport.ClientTransport, opts ...Option) *GameClient {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	clock := cfg.Clock
+	if clock == nil {
+		clock = gamesync.SystemClock()
+	}
+
+	movement, _ := gamesync.NewMovementModel(gamesync.DefaultMovementParams())
+	c := &GameClient{
+		clientID:      clientID,
+		playerID:      playerID,
+		transport:     clientTransport,
+		timeSyncer:    gamesync.NewTimeSynchronizerWithClock(clock),
+		localPlayers:  make(map[string]*LocalPlayerState),
+		stopChan:      make(chan struct{}),
+		movement:      movement,
+		smoothing:     defaultSmoothing(),
+		interpolation: defaultInterpolation(),
+
+		extrapolationLimit: 2 * time.Second,
+		events:             newEventBus(),
+		config:             DefaultConfig(),
+		configUpdated:      make(chan struct{}),
+		sendFailures:       make(map[string]int),
 	}
+
+	if err := cfg.Validate(); err != nil {
+		c.configErr = fmt.Errorf("invalid client config: %w", err)
+		c.config.Clock = cfg.Clock
+	} else {
+		c.config = cfg
+	}
+	return c
 }
 
 // Start 启动客户端
 func (c *GameClient) Start() error {
+	if c.configErr != nil {
+		return c.configErr
+	}
 	c.running = true
 
+	c.mu.Lock()
+	c.setConnectionState(ConnectionJoining, false)
+	c.mu.Unlock()
+
 	// 发送加入游戏请求
-	joinMsg := transport.NewMessage(protocol.MsgTypeJoin, protocol.JoinData{
-		PlayerID: c.playerID,
-	})
-	_ = c.localTransport.SendToServer(c.clientID, joinMsg)
+	c.sendJoin("")
 
 	// 启动消息接收循环
 	go c.messageLoop()
@@ -72,16 +147,118 @@ func (c *GameClient) Start() error {
 	return nil
 }
 
+// StartMatchmaking 以匹配模式启动客户端
+// 先进入匹配队列，收到匹配成功消息后自动加入分配的房间
+func (c *GameClient) StartMatchmaking(attrs protocol.MatchAttributes) error {
+	if c.configErr != nil {
+		return c.configErr
+	}
+	c.running = true
+
+	queueMsg := transport.NewMessage(protocol.MsgTypeQueueJoin, protocol.QueueJoinData{
+		PlayerID:   c.playerID,
+		Attributes: attrs,
+	})
+	if err := c.sendToServer(queueMsg); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.setConnectionState(ConnectionQueued, false)
+	c.mu.Unlock()
+
+	go c.messageLoop()
+	go c.syncLoop()
+
+	log.Printf("[Client %s] Queued for matchmaking as player %s", c.clientID, c.playerID)
+	return nil
+}
+
+// Attach 发送加入请求但不启动后台循环
+// 调用方通过 HandleMessage 投递服务器消息、通过 ReportNow 触发位置上报，用于确定性的模拟和测试
+func (c *GameClient) Attach() error {
+	if c.configErr != nil {
+		return c.configErr
+	}
+	c.running = true
+	c.manual = true
+
+	c.mu.Lock()
+	c.setConnectionState(ConnectionJoining, false)
+	c.mu.Unlock()
+
+	c.sendJoin("")
+	return nil
+}
+
+// sendJoin 发送加入游戏请求
+func (c *GameClient) sendJoin(roomID string) {
+	c.mu.RLock()
+	token := c.sessionToken
+	c.mu.RUnlock()
+
+	joinMsg := transport.NewMessage(protocol.MsgTypeJoin, protocol.JoinData{
+		PlayerID:     c.playerID,
+		RoomID:       roomID,
+		SessionToken: token,
+	})
+	c.sendToServer(joinMsg)
+}
+
+// Reconnect 断线后恢复会话
+// 调用前需要在传输层重新注册同一个客户端ID（TCP 传输层为 Redial），服务器会凭会话令牌恢复原有玩家并下发完整状态
+func (c *GameClient) Reconnect() error {
+	c.mu.Lock()
+	token := c.sessionToken
+	roomID := c.roomID
+	if token != "" {
+		c.setConnectionState(ConnectionReconnecting, false)
+	}
+	c.mu.Unlock()
+
+	if token == "" {
+		return fmt.Errorf("no session to resume")
+	}
+
+	if !c.manual {
+		go c.messageLoop()
+	}
+	c.sendJoin(roomID)
+
+	log.Printf("[Client %s] Reconnecting as player %s", c.clientID, c.playerID)
+	return nil
+}
+
+// Leave 主动离开游戏，服务器会立即移除玩家
+func (c *GameClient) Leave() error {
+	leaveMsg := transport.NewMessage(protocol.MsgTypeLeave, protocol.LeaveData{
+		PlayerID: c.playerID,
+	})
+	if err := c.sendToServer(leaveMsg); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.setConnectionState(ConnectionDisconnected, false)
+	c.mu.Unlock()
+	return nil
+}
+
 // Stop 停止客户端
 func (c *GameClient) Stop() {
 	c.running = false
+
+	c.mu.Lock()
+	c.setConnectionState(ConnectionDisconnected, false)
+	c.mu.Unlock()
+
 	close(c.stopChan)
 	log.Printf("[Client %s] Stopped", c.clientID)
 }
 
 // messageLoop 消息接收循环
 func (c *GameClient) messageLoop() {
-	ch, err := c.localTransport.GetClientChannel(c.clientID)
+	ch, err := c.transport.GetClientChannel(c.clientID)
 	if err != nil {
 		log.Printf("[Client %s] Error getting channel: %v", c.clientID, err)
 		return
@@ -90,6 +267,17 @@ func (c *GameClient) messageLoop() {
 	for msg := range ch {
 		c.handleMessage(msg)
 	}
+
+	// 传输层关闭了客户端通道，视为连接断开
+	c.mu.Lock()
+	c.setConnectionState(ConnectionDisconnected, false)
+	c.mu.Unlock()
+}
+
+// HandleMessage 处理一条服务器消息
+// 正常运行时由消息循环调用，Attach 之后由调用方逐条投递
+func (c *GameClient) HandleMessage(msg transport.Message) {
+	c.handleMessage(msg)
 }
 
 // handleMessage 处理消息
@@ -99,12 +287,26 @@ func (c *GameClient) handleMessage(msg transport.Message) {
 		c.handleWelcome(msg)
 	case protocol.MsgTypePlayerJoined:
 		c.handlePlayerJoined(msg)
+	case protocol.MsgTypePlayerLeft:
+		c.handlePlayerLeft(msg)
+	case protocol.MsgTypePlayerStatus:
+		c.handlePlayerStatus(msg)
 	case protocol.MsgTypeMoveCommand:
 		c.handleMoveCommand(msg)
 	case protocol.MsgTypeTimeSync:
 		c.handleTimeSync(msg)
 	case protocol.MsgTypePositionUpdate:
 		c.handlePositionUpdate(msg)
+	case protocol.MsgTypePositionBatch:
+		c.handlePositionBatch(msg)
+	case protocol.MsgTypeMatchFound:
+		c.handleMatchFound(msg)
+	case protocol.MsgTypePing:
+		c.handlePing(msg)
+	case protocol.MsgTypeAttributes:
+		c.handleAttributes(msg)
+	case protocol.MsgTypeSyncParams:
+		c.handleSyncParams(msg)
 	}
 }
 
@@ -121,8 +323,18 @@ func (c *GameClient) handleWelcome(msg transport.Message) {
 	// 同步游戏时间
 	c.timeSyncer.SetGameTime(welcomeData.GameTime)
 
-	// 初始化本地玩家状态
+	// 用服务器快照重建本地玩家状态
 	c.mu.Lock()
+	previous := c.localPlayers
+	c.roomID = welcomeData.RoomID
+	c.sessionToken = welcomeData.SessionToken
+	c.setSyncParams(welcomeData.Sync)
+	if movement, err := gamesync.NewMovementModel(welcomeData.Movement); err == nil {
+		c.movement = movement
+	} else {
+		log.Printf("[Client %s] Invalid movement params from server, keeping current model: %v", c.clientID, err)
+	}
+	c.localPlayers = make(map[string]*LocalPlayerState, len(welcomeData.Positions))
 	for _, pos := range welcomeData.Positions {
 		c.localPlayers[pos.PlayerID] = &LocalPlayerState{
 			PlayerID:       pos.PlayerID,
@@ -131,12 +343,82 @@ func (c *GameClient) handleWelcome(msg transport.Message) {
 			VelocityX:      0,
 			VelocityY:      0,
 			LastUpdateTime: pos.GameTime,
+			Status:         protocol.PlayerStatusOnline,
 		}
 	}
+	for _, attr := range welcomeData.Attributes {
+		if player, exists := c.localPlayers[attr.PlayerID]; exists {
+			if err := c.setPlayerMovement(player, attr.Movement, attr.GameTime); err != nil {
+				log.Printf("[Client %s] Invalid movement attributes for %s: %v", c.clientID, attr.PlayerID, err)
+			}
+		}
+	}
+	for _, move := range welcomeData.Movements {
+		if player, exists := c.localPlayers[move.PlayerID]; exists {
+			c.applyMoveSnapshot(player, move)
+		}
+	}
+	c.resetInputs()
+	for _, player := range c.localPlayers {
+		c.touchAuthoritative(player, welcomeData.GameTime)
+		c.recordSample(player, playerSample(player))
+	}
+	for _, status := range welcomeData.Statuses {
+		if player, exists := c.localPlayers[status.PlayerID]; exists {
+			player.Status = status.Status
+		}
+	}
+
+	// 与之前的本地世界比较，通知出现和消失的玩家
+	for playerID := range previous {
+		if _, exists := c.localPlayers[playerID]; !exists {
+			c.emit(PlayerLeftEvent{PlayerID: playerID, GameTime: welcomeData.GameTime})
+		}
+	}
+	for playerID, player := range c.localPlayers {
+		if _, exists := previous[playerID]; !exists {
+			x, y := c.predictPosition(player, welcomeData.GameTime)
+			c.emit(PlayerJoinedEvent{PlayerID: playerID, X: x, Y: y, GameTime: welcomeData.GameTime})
+		}
+	}
+	c.setConnectionState(ConnectionConnected, welcomeData.Resumed)
+	c.mu.Unlock()
+
+	if welcomeData.Resumed {
+		log.Printf("[Client %s] Session resumed in room %s! Game time: %d, Players: %v",
+			c.clientID, welcomeData.RoomID, welcomeData.GameTime, welcomeData.Players)
+		return
+	}
+	log.Printf("[Client %s] Welcomed to room %s! Game time: %d, Players: %v",
+		c.clientID, welcomeData.RoomID, welcomeData.GameTime, welcomeData.Players)
+}
+
+// handleMatchFound 处理匹配成功，加入分配的房间
+func (c *GameClient) handleMatchFound(msg transport.Message) {
+	data, err := c.parseData(msg, &protocol.MatchFoundData{})
+	if err != nil {
+		return
+	}
+
+	matchData := data.(*protocol.MatchFoundData)
+	log.Printf("[Client %s] Match found: room %s with %v", c.clientID, matchData.RoomID, matchData.Players)
+
+	c.mu.Lock()
+	c.setConnectionState(ConnectionJoining, false)
 	c.mu.Unlock()
 
-	log.Printf("[Client %s] Welcomed! Game time: %d, Players: %v",
-		c.clientID, welcomeData.GameTime, welcomeData.Players)
+	c.sendJoin(matchData.RoomID)
+}
+
+// handlePing 响应服务器心跳
+func (c *GameClient) handlePing(msg transport.Message) {
+	data, err := c.parseData(msg, &protocol.PingData{})
+	if err != nil {
+		return
+	}
+
+	pongMsg := transport.NewMessage(protocol.MsgTypePong, data)
+	c.sendToServer(pongMsg)
 }
 
 // handlePlayerJoined 处理玩家加入
@@ -150,20 +432,71 @@ func (c *GameClient) handlePlayerJoined(msg transport.Message) {
 
 	c.mu.Lock()
 	if _, exists := c.localPlayers[joinedData.PlayerID]; !exists {
-		c.localPlayers[joinedData.PlayerID] = &LocalPlayerState{
+		// 从服务器记录的位置和移动状态开始模拟，与欢迎快照中的玩家一致
+		now := c.timeSyncer.GetGameTime()
+		player := &LocalPlayerState{
 			PlayerID:       joinedData.PlayerID,
-			X:              0,
-			Y:              0,
+			X:              joinedData.X,
+			Y:              joinedData.Y,
 			VelocityX:      0,
 			VelocityY:      0,
-			LastUpdateTime: c.timeSyncer.GetGameTime(),
+			LastUpdateTime: joinedData.GameTime,
+			Status:         protocol.PlayerStatusOnline,
+		}
+		c.localPlayers[joinedData.PlayerID] = player
+		if err := c.setPlayerMovement(player, joinedData.Movement, 0); err != nil {
+			log.Printf("[Client %s] Invalid movement attributes for %s: %v", c.clientID, joinedData.PlayerID, err)
 		}
+		c.applyMoveSnapshot(player, joinedData.Move)
+		c.touchAuthoritative(player, now)
+		c.recordSample(player, playerSample(player))
+
+		x, y := c.predictPosition(player, now)
+		c.emit(PlayerJoinedEvent{PlayerID: joinedData.PlayerID, X: x, Y: y, GameTime: now})
 	}
 	c.mu.Unlock()
 
 	log.Printf("[Client %s] Player %s joined", c.clientID, joinedData.PlayerID)
 }
 
+// handlePlayerLeft 处理玩家离开
+func (c *GameClient) handlePlayerLeft(msg transport.Message) {
+	data, err := c.parseData(msg, &protocol.PlayerLeftData{})
+	if err != nil {
+		return
+	}
+
+	leftData := data.(*protocol.PlayerLeftData)
+
+	c.mu.Lock()
+	if _, exists := c.localPlayers[leftData.PlayerID]; exists {
+		delete(c.localPlayers, leftData.PlayerID)
+		c.emit(PlayerLeftEvent{PlayerID: leftData.PlayerID, GameTime: c.timeSyncer.GetGameTime()})
+	}
+	c.mu.Unlock()
+
+	log.Printf("[Client %s] Player %s left", c.clientID, leftData.PlayerID)
+}
+
+// handlePlayerStatus 处理玩家连接状态变化
+func (c *GameClient) handlePlayerStatus(msg transport.Message) {
+	data, err := c.parseData(msg, &protocol.PlayerStatusData{})
+	if err != nil {
+		return
+	}
+
+	statusData := data.(*protocol.PlayerStatusData)
+
+	c.mu.Lock()
+	if player, exists := c.localPlayers[statusData.PlayerID]; exists && player.Status != statusData.Status {
+		player.Status = statusData.Status
+		c.emit(PlayerStatusEvent{PlayerID: statusData.PlayerID, Status: statusData.Status})
+	}
+	c.mu.Unlock()
+
+	log.Printf("[Client %s] Player %s is %s", c.clientID, statusData.PlayerID, statusData.Status)
+}
+
 // handleMoveCommand 处理移动指令（客户端计算移动）
 func (c *GameClient) handleMoveCommand(msg transport.Message) {
 	data, err := c.parseData(msg, &protocol.MoveData{})
@@ -181,15 +514,25 @@ func (c *GameClient) handleMoveCommand(msg transport.Message) {
 		return
 	}
 
-	// 先根据旧速度计算到当前时间的位置
-	c.updatePlayerPosition(player, moveData.GameTime)
+	c.touchAuthoritative(player, moveData.GameTime)
 
-	// 设置新的速度向量
-	player.VelocityX = moveData.VectorX * c.moveSpeed
-	player.VelocityY = moveData.VectorY * c.moveSpeed
+	// 自己的输入已经在本地预测过，只需确认
+	if moveData.PlayerID == c.playerID && moveData.Seq != 0 && c.acknowledgeInput(moveData.Seq) {
+		return
+	}
 
-	log.Printf("[Client %s] Player %s moving with velocity (%.2f, %.2f)",
-		c.clientID, moveData.PlayerID, player.VelocityX, player.VelocityY)
+	// 先根据旧输入推进到指令时间，再应用新的输入方向
+	c.applyInput(player, moveData.VectorX, moveData.VectorY, moveData.GameTime)
+	c.recordSample(player, playerSample(player))
+	c.emit(PlayerMovedEvent{
+		PlayerID: moveData.PlayerID,
+		VectorX:  moveData.VectorX,
+		VectorY:  moveData.VectorY,
+		GameTime: moveData.GameTime,
+	})
+
+	log.Printf("[Client %s] Player %s moving with input (%.2f, %.2f)",
+		c.clientID, moveData.PlayerID, player.InputX, player.InputY)
 }
 
 // handleTimeSync 处理时间同步
@@ -205,9 +548,13 @@ func (c *GameClient) handleTimeSync(msg transport.Message) {
 	localTime := c.timeSyncer.GetGameTime()
 	diff := timeSyncData.GameTime - localTime
 
-	// 如果差异超过100ms，才进行调整
-	if math.Abs(float64(diff)) > 100 {
+	// 差异超过阈值才进行调整
+	c.mu.RLock()
+	threshold := c.config.TimeSyncThreshold.Milliseconds()
+	c.mu.RUnlock()
+	if diff > threshold || diff < -threshold {
 		c.timeSyncer.SetGameTime(timeSyncData.GameTime)
+		c.emit(TimeResyncedEvent{GameTime: timeSyncData.GameTime, Diff: diff})
 		log.Printf("[Client %s] Time synced: %d (diff: %d ms)", c.clientID, timeSyncData.GameTime, diff)
 	}
 }
@@ -219,27 +566,65 @@ func (c *GameClient) handlePositionUpdate(msg transport.Message) {
 		return
 	}
 
-	updateData := data.(*protocol.PositionUpdateData)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applyPositionUpdate(data.(*protocol.PositionUpdateData))
+}
+
+// handlePositionBatch 处理一轮仲裁的批量结果
+// 整批在同一次加锁内应用，读取世界状态时不会看到只应用了一部分的结果
+func (c *GameClient) handlePositionBatch(msg transport.Message) {
+	data, err := c.parseData(msg, &protocol.PositionBatchData{})
+	if err != nil {
+		return
+	}
+
+	batch := data.(*protocol.PositionBatchData)
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	for i := range batch.Positions {
+		c.applyPositionUpdate(&batch.Positions[i])
+	}
+}
 
+// applyPositionUpdate 应用一个玩家的仲裁结果（调用方需持有 c.mu 写锁）
+func (c *GameClient) applyPositionUpdate(updateData *protocol.PositionUpdateData) {
 	player, exists := c.localPlayers[updateData.PlayerID]
 	if !exists {
 		return
 	}
 
+	// 仲裁时间点的权威状态：位置来自仲裁结果，速度和输入来自本地模拟
+	authoritative := c.advanceSample(player, playerSample(player), updateData.GameTime)
+	authoritative.X = updateData.X
+	authoritative.Y = updateData.Y
+
+	c.touchAuthoritative(player, updateData.GameTime)
+	c.recordSample(player, authoritative)
+
+	// 本地玩家重放未确认的输入
+	if updateData.PlayerID == c.playerID {
+		c.reconcile(player, updateData)
+		return
+	}
+
 	// 计算误差
 	localX, localY := c.predictPosition(player, updateData.GameTime)
 	errorX := updateData.X - localX
 	errorY := updateData.Y - localY
 	distance := math.Sqrt(errorX*errorX + errorY*errorY)
 
-	// 如果误差较大，进行校正
-	if distance > 0.5 {
-		player.X = updateData.X
-		player.Y = updateData.Y
-		player.LastUpdateTime = updateData.GameTime
+	// 如果误差较大，进行校正（渲染位置平滑过渡）
+	if distance > c.config.CorrectionThreshold {
+		c.applyCorrection(player, authoritative, c.timeSyncer.GetGameTime())
+		c.emit(PlayerCorrectedEvent{
+			PlayerID: updateData.PlayerID,
+			X:        updateData.X,
+			Y:        updateData.Y,
+			Error:    distance,
+			GameTime: updateData.GameTime,
+		})
 		log.Printf("[Client %s] Position corrected for %s: (%.2f, %.2f), error: %.2f",
 			c.clientID, updateData.PlayerID, updateData.X, updateData.Y, distance)
 	}
@@ -247,19 +632,29 @@ func (c *GameClient) handlePositionUpdate(msg transport.Message) {
 
 // syncLoop 同步循环：定期上报位置
 func (c *GameClient) syncLoop() {
-	ticker := time.NewTicker(200 * time.Millisecond)
+	interval, updated := c.reportInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			c.reportPositions()
+			c.ReportNow()
+		case <-updated:
+			interval, updated = c.reportInterval()
+			ticker.Reset(interval)
 		case <-c.stopChan:
 			return
 		}
 	}
 }
 
+// ReportNow 立即上报一次位置并检查远程玩家是否过期
+func (c *GameClient) ReportNow() {
+	c.reportPositions()
+	c.checkStaleness()
+}
+
 // reportPositions 上报所有玩家的位置
 func (c *GameClient) reportPositions() {
 	gameTime := c.timeSyncer.GetGameTime()
@@ -282,39 +677,81 @@ func (c *GameClient) reportPositions() {
 			Positions: positions,
 			GameTime:  gameTime,
 		})
-		_ = c.localTransport.SendToServer(c.clientID, syncMsg)
+		c.sendToServer(syncMsg)
+	}
+}
+
+// sendToServer 发送消息到服务器，传输层拒绝时按类型计数
+func (c *GameClient) sendToServer(msg transport.Message) error {
+	err := c.transport.SendToServer(c.clientID, msg)
+	if err != nil {
+		c.mu.Lock()
+		c.sendFailures[msg.GetType()]++
+		c.mu.Unlock()
 	}
+	return err
+}
+
+// SendFailures 获取传输层拒绝发送的消息数，按类型
+func (c *GameClient) SendFailures() map[string]int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	failures := make(map[string]int, len(c.sendFailures))
+	for msgType, n := range c.sendFailures {
+		failures[msgType] = n
+	}
+	return failures
 }
 
 // Move 发起移动
+// 输入立即在本地生效，同时带序号发送给服务器，收到回显后确认
 func (c *GameClient) Move(vectorX, vectorY float64) {
 	gameTime := c.timeSyncer.GetGameTime()
-	moveMsg := transport.NewMessage(protocol.MsgTypeMove, protocol.MoveData{
+
+	c.mu.Lock()
+	c.inputSeq++
+	seq := c.inputSeq
+	c.predictLocalMove(pendingInput{
+		Seq:      seq,
+		VectorX:  vectorX,
+		VectorY:  vectorY,
+		GameTime: gameTime,
+	})
+	c.emit(PlayerMovedEvent{
 		PlayerID: c.playerID,
 		VectorX:  vectorX,
 		VectorY:  vectorY,
 		GameTime: gameTime,
+		Local:    true,
 	})
-	_ = c.localTransport.SendToServer(c.clientID, moveMsg)
-}
-
-// updatePlayerPosition 更新玩家位置到指定游戏时间
-func (c *GameClient) updatePlayerPosition(player *LocalPlayerState, targetTime int64) {
-	deltaTime := float64(targetTime-player.LastUpdateTime) / 1000.0 // 转换为秒
+	c.mu.Unlock()
 
-	player.X += player.VelocityX * deltaTime
-	player.Y += player.VelocityY * deltaTime
-	player.LastUpdateTime = targetTime
+	moveMsg := transport.NewMessage(protocol.MsgTypeMove, protocol.MoveData{
+		PlayerID: c.playerID,
+		VectorX:  vectorX,
+		VectorY:  vectorY,
+		GameTime: gameTime,
+		Seq:      seq,
+	})
+	c.sendToServer(moveMsg)
 }
 
 // predictPosition 预测玩家在指定时间的位置
+// 远程玩家最多外推到最后一次权威状态之后的外推上限
 func (c *GameClient) predictPosition(player *LocalPlayerState, targetTime int64) (float64, float64) {
-	deltaTime := float64(targetTime-player.LastUpdateTime) / 1000.0
-
-	x := player.X + player.VelocityX*deltaTime
-	y := player.Y + player.VelocityY*deltaTime
+	state := c.predictState(player, targetTime)
+	return state.X, state.Y
+}
 
-	return x, y
+// predictState 预测玩家在指定时间的运动状态，超过外推上限时停在上限处且速度为零
+func (c *GameClient) predictState(player *LocalPlayerState, targetTime int64) stateSample {
+	if limit, ok := c.extrapolationCap(player); ok && targetTime > limit {
+		state := c.advanceSample(player, playerSample(player), limit)
+		state.GameTime = targetTime
+		state.VelocityX, state.VelocityY = 0, 0
+		return state
+	}
+	return c.advanceSample(player, playerSample(player), targetTime)
 }
 
 // GetPlayerPosition 获取玩家当前位置
@@ -332,6 +769,30 @@ func (c *GameClient) GetPlayerPosition(playerID string) (x, y float64, ok bool)
 	return x, y, true
 }
 
+// GetPlayerStatus 获取玩家连接状态
+func (c *GameClient) GetPlayerStatus(playerID string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	player, exists := c.localPlayers[playerID]
+	if !exists {
+		return "", false
+	}
+	return player.Status, true
+}
+
+// GetGameTime 获取本地游戏时间
+func (c *GameClient) GetGameTime() int64 {
+	return c.timeSyncer.GetGameTime()
+}
+
+// GetRoomID 获取当前所在房间
+func (c *GameClient) GetRoomID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.roomID
+}
+
 // parseData 解析消息数据
 func (c *GameClient) parseData(msg transport.Message, target interface{}) (interface{}, error) {
 	dataBytes, err := json.Marshal(msg.GetData())
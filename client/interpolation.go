@@ -0,0 +1,93 @@
+package client
+
+import (
+	"sort"
+	"time"
+)
+
+// stateSample 带时间戳的权威状态
+type stateSample struct {
+	GameTime  int64
+	X         float64
+	Y         float64
+	VelocityX float64
+	VelocityY float64
+	InputX    float64 // 输入方向，用于按移动模型外推
+	InputY    float64
+}
+
+// interpolationConfig 远程玩家插值配置
+type interpolationConfig struct {
+	enabled          bool
+	delay            time.Duration // 渲染时间相对当前游戏时间的延迟
+	maxExtrapolation time.Duration // 缓冲耗尽后最多外推的时间
+	bufferSize       int
+}
+
+// defaultInterpolation 默认插值配置（关闭）
+// 延迟略大于服务器仲裁间隔，保证渲染时间两侧通常都有权威状态
+func defaultInterpolation() interpolationConfig {
+	return interpolationConfig{
+		enabled:          false,
+		delay:            550 * time.Millisecond,
+		maxExtrapolation: 250 * time.Millisecond,
+		bufferSize:       32,
+	}
+}
+
+// SetInterpolation 设置远程玩家插值
+// 开启后 GetRenderPosition 以 delay 之前的时间在权威状态之间插值显示远程玩家，
+// 缓冲耗尽时最多外推 maxExtrapolation；本地玩家不受影响。
+// delay 应大于服务器的仲裁间隔，否则渲染时间经常落在缓冲之外
+func (c *GameClient) SetInterpolation(enabled bool, delay, maxExtrapolation time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interpolation.enabled = enabled
+	c.interpolation.delay = delay
+	c.interpolation.maxExtrapolation = maxExtrapolation
+}
+
+// recordSample 记录一条权威状态，按游戏时间有序插入（调用方需持有 c.mu 写锁）
+func (c *GameClient) recordSample(player *LocalPlayerState, sample stateSample) {
+	i := sort.Search(len(player.samples), func(i int) bool {
+		return player.samples[i].GameTime > sample.GameTime
+	})
+	player.samples = append(player.samples, stateSample{})
+	copy(player.samples[i+1:], player.samples[i:])
+	player.samples[i] = sample
+
+	if over := len(player.samples) - c.interpolation.bufferSize; over > 0 {
+		player.samples = append(player.samples[:0], player.samples[over:]...)
+	}
+}
+
+// interpolatePosition 计算玩家在渲染时间的插值位置
+func (c *GameClient) interpolatePosition(player *LocalPlayerState, renderTime int64) (float64, float64) {
+	samples := player.samples
+	if len(samples) == 0 {
+		return c.predictPosition(player, renderTime)
+	}
+
+	// 早于缓冲：停在最早的状态
+	first := samples[0]
+	if renderTime <= first.GameTime {
+		return first.X, first.Y
+	}
+
+	// 晚于缓冲：有限外推
+	last := samples[len(samples)-1]
+	if renderTime >= last.GameTime {
+		elapsed := min(renderTime-last.GameTime, c.interpolation.maxExtrapolation.Milliseconds())
+		state := c.advanceSample(player, last, last.GameTime+elapsed)
+		return state.X, state.Y
+	}
+
+	// 在两个状态之间线性插值
+	i := sort.Search(len(samples), func(i int) bool {
+		return samples[i].GameTime > renderTime
+	})
+	a, b := samples[i-1], samples[i]
+	t := float64(renderTime-a.GameTime) / float64(b.GameTime-a.GameTime)
+	return a.X + (b.X-a.X)*t, a.Y + (b.Y-a.Y)*t
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"math"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+	"time"
+)
+
+func TestInterpolationDelayAndUnderrun(t *testing.T) {
+	c, clock := newTestClient(t)
+	c.SetInterpolation(true, 100*time.Millisecond, 50*time.Millisecond)
+	welcome(c, 0, map[string][2]float64{"alice": {0, 0}, "bob": {0, 0}})
+
+	// bob 在 100 被校正到 10，随后以 10/秒 向右移动
+	positionBatch(c, 100, map[string][2]float64{"bob": {10, 0}})
+	c.handleMessage(transport.NewMessage(protocol.MsgTypeMoveCommand, protocol.MoveData{
+		PlayerID: "bob", VectorX: 1, GameTime: 100,
+	}))
+	positionBatch(c, 200, map[string][2]float64{"bob": {11, 0}})
+
+	tests := []struct {
+		gameTime int64
+		want     float64
+	}{
+		{50, 0},     // 渲染时间早于缓冲，停在最早的状态
+		{150, 5},    // 在 0 和 100 的状态之间插值
+		{250, 10.5}, // 延迟 100ms 显示
+		{290, 10.9},
+		{320, 11.2}, // 缓冲耗尽后按最后的状态外推
+		{400, 11.5}, // 外推不超过 50ms
+	}
+	for _, tt := range tests {
+		clock.Set(testEpoch.Add(time.Duration(tt.gameTime) * time.Millisecond))
+		if x, _, _ := c.GetRenderPosition("bob"); math.Abs(x-tt.want) > 1e-9 {
+			t.Errorf("render x at %d = %v, want %v", tt.gameTime, x, tt.want)
+		}
+	}
+
+	// 模拟位置不受插值影响
+	if x, _, _ := c.GetPlayerPosition("bob"); math.Abs(x-13) > 1e-9 {
+		t.Errorf("simulated x = %v, want 13", x)
+	}
+}
+
+func TestInterpolationSkipsLocalPlayer(t *testing.T) {
+	c, clock := newTestClient(t)
+	c.SetInterpolation(true, 100*time.Millisecond, 50*time.Millisecond)
+	welcome(c, 0, map[string][2]float64{"alice": {0, 0}})
+
+	c.Move(1, 0)
+	clock.Advance(100 * time.Millisecond)
+	if x, _, _ := c.GetRenderPosition("alice"); math.Abs(x-1) > 1e-9 {
+		t.Errorf("local render x = %v, want the predicted 1", x)
+	}
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"syncServerDemo/gamesync"
+	"syncServerDemo/protocol"
+)
+
+// playerSample 玩家在最后更新时间的运动状态（调用方需持有 c.mu）
+func playerSample(player *LocalPlayerState) stateSample {
+	return stateSample{
+		GameTime:  player.LastUpdateTime,
+		X:         player.X,
+		Y:         player.Y,
+		VelocityX: player.VelocityX,
+		VelocityY: player.VelocityY,
+		InputX:    player.InputX,
+		InputY:    player.InputY,
+	}
+}
+
+// setPlayerSample 用运动状态覆盖玩家的模拟状态（调用方需持有 c.mu 写锁）
+func setPlayerSample(player *LocalPlayerState, state stateSample) {
+	player.X = state.X
+	player.Y = state.Y
+	player.VelocityX = state.VelocityX
+	player.VelocityY = state.VelocityY
+	player.InputX = state.InputX
+	player.InputY = state.InputY
+	player.LastUpdateTime = state.GameTime
+}
+
+// advanceSample 按玩家的移动属性将状态推进到指定游戏时间，输入保持不变（调用方需持有 c.mu）
+// 期间有属性变化时在生效时间分段推进
+func (c *GameClient) advanceSample(player *LocalPlayerState, state stateSample, targetTime int64) stateSample {
+	for _, phase := range player.movement {
+		if phase.from <= state.GameTime {
+			continue
+		}
+		if phase.from >= targetTime {
+			break
+		}
+		state = advanceWith(c.modelAt(player, state.GameTime), state, phase.from)
+	}
+	return advanceWith(c.modelAt(player, state.GameTime), state, targetTime)
+}
+
+// advanceWith 用指定的移动模型推进状态
+func advanceWith(model gamesync.MovementModel, state stateSample, targetTime int64) stateSample {
+	next := model.Advance(gamesync.MovementState{
+		X:         state.X,
+		Y:         state.Y,
+		VelocityX: state.VelocityX,
+		VelocityY: state.VelocityY,
+	}, state.InputX, state.InputY, float64(targetTime-state.GameTime)/1000.0)
+
+	state.X, state.Y = next.X, next.Y
+	state.VelocityX, state.VelocityY = next.VelocityX, next.VelocityY
+	state.GameTime = targetTime
+	return state
+}
+
+// withInput 在状态的时间点应用新的输入方向（调用方需持有 c.mu）
+func (c *GameClient) withInput(player *LocalPlayerState, state stateSample, inputX, inputY float64) stateSample {
+	state.InputX, state.InputY = inputX, inputY
+	return advanceWith(c.modelAt(player, state.GameTime), state, state.GameTime)
+}
+
+// applyMoveSnapshot 用服务器快照中的最后移动指令设置玩家的速度和输入（调用方需持有 c.mu 写锁）
+// 从指令生效时的速度推进到玩家位置对应的时间
+func (c *GameClient) applyMoveSnapshot(player *LocalPlayerState, move protocol.MoveData) {
+	state := c.withInput(player, stateSample{
+		GameTime:  move.GameTime,
+		VelocityX: move.VelocityX,
+		VelocityY: move.VelocityY,
+	}, move.VectorX, move.VectorY)
+	if move.GameTime < player.LastUpdateTime {
+		state = c.advanceSample(player, state, player.LastUpdateTime)
+	}
+	player.VelocityX, player.VelocityY = state.VelocityX, state.VelocityY
+	player.InputX, player.InputY = move.VectorX, move.VectorY
+}
+
+// applyInput 将玩家推进到输入时间并应用新的输入方向（调用方需持有 c.mu 写锁）
+func (c *GameClient) applyInput(player *LocalPlayerState, inputX, inputY float64, gameTime int64) {
+	state := c.advanceSample(player, playerSample(player), gameTime)
+	setPlayerSample(player, c.withInput(player, state, inputX, inputY))
+}
+
+// GetMovementParams 获取服务器下发的默认移动模型参数
+func (c *GameClient) GetMovementParams() protocol.MovementParams {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.movement.Params()
+}
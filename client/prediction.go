@@ -0,0 +1,112 @@
+package client
+
+import (
+	"log"
+	"math"
+	"syncServerDemo/protocol"
+)
+
+// pendingInput 本地玩家的输入
+type pendingInput struct {
+	Seq      uint32
+	VectorX  float64
+	VectorY  float64
+	GameTime int64
+	Acked    bool // 是否已收到服务器回显
+}
+
+// predictLocalMove 立即在本地应用自己的输入并保存，等待服务器确认（调用方需持有 c.mu 写锁）
+func (c *GameClient) predictLocalMove(input pendingInput) {
+	c.inputs = append(c.inputs, input)
+
+	player, exists := c.localPlayers[c.playerID]
+	if !exists {
+		return
+	}
+
+	c.applyInput(player, input.VectorX, input.VectorY, input.GameTime)
+}
+
+// acknowledgeInput 确认服务器回显的输入，返回该输入是否已在本地预测过（调用方需持有 c.mu 写锁）
+func (c *GameClient) acknowledgeInput(seq uint32) bool {
+	for i := range c.inputs {
+		if c.inputs[i].Seq == seq {
+			c.inputs[i].Acked = true
+			return true
+		}
+	}
+	return false
+}
+
+// resetInputs 清空输入记录，以玩家当前运动状态为基准（调用方需持有 c.mu 写锁）
+func (c *GameClient) resetInputs() {
+	c.inputs = nil
+	c.baseState = stateSample{}
+	if player, exists := c.localPlayers[c.playerID]; exists {
+		c.baseState = playerSample(player)
+	}
+}
+
+// reconcile 本地玩家的服务器校正
+// 以仲裁位置为基础，重放仲裁时间之后的输入得到新的模拟状态，与当前预测差距较大时才校正
+func (c *GameClient) reconcile(player *LocalPlayerState, update *protocol.PositionUpdateData) {
+	// 速度只取决于输入历史，从基准状态推进到仲裁时间得到当时的速度
+	state := c.baseState
+	for _, input := range c.inputs {
+		if input.GameTime > update.GameTime {
+			break
+		}
+		state = c.withInput(player, c.advanceSample(player, state, input.GameTime), input.VectorX, input.VectorY)
+	}
+	state = c.advanceSample(player, state, update.GameTime)
+	state.X = update.X
+	state.Y = update.Y
+
+	// 重放之后的输入
+	for _, input := range c.inputs {
+		if input.GameTime <= update.GameTime {
+			continue
+		}
+		state = c.withInput(player, c.advanceSample(player, state, input.GameTime), input.VectorX, input.VectorY)
+	}
+
+	now := c.timeSyncer.GetGameTime()
+	currentX, currentY := c.predictPosition(player, now)
+	reconciled := c.advanceSample(player, state, now)
+	distance := math.Hypot(reconciled.X-currentX, reconciled.Y-currentY)
+
+	if distance > c.config.CorrectionThreshold {
+		c.applyCorrection(player, state, now)
+		c.emit(PlayerCorrectedEvent{
+			PlayerID: player.PlayerID,
+			X:        reconciled.X,
+			Y:        reconciled.Y,
+			Error:    distance,
+			GameTime: now,
+			Local:    true,
+		})
+		log.Printf("[Client %s] Reconciled local player %s: replayed %d inputs, error: %.2f",
+			c.clientID, player.PlayerID, len(c.inputs), distance)
+	}
+
+	// 丢弃已确认且早于仲裁时间的输入，基准状态随之前移
+	for len(c.inputs) > 0 && c.inputs[0].Acked && c.inputs[0].GameTime <= update.GameTime {
+		input := c.inputs[0]
+		c.baseState = c.withInput(player, c.advanceSample(player, c.baseState, input.GameTime), input.VectorX, input.VectorY)
+		c.inputs = c.inputs[1:]
+	}
+}
+
+// GetPendingInputCount 获取尚未被服务器确认的输入数
+func (c *GameClient) GetPendingInputCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	count := 0
+	for _, input := range c.inputs {
+		if !input.Acked {
+			count++
+		}
+	}
+	return count
+}
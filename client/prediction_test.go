@@ -0,0 +1,57 @@
+package client
+
+import (
+	"math"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+	"time"
+)
+
+func TestReconcileReplaysPendingInputs(t *testing.T) {
+	c, clock := newTestClient(t)
+	c.SetSmoothing(SmoothingNone, 0, 0)
+	welcome(c, 0, map[string][2]float64{"alice": {0, 0}})
+
+	// 向右 100ms，向上 100ms，然后停下
+	c.Move(1, 0)
+	clock.Advance(100 * time.Millisecond)
+	c.Move(0, 1)
+	clock.Advance(100 * time.Millisecond)
+	c.Move(0, 0)
+	clock.Advance(100 * time.Millisecond)
+
+	// 服务器回显第一个输入
+	c.handleMessage(transport.NewMessage(protocol.MsgTypeMoveCommand, protocol.MoveData{
+		PlayerID: "alice", VectorX: 1, GameTime: 0, Seq: 1,
+	}))
+	if n := c.GetPendingInputCount(); n != 2 {
+		t.Fatalf("%d pending inputs after the first ack, want 2", n)
+	}
+
+	assertPosition := func(wantX, wantY float64) {
+		t.Helper()
+		x, y, _ := c.GetPlayerPosition("alice")
+		if math.Abs(x-wantX) > 1e-9 || math.Abs(y-wantY) > 1e-9 {
+			t.Errorf("position = (%v, %v), want (%v, %v)", x, y, wantX, wantY)
+		}
+	}
+
+	// 仲裁结果与预测一致，不校正
+	positionBatch(c, 50, map[string][2]float64{"alice": {0.5, 0}})
+	assertPosition(1, 1)
+	if len(c.inputs) != 2 {
+		t.Errorf("%d inputs kept, want the acknowledged input before the arbitration dropped", len(c.inputs))
+	}
+
+	// 服务器认为 alice 在 50 时位于 (3, 0)，之后的两个输入在此基础上重放
+	positionBatch(c, 50, map[string][2]float64{"alice": {3, 0}})
+	assertPosition(3.5, 1)
+	if n := c.GetPendingInputCount(); n != 2 {
+		t.Errorf("%d pending inputs after the correction, want 2", n)
+	}
+
+	// 之后的预测从校正后的状态继续
+	clock.Advance(100 * time.Millisecond)
+	assertPosition(3.5, 1)
+}
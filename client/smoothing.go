@@ -0,0 +1,123 @@
+package client
+
+import (
+	"math"
+	"time"
+)
+
+// SmoothingMode 校正平滑模式
+type SmoothingMode int
+
+const (
+	SmoothingNone        SmoothingMode = iota // 直接跳到校正后的位置
+	SmoothingExponential                      // 指数平滑，Duration 为时间常数
+	SmoothingLinear                           // 在 Duration 内线性收敛
+)
+
+// smoothingConfig 校正平滑配置
+type smoothingConfig struct {
+	mode          SmoothingMode
+	duration      time.Duration
+	snapThreshold float64 // 误差超过该值时直接跳变
+}
+
+// defaultSmoothing 默认平滑配置
+func defaultSmoothing() smoothingConfig {
+	return smoothingConfig{
+		mode:          SmoothingExponential,
+		duration:      150 * time.Millisecond,
+		snapThreshold: 5.0,
+	}
+}
+
+// SetSmoothing 设置校正平滑方式
+// 校正时模拟状态立即更新，渲染位置在 duration 内收敛到模拟位置；误差超过 snapThreshold 时直接跳变
+func (c *GameClient) SetSmoothing(mode SmoothingMode, duration time.Duration, snapThreshold float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.smoothing = smoothingConfig{
+		mode:          mode,
+		duration:      duration,
+		snapThreshold: snapThreshold,
+	}
+}
+
+// correctionFactor 计算渲染偏移在经过 elapsed 毫秒后剩余的比例
+func (c *GameClient) correctionFactor(elapsed int64) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	duration := float64(c.smoothing.duration.Milliseconds())
+	if duration <= 0 {
+		return 0
+	}
+
+	switch c.smoothing.mode {
+	case SmoothingExponential:
+		return math.Exp(-float64(elapsed) / duration)
+	case SmoothingLinear:
+		return math.Max(0, 1-float64(elapsed)/duration)
+	default:
+		return 0
+	}
+}
+
+// renderOffset 获取玩家在指定时间的渲染偏移
+func (c *GameClient) renderOffset(player *LocalPlayerState, gameTime int64) (float64, float64) {
+	if player.CorrectionX == 0 && player.CorrectionY == 0 {
+		return 0, 0
+	}
+
+	factor := c.correctionFactor(gameTime - player.CorrectionTime)
+	return player.CorrectionX * factor, player.CorrectionY * factor
+}
+
+// applyCorrection 校正模拟状态，并把校正前后的渲染位置差记为偏移，使渲染位置平滑收敛
+func (c *GameClient) applyCorrection(player *LocalPlayerState, state stateSample, now int64) {
+	// 校正前的渲染位置
+	oldX, oldY := c.predictPosition(player, now)
+	offsetX, offsetY := c.renderOffset(player, now)
+	renderX, renderY := oldX+offsetX, oldY+offsetY
+
+	setPlayerSample(player, state)
+
+	newX, newY := c.predictPosition(player, now)
+	player.CorrectionX = renderX - newX
+	player.CorrectionY = renderY - newY
+	player.CorrectionTime = now
+
+	if c.smoothing.mode == SmoothingNone ||
+		math.Hypot(player.CorrectionX, player.CorrectionY) > c.smoothing.snapThreshold {
+		player.CorrectionX = 0
+		player.CorrectionY = 0
+	}
+}
+
+// GetRenderPosition 获取玩家的渲染位置
+// 与 GetPlayerPosition 返回的模拟位置不同，渲染位置在校正后平滑收敛，避免画面跳变；
+// 开启插值时远程玩家按延迟后的时间在权威状态之间插值
+func (c *GameClient) GetRenderPosition(playerID string) (x, y float64, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	player, exists := c.localPlayers[playerID]
+	if !exists {
+		return 0, 0, false
+	}
+
+	x, y = c.renderPosition(player, c.timeSyncer.GetGameTime())
+	return x, y, true
+}
+
+// renderPosition 计算玩家在指定游戏时间的渲染位置（调用方需持有 c.mu）
+func (c *GameClient) renderPosition(player *LocalPlayerState, gameTime int64) (float64, float64) {
+	if c.interpolation.enabled && player.PlayerID != c.playerID {
+		return c.interpolatePosition(player, gameTime-c.interpolation.delay.Milliseconds())
+	}
+
+	x, y := c.predictPosition(player, gameTime)
+	offsetX, offsetY := c.renderOffset(player, gameTime)
+	return x + offsetX, y + offsetY
+}
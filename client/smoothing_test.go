@@ -0,0 +1,54 @@
+package client
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCorrectionSmoothing(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    SmoothingMode
+		snap    float64
+		elapsed time.Duration
+		want    float64 // bob 的渲染 X，模拟位置已校正到 2
+	}{
+		{"none", SmoothingNone, 5, 0, 2},
+		{"exponential start", SmoothingExponential, 5, 0, 0},
+		{"exponential one time constant", SmoothingExponential, 5, 100 * time.Millisecond, 2 - 2/math.E},
+		{"linear halfway", SmoothingLinear, 5, 50 * time.Millisecond, 1},
+		{"linear done", SmoothingLinear, 5, 100 * time.Millisecond, 2},
+		{"linear after duration", SmoothingLinear, 5, 300 * time.Millisecond, 2},
+		{"snap", SmoothingExponential, 1, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, clock := newTestClient(t)
+			c.SetSmoothing(tt.mode, 100*time.Millisecond, tt.snap)
+			welcome(c, 0, map[string][2]float64{"alice": {0, 0}, "bob": {0, 0}})
+
+			// 仲裁结果与本地模拟相差 2，超过默认校正阈值
+			positionBatch(c, 0, map[string][2]float64{"bob": {2, 0}})
+			clock.Advance(tt.elapsed)
+
+			if x, _, _ := c.GetPlayerPosition("bob"); x != 2 {
+				t.Errorf("simulated x = %v, want the corrected 2", x)
+			}
+			if x, _, _ := c.GetRenderPosition("bob"); math.Abs(x-tt.want) > 1e-9 {
+				t.Errorf("render x = %v, want %v", x, tt.want)
+			}
+		})
+	}
+}
+
+func TestCorrectionBelowThreshold(t *testing.T) {
+	c, _ := newTestClient(t, WithCorrectionThreshold(0.5))
+	welcome(c, 0, map[string][2]float64{"alice": {0, 0}, "bob": {0, 0}})
+
+	// 误差不超过阈值时保持本地模拟
+	positionBatch(c, 0, map[string][2]float64{"bob": {0.3, 0}})
+	if x, _, _ := c.GetPlayerPosition("bob"); x != 0 {
+		t.Errorf("simulated x = %v, want the uncorrected 0", x)
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"sort"
+)
+
+// EntitySnapshot 实体在快照时间的状态
+type EntitySnapshot struct {
+	PlayerID  string
+	X         float64 // 模拟位置
+	Y         float64
+	RenderX   float64 // 渲染位置（含校正平滑和插值）
+	RenderY   float64
+	VelocityX float64 // 快照时间的速度，过期实体为零
+	VelocityY float64
+	InputX    float64 // 当前输入方向
+	InputY    float64
+	Status    string // 连接状态
+	Local     bool   // 是否为本地玩家
+
+	LastAuthoritativeTime int64 // 最后一次收到权威状态的游戏时间
+	Stale                 bool  // 在快照时间是否已超过外推上限
+}
+
+// WorldSnapshot 客户端在某一游戏时间看到的世界
+type WorldSnapshot struct {
+	GameTime int64
+	RoomID   string
+	Entities []EntitySnapshot // 按玩家ID排序
+}
+
+// Snapshot 获取所有已知实体在指定游戏时间的状态
+// 所有实体在同一把锁内按同一时间计算，结果之间相互一致
+func (c *GameClient) Snapshot(atGameTime int64) WorldSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snap := WorldSnapshot{
+		GameTime: atGameTime,
+		RoomID:   c.roomID,
+		Entities: make([]EntitySnapshot, 0, len(c.localPlayers)),
+	}
+	for _, player := range c.localPlayers {
+		state := c.predictState(player, atGameTime)
+		renderX, renderY := c.renderPosition(player, atGameTime)
+		snap.Entities = append(snap.Entities, EntitySnapshot{
+			PlayerID:  player.PlayerID,
+			X:         state.X,
+			Y:         state.Y,
+			RenderX:   renderX,
+			RenderY:   renderY,
+			VelocityX: state.VelocityX,
+			VelocityY: state.VelocityY,
+			InputX:    state.InputX,
+			InputY:    state.InputY,
+			Status:    player.Status,
+			Local:     player.PlayerID == c.playerID,
+
+			LastAuthoritativeTime: player.LastAuthoritativeTime,
+			Stale:                 c.isStale(player, atGameTime),
+		})
+	}
+
+	sort.Slice(snap.Entities, func(i, j int) bool {
+		return snap.Entities[i].PlayerID < snap.Entities[j].PlayerID
+	})
+	return snap
+}
+
+// SnapshotNow 获取所有已知实体在当前游戏时间的状态
+func (c *GameClient) SnapshotNow() WorldSnapshot {
+	return c.Snapshot(c.timeSyncer.GetGameTime())
+}
+
+// Entity 在快照中查找实体
+func (s *WorldSnapshot) Entity(playerID string) (EntitySnapshot, bool) {
+	i := sort.Search(len(s.Entities), func(i int) bool {
+		return s.Entities[i].PlayerID >= playerID
+	})
+	if i < len(s.Entities) && s.Entities[i].PlayerID == playerID {
+		return s.Entities[i], true
+	}
+	return EntitySnapshot{}, false
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"reflect"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	c, clock := newTestClient(t)
+	c.SetExtrapolationLimit(200 * time.Millisecond)
+	welcome(c, 0, map[string][2]float64{"alice": {0, 0}, "bob": {0, 0}, "charlie": {5, 5}})
+	for _, msg := range []transport.Message{
+		transport.NewMessage(protocol.MsgTypeMoveCommand, protocol.MoveData{PlayerID: "bob", VectorX: 1, GameTime: 0}),
+		transport.NewMessage(protocol.MsgTypePlayerStatus, protocol.PlayerStatusData{
+			PlayerID: "charlie", Status: protocol.PlayerStatusReconnecting,
+		}),
+	} {
+		c.handleMessage(msg)
+	}
+	c.Move(0, 1)
+	clock.Advance(100 * time.Millisecond)
+	positionBatch(c, 200, map[string][2]float64{"charlie": {5, 5}})
+
+	snap := c.Snapshot(300)
+	want := WorldSnapshot{
+		GameTime: 300,
+		RoomID:   "room",
+		Entities: []EntitySnapshot{
+			{
+				PlayerID: "alice", Y: 3, RenderY: 3, VelocityY: 10, InputY: 1,
+				Status: protocol.PlayerStatusOnline, Local: true,
+			},
+			// bob 在 200 达到外推上限，停在上限处且速度为零
+			{
+				PlayerID: "bob", X: 2, RenderX: 2, InputX: 1,
+				Status: protocol.PlayerStatusOnline, Stale: true,
+			},
+			{
+				PlayerID: "charlie", X: 5, Y: 5, RenderX: 5, RenderY: 5,
+				Status: protocol.PlayerStatusReconnecting, LastAuthoritativeTime: 200,
+			},
+		},
+	}
+	if !reflect.DeepEqual(snap, want) {
+		t.Errorf("snapshot =\n%+v\nwant\n%+v", snap, want)
+	}
+
+	if e, ok := snap.Entity("bob"); !ok || e.PlayerID != "bob" {
+		t.Errorf("Entity(bob) = %+v, %v", e, ok)
+	}
+	if _, ok := snap.Entity("dave"); ok {
+		t.Error("Entity(dave) found in snapshot")
+	}
+	if now := c.SnapshotNow(); now.GameTime != 100 {
+		t.Errorf("SnapshotNow game time = %d, want 100", now.GameTime)
+	}
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"log"
+	"time"
+)
+
+// StaleHandler 远程玩家进入或离开过期状态时的回调，可用于在界面上淡出实体
+type StaleHandler func(playerID string, stale bool)
+
+// staleListener 将 StaleHandler 适配为只关心过期事件的监听者
+type staleListener struct {
+	BaseListener
+	handler StaleHandler
+}
+
+func (l staleListener) OnPlayerStale(event PlayerStaleEvent) {
+	l.handler(event.PlayerID, event.Stale)
+}
+
+// SetStaleHandler 设置过期状态变化回调，替换之前设置的回调，nil 表示取消
+// 等价于通过 AddListener 监听 PlayerStaleEvent，回调在事件协程中执行
+func (c *GameClient) SetStaleHandler(handler StaleHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.removeStaleHandler != nil {
+		c.removeStaleHandler()
+		c.removeStaleHandler = nil
+	}
+	if handler != nil {
+		c.removeStaleHandler = c.AddListener(staleListener{handler: handler})
+	}
+}
+
+// SetExtrapolationLimit 设置远程玩家的最大外推时间
+// 超过该时间没有收到权威状态的玩家停在外推上限处并标记为过期，0 表示不限制
+func (c *GameClient) SetExtrapolationLimit(limit time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.extrapolationLimit = limit
+}
+
+// touchAuthoritative 记录收到权威状态的游戏时间（调用方需持有 c.mu 写锁）
+func (c *GameClient) touchAuthoritative(player *LocalPlayerState, gameTime int64) {
+	if gameTime > player.LastAuthoritativeTime {
+		player.LastAuthoritativeTime = gameTime
+	}
+}
+
+// extrapolationCap 获取玩家允许外推到的最晚时间，不限制时返回 false
+func (c *GameClient) extrapolationCap(player *LocalPlayerState) (int64, bool) {
+	if c.extrapolationLimit <= 0 || player.PlayerID == c.playerID {
+		return 0, false
+	}
+	return player.LastAuthoritativeTime + c.extrapolationLimit.Milliseconds(), true
+}
+
+// isStale 判断玩家在指定时间是否过期
+func (c *GameClient) isStale(player *LocalPlayerState, gameTime int64) bool {
+	limit, ok := c.extrapolationCap(player)
+	return ok && gameTime > limit
+}
+
+// checkStaleness 检查过期状态变化并发出事件
+func (c *GameClient) checkStaleness() {
+	gameTime := c.timeSyncer.GetGameTime()
+
+	type staleChange struct {
+		playerID string
+		stale    bool
+	}
+	var changes []staleChange
+
+	c.mu.Lock()
+	for _, player := range c.localPlayers {
+		stale := c.isStale(player, gameTime)
+		if stale != player.Stale {
+			player.Stale = stale
+			changes = append(changes, staleChange{player.PlayerID, stale})
+		}
+	}
+	c.mu.Unlock()
+
+	for _, change := range changes {
+		if change.stale {
+			log.Printf("[Client %s] Player %s is stale, extrapolation frozen", c.clientID, change.playerID)
+		} else {
+			log.Printf("[Client %s] Player %s is fresh again", c.clientID, change.playerID)
+		}
+		c.emit(PlayerStaleEvent{PlayerID: change.playerID, Stale: change.stale})
+	}
+}
+
+// GetPlayerLag 获取距离玩家最后一次权威状态的时间，以及是否已过期
+// 可用于在界面上显示延迟指示
+func (c *GameClient) GetPlayerLag(playerID string) (lag time.Duration, stale bool, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	player, exists := c.localPlayers[playerID]
+	if !exists {
+		return 0, false, false
+	}
+
+	gameTime := c.timeSyncer.GetGameTime()
+	lag = time.Duration(max(gameTime-player.LastAuthoritativeTime, 0)) * time.Millisecond
+	return lag, c.isStale(player, gameTime), true
+}
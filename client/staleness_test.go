@@ -0,0 +1,67 @@
+package client
+
+import (
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+	"time"
+)
+
+func TestStaleMarking(t *testing.T) {
+	c, clock := newTestClient(t)
+	c.SetExtrapolationLimit(200 * time.Millisecond)
+	changes := make(chan PlayerStaleEvent, 10)
+	c.SetStaleHandler(func(playerID string, stale bool) {
+		changes <- PlayerStaleEvent{PlayerID: playerID, Stale: stale}
+	})
+	welcome(c, 0, map[string][2]float64{"alice": {0, 0}, "bob": {0, 0}})
+	c.handleMessage(transport.NewMessage(protocol.MsgTypeMoveCommand, protocol.MoveData{
+		PlayerID: "bob", VectorX: 1, GameTime: 0,
+	}))
+
+	expectChange := func(want PlayerStaleEvent) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Errorf("stale change = %+v, want %+v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no stale change, want %+v", want)
+		}
+	}
+
+	clock.Advance(150 * time.Millisecond)
+	c.ReportNow()
+	if lag, stale, _ := c.GetPlayerLag("bob"); lag != 150*time.Millisecond || stale {
+		t.Errorf("lag %v stale %v, want 150ms and fresh", lag, stale)
+	}
+
+	// 超过外推上限后停在上限处
+	clock.Advance(150 * time.Millisecond)
+	c.ReportNow()
+	expectChange(PlayerStaleEvent{PlayerID: "bob", Stale: true})
+	if x, _, _ := c.GetPlayerPosition("bob"); x != 2 {
+		t.Errorf("stale bob at x = %v, want frozen at 2", x)
+	}
+	// 本地玩家不会过期
+	if _, stale, _ := c.GetPlayerLag("alice"); stale {
+		t.Error("local player marked stale")
+	}
+
+	// 收到新的权威状态后恢复
+	positionBatch(c, 300, map[string][2]float64{"bob": {3, 0}})
+	c.ReportNow()
+	expectChange(PlayerStaleEvent{PlayerID: "bob", Stale: false})
+	if lag, stale, _ := c.GetPlayerLag("bob"); lag != 0 || stale {
+		t.Errorf("lag %v stale %v after an update, want 0 and fresh", lag, stale)
+	}
+
+	// 状态不变时不重复通知
+	c.ReportNow()
+	select {
+	case got := <-changes:
+		t.Errorf("unexpected stale change %+v", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
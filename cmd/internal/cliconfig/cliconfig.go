@@ -0,0 +1,79 @@
+// Package cliconfig 命令行工具的配置加载
+// 配置依次来自默认值、JSON 配置文件（-config 指定）和命令行参数，后者覆盖前者
+package cliconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Duration 配置文件中以 "500ms"、"1s" 形式书写的时间长度，同时可作为命令行参数
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// Set 实现 flag.Value
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"500ms\": %w", err)
+	}
+	return d.Set(s)
+}
+
+// Parse 解析命令行参数和配置文件
+// bind 把配置字段绑定到参数集，参数的初始值即为默认值；cfg 为同一份配置，用于解码配置文件。
+// 配置文件中的未知字段视为错误，避免拼写错误被静默忽略
+func Parse(name string, args []string, cfg interface{}, bind func(fs *flag.FlagSet)) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := fs.String("config", "", "JSON 配置文件路径，命令行参数优先")
+	bind(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *configPath == "" {
+		return nil
+	}
+
+	// 记下显式给出的参数，配置文件加载后重新应用
+	explicit := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("invalid config %s: %w", *configPath, err)
+	}
+
+	for flagName, value := range explicit {
+		if err := fs.Set(flagName, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
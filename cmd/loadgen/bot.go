@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"syncServerDemo/client"
+	"time"
+)
+
+// 移动模式
+const (
+	PatternIdle      = "idle"      // 不移动
+	PatternRandom    = "random"    // 随机游走
+	PatternWaypoints = "waypoints" // 依次走向路径点
+	PatternMix       = "mix"       // 按顺序轮流分配以上模式
+)
+
+// bot 无界面客户端的输入驱动
+type bot struct {
+	client    *client.GameClient
+	playerID  string
+	pattern   string
+	rng       *rand.Rand
+	interval  time.Duration // 随机游走的平均换向间隔
+	waypoints [][2]float64
+	next      int // 下一个路径点
+
+	vectorX float64 // 最后一次发送的输入方向
+	vectorY float64
+	moves   int
+}
+
+// patternFor 第 i 个客户端使用的移动模式
+func patternFor(pattern string, i int) string {
+	if pattern != PatternMix {
+		return pattern
+	}
+	return []string{PatternRandom, PatternWaypoints, PatternIdle}[i%3]
+}
+
+// parseWaypoints 解析 "x,y;x,y" 格式的路径点
+func parseWaypoints(s string) ([][2]float64, error) {
+	var points [][2]float64
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		coords := strings.Split(part, ",")
+		if len(coords) != 2 {
+			return nil, fmt.Errorf("invalid waypoint %q", part)
+		}
+		x, err := strconv.ParseFloat(strings.TrimSpace(coords[0]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid waypoint %q: %w", part, err)
+		}
+		y, err := strconv.ParseFloat(strings.TrimSpace(coords[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid waypoint %q: %w", part, err)
+		}
+		points = append(points, [2]float64{x, y})
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no waypoints")
+	}
+	return points, nil
+}
+
+// run 按移动模式驱动输入，直到 done 关闭
+func (b *bot) run(done <-chan struct{}) {
+	if b.pattern == PatternIdle {
+		<-done
+		return
+	}
+
+	tick := 200 * time.Millisecond
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	var nextTurn time.Time
+	for {
+		select {
+		case now := <-ticker.C:
+			switch b.pattern {
+			case PatternRandom:
+				if now.After(nextTurn) {
+					b.randomTurn()
+					// 换向间隔在平均值的 50%~150% 之间
+					nextTurn = now.Add(time.Duration(float64(b.interval) * (0.5 + b.rng.Float64())))
+				}
+			case PatternWaypoints:
+				b.steer()
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// randomTurn 随机选择新的方向，有一定概率停下
+func (b *bot) randomTurn() {
+	if b.rng.Float64() < 0.2 {
+		b.move(0, 0)
+		return
+	}
+	angle := b.rng.Float64() * 2 * math.Pi
+	b.move(math.Cos(angle), math.Sin(angle))
+}
+
+// steer 朝下一个路径点移动，到达后切换到下一个
+func (b *bot) steer() {
+	x, y, ok := b.client.GetPlayerPosition(b.playerID)
+	if !ok {
+		return
+	}
+
+	target := b.waypoints[b.next]
+	dx, dy := target[0]-x, target[1]-y
+	dist := math.Hypot(dx, dy)
+	if dist < 1.0 {
+		b.next = (b.next + 1) % len(b.waypoints)
+		target = b.waypoints[b.next]
+		dx, dy = target[0]-x, target[1]-y
+		dist = math.Hypot(dx, dy)
+	}
+	if dist < 1e-6 {
+		b.move(0, 0)
+		return
+	}
+
+	// 方向变化不大时不重复发送
+	vx, vy := dx/dist, dy/dist
+	if math.Hypot(vx-b.vectorX, vy-b.vectorY) > 0.1 {
+		b.move(vx, vy)
+	}
+}
+
+// move 发送输入
+func (b *bot) move(vectorX, vectorY float64) {
+	b.vectorX, b.vectorY = vectorX, vectorY
+	b.moves++
+	b.client.Move(vectorX, vectorY)
+}
@@ -0,0 +1,221 @@
+// loadgen 启动一个服务器和大量无界面客户端，按移动模式产生负载并输出统计
+//
+// 用法：
+//
+//	go run ./cmd/loadgen -clients 50 -duration 30s -pattern mix [-transport netsim -latency 50ms -loss 0.01] [-consistency-out soak.csv]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"syncServerDemo/client"
+	"syncServerDemo/consistency"
+	"syncServerDemo/server"
+	"syncServerDemo/transport"
+	"time"
+)
+
+func main() {
+	numClients := flag.Int("clients", 20, "客户端数量")
+	duration := flag.Duration("duration", 20*time.Second, "运行时间（全部客户端加入后开始计时）")
+	pattern := flag.String("pattern", PatternMix, "移动模式：idle、random、waypoints 或 mix")
+	waypointSpec := flag.String("waypoints", "0,0;20,0;20,20;0,20", "路径点，格式 x,y;x,y")
+	turnInterval := flag.Duration("turn-interval", 2*time.Second, "随机游走的平均换向间隔")
+	spawnInterval := flag.Duration("spawn-interval", 20*time.Millisecond, "相邻客户端加入的间隔")
+	sampleInterval := flag.Duration("sample-interval", 500*time.Millisecond, "一致性采样间隔")
+	consistencyOut := flag.String("consistency-out", "", "导出一致性采样的文件，按扩展名选择 .csv 或 .json")
+	transportKind := flag.String("transport", "local", "服务器传输层：local 或 netsim")
+	latency := flag.Duration("latency", 30*time.Millisecond, "netsim 单向延迟")
+	jitter := flag.Duration("jitter", 10*time.Millisecond, "netsim 延迟抖动")
+	loss := flag.Float64("loss", 0, "netsim 丢包率")
+	overflowName := flag.String("overflow", transport.DropNewest.String(), "发送队列满时的处理策略：drop-newest、drop-oldest、disconnect 或 coalesce")
+	seed := flag.Int64("seed", 1, "随机种子")
+	verbose := flag.Bool("v", false, "输出服务器和客户端日志")
+	flag.Parse()
+
+	switch *pattern {
+	case PatternIdle, PatternRandom, PatternWaypoints, PatternMix:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown pattern %q\n", *pattern)
+		os.Exit(2)
+	}
+	waypoints, err := parseWaypoints(*waypointSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing waypoints: %v\n", err)
+		os.Exit(2)
+	}
+	overflow, err := transport.ParseOverflowPolicy(*overflowName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing overflow policy: %v\n", err)
+		os.Exit(2)
+	}
+	if ext := filepath.Ext(*consistencyOut); *consistencyOut != "" && ext != ".csv" && ext != ".json" {
+		fmt.Fprintf(os.Stderr, "Consistency output must be .csv or .json, got %q\n", *consistencyOut)
+		os.Exit(2)
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	// 客户端直接使用本地传输层，服务器一侧可以叠加网络模拟
+	localTransport := transport.NewLocalTransport()
+	var serverTransport transport.Transport = localTransport
+	switch *transportKind {
+	case "local":
+	case "netsim":
+		sim := transport.NewNetworkSimulator(localTransport, *seed)
+		sim.SetDefaultConditions(transport.Symmetric(transport.LinkConditions{
+			Latency:  *latency,
+			Jitter:   *jitter,
+			LossRate: *loss,
+		}))
+		serverTransport = sim
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown transport %q\n", *transportKind)
+		os.Exit(2)
+	}
+
+	counter := newCountingTransport(serverTransport)
+	counter.SetOverflowPolicy("", overflow)
+	gameServer := server.NewGameServer(counter)
+	counter.gameClock = gameServer.GetGameTime
+	if err := gameServer.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start server: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Load test: %d clients, %s, pattern %s, transport %s, overflow %s\n",
+		*numClients, *duration, *pattern, *transportKind, overflow)
+
+	// 逐个加入客户端
+	var localCorrections, remoteCorrections histogram
+	clients := make([]*client.GameClient, 0, *numClients)
+	bots := make([]*bot, 0, *numClients)
+	done := make(chan struct{})
+	for i := 0; i < *numClients; i++ {
+		clientID := fmt.Sprintf("bot_%d", i)
+		playerID := fmt.Sprintf("Bot%d", i)
+		if err := counter.Register(clientID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error registering %s: %v\n", clientID, err)
+			os.Exit(1)
+		}
+
+		c := client.NewGameClient(clientID, playerID, localTransport)
+		c.AddListener(correctionCounter{local: &localCorrections, remote: &remoteCorrections})
+		c.Start()
+		clients = append(clients, c)
+
+		b := &bot{
+			client:    c,
+			playerID:  playerID,
+			pattern:   patternFor(*pattern, i),
+			rng:       rand.New(rand.NewSource(*seed + int64(i))),
+			interval:  *turnInterval,
+			waypoints: waypoints,
+			next:      i % len(waypoints),
+		}
+		bots = append(bots, b)
+		go b.run(done)
+
+		time.Sleep(*spawnInterval)
+	}
+
+	// 运行并定期采样一致性
+	tracker := consistency.NewTracker()
+	sources := consistency.Clients(clients)
+	start := time.Now()
+	inBefore, outBefore := counter.counts()
+	ticker := time.NewTicker(*sampleInterval)
+	deadline := time.After(*duration)
+sampling:
+	for {
+		select {
+		case <-ticker.C:
+			tracker.Sample(sources, gameServer.GetGameTime())
+		case <-deadline:
+			break sampling
+		}
+	}
+	ticker.Stop()
+	elapsed := time.Since(start)
+	inAfter, outAfter := counter.counts()
+
+	close(done)
+	for _, c := range clients {
+		c.Stop()
+	}
+	gameServer.Stop()
+
+	// 输出统计
+	moves := 0
+	for _, b := range bots {
+		moves += b.moves
+	}
+	fmt.Printf("\nRan %.1fs with %d players online, %d moves sent\n",
+		elapsed.Seconds(), gameServer.GetPlayerCount(), moves)
+
+	fmt.Println("\nMessages (server side, during the measured window):")
+	printRates("in", inBefore, inAfter, elapsed)
+	printRates("out", outBefore, outAfter, elapsed)
+
+	fmt.Println("\nArbitration latency, report to broadcast (ms):")
+	fmt.Printf("  %s\n", counter.arbitrationLatency.summary())
+
+	fmt.Println("\nCorrection error (units):")
+	fmt.Printf("  local  %s\n", localCorrections.summary())
+	fmt.Printf("  remote %s\n", remoteCorrections.summary())
+
+	fmt.Println("\nCross-client deviation per entity (units):")
+	fmt.Printf("  %s\n", tracker.Overall())
+
+	if *consistencyOut != "" {
+		if err := writeConsistency(tracker, *consistencyOut); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing consistency samples: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("  samples written to %s\n", *consistencyOut)
+	}
+}
+
+// writeConsistency 按扩展名导出一致性采样
+func writeConsistency(tracker *consistency.Tracker, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(path) == ".json" {
+		err = tracker.WriteJSON(f)
+	} else {
+		err = tracker.WriteCSV(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// printRates 输出按类型统计的消息速率
+func printRates(direction string, before, after map[string]int, elapsed time.Duration) {
+	types := make([]string, 0, len(after))
+	total := 0
+	for msgType, n := range after {
+		types = append(types, msgType)
+		total += n - before[msgType]
+	}
+	sort.Strings(types)
+
+	fmt.Printf("  %-4s %8d  %9.1f/s\n", direction, total, float64(total)/elapsed.Seconds())
+	for _, msgType := range types {
+		n := after[msgType] - before[msgType]
+		if n == 0 {
+			continue
+		}
+		fmt.Printf("    %-16s %8d  %9.1f/s\n", msgType, n, float64(n)/elapsed.Seconds())
+	}
+}
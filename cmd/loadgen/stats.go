@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"syncServerDemo/client"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+)
+
+// histogram 数值分布
+type histogram struct {
+	values []float64
+	mu     sync.Mutex
+}
+
+func (h *histogram) add(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.values = append(h.values, v)
+}
+
+// summary 数量、百分位和最大值
+func (h *histogram) summary() string {
+	h.mu.Lock()
+	values := append([]float64(nil), h.values...)
+	h.mu.Unlock()
+
+	if len(values) == 0 {
+		return "n=0"
+	}
+	sort.Float64s(values)
+	percentile := func(p float64) float64 {
+		return values[int(math.Ceil(p*float64(len(values))))-1]
+	}
+	return fmt.Sprintf("n=%d p50=%.3f p90=%.3f p99=%.3f max=%.3f",
+		len(values), percentile(0.50), percentile(0.90), percentile(0.99), values[len(values)-1])
+}
+
+// countingTransport 统计服务器收发消息的传输层包装
+type countingTransport struct {
+	transport.Transport
+	gameClock func() int64
+
+	clients    map[string]bool
+	in         map[string]int
+	out        map[string]int
+	lastUpdate map[string]int64 // 每个玩家最后一次仲裁结果的游戏时间
+	mu         sync.Mutex
+
+	arbitrationLatency histogram // 上报时间到广播仲裁结果的延迟（毫秒）
+}
+
+func newCountingTransport(inner transport.Transport) *countingTransport {
+	return &countingTransport{
+		Transport:  inner,
+		gameClock:  func() int64 { return 0 },
+		clients:    make(map[string]bool),
+		in:         make(map[string]int),
+		out:        make(map[string]int),
+		lastUpdate: make(map[string]int64),
+	}
+}
+
+// countOut 记录发出的消息
+func (t *countingTransport) countOut(msg transport.Message, recipients int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.out[msg.GetType()] += recipients
+	switch data := msg.GetData().(type) {
+	case protocol.PositionUpdateData:
+		t.countArbitration(data)
+	case protocol.PositionBatchData:
+		for _, update := range data.Positions {
+			t.countArbitration(update)
+		}
+	}
+}
+
+// countArbitration 记录仲裁延迟，同一次仲裁结果发给多个客户端只统计一次（调用方需持有 t.mu）
+func (t *countingTransport) countArbitration(update protocol.PositionUpdateData) {
+	if t.lastUpdate[update.PlayerID] != update.GameTime {
+		t.lastUpdate[update.PlayerID] = update.GameTime
+		t.arbitrationLatency.add(float64(t.gameClock() - update.GameTime))
+	}
+}
+
+func (t *countingTransport) Send(clientID string, msg transport.Message) error {
+	t.countOut(msg, 1)
+	return t.Transport.Send(clientID, msg)
+}
+
+func (t *countingTransport) Broadcast(msg transport.Message, excludeID string) error {
+	t.mu.Lock()
+	recipients := len(t.clients)
+	if t.clients[excludeID] {
+		recipients--
+	}
+	t.mu.Unlock()
+
+	t.countOut(msg, recipients)
+	return t.Transport.Broadcast(msg, excludeID)
+}
+
+func (t *countingTransport) Receive() (string, transport.Message, error) {
+	clientID, msg, err := t.Transport.Receive()
+	if err == nil {
+		t.mu.Lock()
+		t.in[msg.GetType()]++
+		t.mu.Unlock()
+	}
+	return clientID, msg, err
+}
+
+func (t *countingTransport) Register(clientID string) error {
+	t.mu.Lock()
+	t.clients[clientID] = true
+	t.mu.Unlock()
+	return t.Transport.Register(clientID)
+}
+
+func (t *countingTransport) Unregister(clientID string) error {
+	t.mu.Lock()
+	delete(t.clients, clientID)
+	t.mu.Unlock()
+	return t.Transport.Unregister(clientID)
+}
+
+// SetOverflowPolicy 转发给底层传输层，底层不支持溢出策略时忽略
+func (t *countingTransport) SetOverflowPolicy(clientID string, policy transport.OverflowPolicy) {
+	if oc, ok := t.Transport.(transport.OverflowController); ok {
+		oc.SetOverflowPolicy(clientID, policy)
+	}
+}
+
+// SetOverflowHandler 转发给底层传输层，底层不支持溢出策略时忽略
+func (t *countingTransport) SetOverflowHandler(handler transport.OverflowHandler) {
+	if oc, ok := t.Transport.(transport.OverflowController); ok {
+		oc.SetOverflowHandler(handler)
+	}
+}
+
+// counts 获取收发计数
+func (t *countingTransport) counts() (in, out map[string]int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	in = make(map[string]int, len(t.in))
+	for msgType, n := range t.in {
+		in[msgType] = n
+	}
+	out = make(map[string]int, len(t.out))
+	for msgType, n := range t.out {
+		out[msgType] = n
+	}
+	return in, out
+}
+
+// correctionCounter 统计客户端校正
+type correctionCounter struct {
+	client.BaseListener
+	local  *histogram
+	remote *histogram
+}
+
+func (c correctionCounter) OnPlayerCorrected(event client.PlayerCorrectedEvent) {
+	if event.Local {
+		c.local.add(event.Error)
+		return
+	}
+	c.remote.add(event.Error)
+}
@@ -0,0 +1,69 @@
+// replay 回放录制的传输层流量并对比仲裁结果
+//
+// 用法：
+//
+//	go run ./cmd/replay -recording session.jsonl [-tolerance 0.001] [-v]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"syncServerDemo/replay"
+	"syncServerDemo/transport"
+)
+
+func main() {
+	recordingPath := flag.String("recording", "", "录制文件路径")
+	tolerance := flag.Float64("tolerance", 0.001, "仲裁结果允许的位置误差")
+	verbose := flag.Bool("v", false, "输出服务器日志和每次仲裁的结果")
+	flag.Parse()
+
+	if *recordingPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	entries, err := transport.ReadRecording(*recordingPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading recording: %v\n", err)
+		os.Exit(1)
+	}
+
+	result, err := replay.Run(entries, replay.Options{Tolerance: *tolerance})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error replaying recording: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Replayed %d entries (%d client messages), %d recorded / %d replayed arbitration ticks\n",
+		len(entries), result.Inbound, len(result.Recorded), len(result.Replayed))
+
+	if *verbose {
+		for tick, results := range result.Replayed {
+			for _, r := range results {
+				fmt.Printf("  tick %d %s: (%.2f, %.2f) at %d\n", tick, r.PlayerID, r.X, r.Y, r.GameTime)
+			}
+		}
+		for id, vc := range result.Clients {
+			fmt.Printf("  client %s (%s): %d messages\n", id, vc.PlayerID, len(vc.Received))
+		}
+	}
+
+	if len(result.Diffs) == 0 {
+		fmt.Println("✓ Arbitration results match the recording")
+		return
+	}
+
+	fmt.Printf("⚠ %d arbitration differences:\n", len(result.Diffs))
+	for _, diff := range result.Diffs {
+		fmt.Printf("  %s\n", diff)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,108 @@
+// scenario 运行声明式的同步场景文件并检查期望
+//
+// 用法：
+//
+//	go run ./cmd/scenario [-v] scenarios/*.json
+//
+// 场景在确定性模拟器中运行，相同的文件每次得到相同的结果。任一场景失败时退出码为 1。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"syncServerDemo/sim"
+)
+
+func main() {
+	verbose := flag.Bool("v", false, "输出服务器和客户端日志、结束时的世界状态和消息计数")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-v] scenario.json...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	failed := 0
+	for _, path := range flag.Args() {
+		if !runFile(path, *verbose) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d of %d scenarios failed\n", failed, flag.NArg())
+		os.Exit(1)
+	}
+	fmt.Printf("\nAll %d scenarios passed\n", flag.NArg())
+}
+
+// runFile 运行一个场景文件，返回是否通过
+func runFile(path string, verbose bool) bool {
+	scenario, err := sim.LoadScenario(path)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return false
+	}
+
+	result, err := scenario.Run()
+	if err != nil {
+		fmt.Printf("✗ %s: %v\n", path, err)
+		return false
+	}
+	defer result.Harness.Close()
+
+	if verbose {
+		printState(result)
+	}
+
+	if result.Passed() {
+		fmt.Printf("✓ %s (%s, %d clients)\n", scenario.Name, scenario.Duration, len(scenario.Clients))
+		return true
+	}
+	fmt.Printf("✗ %s\n", scenario.Name)
+	for _, failure := range result.Failures {
+		fmt.Printf("    %s\n", failure)
+	}
+	return false
+}
+
+// printState 输出结束时各客户端的世界视图和消息计数
+func printState(result *sim.Result) {
+	h := result.Harness
+	fmt.Printf("=== %s ===\n", result.Scenario.Name)
+	if result.Scenario.Description != "" {
+		fmt.Println(result.Scenario.Description)
+	}
+
+	gameTime := h.GameTime()
+	for _, playerID := range h.OnlinePlayers() {
+		fmt.Printf("%s sees at game time %d:\n", playerID, gameTime)
+		for _, entity := range h.Client(playerID).Snapshot(gameTime).Entities {
+			fmt.Printf("  %s: (%.3f, %.3f) %s\n", entity.PlayerID, entity.X, entity.Y, entity.Status)
+		}
+	}
+
+	stats := h.Network().AllStats()
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Println("messages:")
+	for _, key := range keys {
+		s := stats[key]
+		fmt.Printf("  %-24s sent=%d delivered=%d dropped=%d\n", key, s.Sent, s.Delivered, s.Dropped)
+	}
+}
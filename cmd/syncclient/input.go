@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"syncServerDemo/client"
+	"time"
+)
+
+// scriptStep 脚本中的一次输入
+type scriptStep struct {
+	At      time.Duration // 相对加入时刻的偏移
+	VectorX float64
+	VectorY float64
+}
+
+// parseScript 解析 "1s:1,0;3s:0,0" 格式的输入脚本，结果按时间排序
+func parseScript(s string) ([]scriptStep, error) {
+	var steps []scriptStep
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		at, vector, found := strings.Cut(part, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid script step %q", part)
+		}
+		offset, err := time.ParseDuration(strings.TrimSpace(at))
+		if err != nil {
+			return nil, fmt.Errorf("invalid script step %q: %w", part, err)
+		}
+		x, y, err := parseVector(vector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid script step %q: %w", part, err)
+		}
+		steps = append(steps, scriptStep{At: offset, VectorX: x, VectorY: y})
+	}
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].At < steps[j].At
+	})
+	return steps, nil
+}
+
+// parseVector 解析 "x,y" 格式的方向
+func parseVector(s string) (float64, float64, error) {
+	xs, ys, found := strings.Cut(s, ",")
+	if !found {
+		return 0, 0, fmt.Errorf("vector must be x,y")
+	}
+	x, err := strconv.ParseFloat(strings.TrimSpace(xs), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	y, err := strconv.ParseFloat(strings.TrimSpace(ys), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return x, y, nil
+}
+
+// runScript 按时间依次发送脚本输入，直到脚本结束或 done 关闭
+func runScript(c *client.GameClient, steps []scriptStep, done <-chan struct{}) {
+	start := time.Now()
+	for _, step := range steps {
+		select {
+		case <-time.After(time.Until(start.Add(step.At))):
+			c.Move(step.VectorX, step.VectorY)
+		case <-done:
+			return
+		}
+	}
+}
+
+// 交互模式下的方向键
+var keyVectors = map[string][2]float64{
+	"w": {0, 1},
+	"a": {-1, 0},
+	"s": {0, -1},
+	"d": {1, 0},
+}
+
+// runInteractive 从输入读取命令，输入结束或收到 quit 时返回
+//
+//	move x y   按方向移动
+//	w/a/s/d    上/左/下/右
+//	stop       停止
+//	pos        输出当前世界状态
+//	quit       退出
+func runInteractive(c *client.GameClient, in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprintln(out, "Commands: move x y | w a s d | stop | pos | quit")
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch cmd := strings.ToLower(fields[0]); cmd {
+		case "move":
+			if len(fields) != 3 {
+				fmt.Fprintln(out, "usage: move x y")
+				continue
+			}
+			x, y, err := parseVector(fields[1] + "," + fields[2])
+			if err != nil {
+				fmt.Fprintf(out, "invalid vector: %v\n", err)
+				continue
+			}
+			c.Move(x, y)
+		case "w", "a", "s", "d":
+			v := keyVectors[cmd]
+			c.Move(v[0], v[1])
+		case "stop":
+			c.Move(0, 0)
+		case "pos":
+			printWorld(out, c.SnapshotNow())
+		case "quit", "exit":
+			return
+		default:
+			fmt.Fprintf(out, "unknown command %q\n", cmd)
+		}
+	}
+}
+
+// printWorld 输出世界状态
+func printWorld(out io.Writer, snapshot client.WorldSnapshot) {
+	fmt.Fprintf(out, "[%d] room %s, %d players\n", snapshot.GameTime, snapshot.RoomID, len(snapshot.Entities))
+	for _, e := range snapshot.Entities {
+		marker := " "
+		if e.Local {
+			marker = "*"
+		}
+		flags := ""
+		if e.Stale {
+			flags = " stale"
+		}
+		fmt.Fprintf(out, "  %s %-12s (%7.2f, %7.2f) v=(%5.2f, %5.2f) %s%s\n",
+			marker, e.PlayerID, e.RenderX, e.RenderY, e.VelocityX, e.VelocityY, e.Status, flags)
+	}
+}
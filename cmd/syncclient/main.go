@@ -0,0 +1,247 @@
+// syncclient 连接独立服务器的游戏客户端，输入来自脚本或标准输入
+//
+// 用法：
+//
+//	go run ./cmd/syncclient -server localhost:7777 -player Alice -script "1s:1,0;3s:0,1;5s:0,0"
+//	go run ./cmd/syncclient -server localhost:7777 -player Bob -input interactive
+//	go run ./cmd/syncclient -config client.json
+//
+// 配置文件为 JSON，字段与下方 config 结构一致，命令行参数优先于配置文件。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syncServerDemo/client"
+	"syncServerDemo/cmd/internal/cliconfig"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"syscall"
+	"time"
+)
+
+// 输入模式
+const (
+	InputScript      = "script"      // 按脚本定时发送输入
+	InputInteractive = "interactive" // 从标准输入读取命令
+)
+
+// config 客户端配置
+type config struct {
+	Server   string `json:"server"`
+	PlayerID string `json:"player_id"`
+	ClientID string `json:"client_id,omitempty"` // 为空时使用玩家ID
+
+	Matchmaking bool    `json:"matchmaking"` // 通过匹配队列进入房间
+	Region      string  `json:"region,omitempty"`
+	Skill       float64 `json:"skill,omitempty"`
+
+	Input  string `json:"input"`
+	Script string `json:"script,omitempty"` // 格式 offset:x,y;offset:x,y，例如 1s:1,0;3s:0,0
+
+	PrintInterval  cliconfig.Duration `json:"print_interval"` // 定期输出世界状态，0 表示不输出
+	Duration       cliconfig.Duration `json:"duration"`       // 运行时间，0 表示直到中断或脚本/输入结束
+	Reconnect      bool               `json:"reconnect"`      // 断线后自动重连
+	ReconnectDelay cliconfig.Duration `json:"reconnect_delay"`
+	Verbose        bool               `json:"verbose"`
+
+	TimeSyncThreshold   cliconfig.Duration `json:"time_sync_threshold"`
+	CorrectionThreshold float64            `json:"correction_threshold"`
+}
+
+// defaultConfig 默认配置
+func defaultConfig() *config {
+	defaults := client.DefaultConfig()
+	return &config{
+		Server: "localhost:7777",
+		Input:  InputScript,
+
+		PrintInterval:  cliconfig.Duration(1 * time.Second),
+		Reconnect:      true,
+		ReconnectDelay: cliconfig.Duration(1 * time.Second),
+
+		TimeSyncThreshold:   cliconfig.Duration(defaults.TimeSyncThreshold),
+		CorrectionThreshold: defaults.CorrectionThreshold,
+	}
+}
+
+// bindFlags 绑定命令行参数
+func (cfg *config) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&cfg.Server, "server", cfg.Server, "服务器地址")
+	fs.StringVar(&cfg.PlayerID, "player", cfg.PlayerID, "玩家ID")
+	fs.StringVar(&cfg.ClientID, "client-id", cfg.ClientID, "客户端ID，默认与玩家ID相同")
+
+	fs.BoolVar(&cfg.Matchmaking, "matchmaking", cfg.Matchmaking, "通过匹配队列进入房间")
+	fs.StringVar(&cfg.Region, "region", cfg.Region, "匹配区域")
+	fs.Float64Var(&cfg.Skill, "skill", cfg.Skill, "匹配技能分")
+
+	fs.StringVar(&cfg.Input, "input", cfg.Input, "输入模式：script 或 interactive")
+	fs.StringVar(&cfg.Script, "script", cfg.Script, "输入脚本，格式 offset:x,y;offset:x,y")
+
+	fs.Var(&cfg.PrintInterval, "print-interval", "输出世界状态的间隔，0 表示不输出")
+	fs.Var(&cfg.Duration, "duration", "运行时间，0 表示直到中断或输入结束")
+	fs.BoolVar(&cfg.Reconnect, "reconnect", cfg.Reconnect, "断线后自动重连")
+	fs.Var(&cfg.ReconnectDelay, "reconnect-delay", "重连间隔")
+	fs.BoolVar(&cfg.Verbose, "v", cfg.Verbose, "输出客户端日志")
+
+	fs.Var(&cfg.TimeSyncThreshold, "time-sync-threshold", "本地时间与服务器时间相差超过该值才校正")
+	fs.Float64Var(&cfg.CorrectionThreshold, "correction-threshold", cfg.CorrectionThreshold, "位置误差超过该值才校正")
+}
+
+// reconnectSignal 连接断开时通知主循环
+type reconnectSignal struct {
+	client.BaseListener
+	disconnected chan struct{}
+}
+
+func (l reconnectSignal) OnConnectionState(event client.ConnectionStateEvent) {
+	if event.State != client.ConnectionDisconnected || event.Previous == client.ConnectionDisconnected {
+		return
+	}
+	select {
+	case l.disconnected <- struct{}{}:
+	default:
+	}
+}
+
+func main() {
+	cfg := defaultConfig()
+	if err := cliconfig.Parse("syncclient", os.Args[1:], cfg, cfg.bindFlags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(2)
+	}
+	if cfg.PlayerID == "" {
+		fmt.Fprintln(os.Stderr, "Player ID is required (-player)")
+		os.Exit(2)
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = cfg.PlayerID
+	}
+
+	var steps []scriptStep
+	switch cfg.Input {
+	case InputScript:
+		var err error
+		if steps, err = parseScript(cfg.Script); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing script: %v\n", err)
+			os.Exit(2)
+		}
+	case InputInteractive:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown input mode %q\n", cfg.Input)
+		os.Exit(2)
+	}
+	if !cfg.Verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	if err := run(cfg, steps); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run 连接服务器并驱动输入，直到输入结束、运行时间到达或收到中断信号
+func run(cfg *config, steps []scriptStep) error {
+	tcp, err := transport.DialTCP(cfg.Server, cfg.ClientID)
+	if err != nil {
+		return err
+	}
+	defer tcp.Close()
+
+	gameClient := client.NewGameClient(cfg.ClientID, cfg.PlayerID, tcp,
+		client.WithTimeSyncThreshold(time.Duration(cfg.TimeSyncThreshold)),
+		client.WithCorrectionThreshold(cfg.CorrectionThreshold))
+	watcher := reconnectSignal{disconnected: make(chan struct{}, 1)}
+	gameClient.AddListener(watcher)
+
+	if cfg.Matchmaking {
+		err = gameClient.StartMatchmaking(protocol.MatchAttributes{Region: cfg.Region, Skill: cfg.Skill})
+	} else {
+		err = gameClient.Start()
+	}
+	if err != nil {
+		return err
+	}
+	defer gameClient.Stop()
+	fmt.Printf("Connected to %s as %s\n", cfg.Server, cfg.PlayerID)
+
+	// 输入结束时退出；脚本模式下只有设置了运行时间才在脚本结束后继续运行
+	done := make(chan struct{})
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		if cfg.Input == InputInteractive {
+			runInteractive(gameClient, os.Stdin, os.Stdout)
+			return
+		}
+		runScript(gameClient, steps, done)
+		if cfg.Duration > 0 {
+			<-done
+		}
+	}()
+	defer close(done)
+
+	var deadline <-chan time.Time
+	if cfg.Duration > 0 {
+		deadline = time.After(time.Duration(cfg.Duration))
+	}
+	var printTicks <-chan time.Time
+	if cfg.PrintInterval > 0 && cfg.Input == InputScript {
+		ticker := time.NewTicker(time.Duration(cfg.PrintInterval))
+		defer ticker.Stop()
+		printTicks = ticker.C
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	for {
+		select {
+		case <-printTicks:
+			printWorld(os.Stdout, gameClient.SnapshotNow())
+		case <-watcher.disconnected:
+			if !cfg.Reconnect {
+				fmt.Println("Disconnected from server")
+				return nil
+			}
+			reconnect(gameClient, tcp, time.Duration(cfg.ReconnectDelay), signals)
+		case <-inputDone:
+			printWorld(os.Stdout, gameClient.SnapshotNow())
+			return nil
+		case <-deadline:
+			printWorld(os.Stdout, gameClient.SnapshotNow())
+			return nil
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// reconnect 重新连接服务器并恢复会话，失败时按固定间隔重试，直到成功或收到中断信号
+func reconnect(gameClient *client.GameClient, tcp *transport.TCPClientTransport, delay time.Duration, signals chan os.Signal) {
+	fmt.Println("Disconnected, reconnecting...")
+	for {
+		select {
+		case <-time.After(delay):
+		case sig := <-signals:
+			// 交还给主循环处理
+			signals <- sig
+			return
+		}
+
+		if err := tcp.Redial(); err != nil {
+			fmt.Printf("Reconnect failed: %v\n", err)
+			continue
+		}
+		if err := gameClient.Reconnect(); err != nil {
+			fmt.Printf("Cannot resume session: %v\n", err)
+			return
+		}
+		fmt.Println("Reconnected")
+		return
+	}
+}
@@ -0,0 +1,254 @@
+// syncserver 独立运行的游戏同步服务器
+//
+// 用法：
+//
+//	go run ./cmd/syncserver -listen :7777
+//	go run ./cmd/syncserver -config server.json -epsilon 0.5
+//
+// 配置文件为 JSON，字段与下方 config 结构一致，命令行参数优先于配置文件。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syncServerDemo/cmd/internal/cliconfig"
+	"syncServerDemo/protocol"
+	"syncServerDemo/server"
+	"syncServerDemo/transport"
+	"syscall"
+	"time"
+)
+
+// config 服务器配置
+type config struct {
+	Listen    string `json:"listen"`
+	Transport string `json:"transport"`         // 目前支持 tcp
+	Record    string `json:"record,omitempty"`  // 录制流量的文件，为空时不录制
+	Metrics   string `json:"metrics,omitempty"` // 指标 HTTP 监听地址，为空时不提供
+	Overflow  string `json:"overflow"`          // 客户端发送队列满时的处理策略
+
+	TimeSyncInterval    cliconfig.Duration `json:"time_sync_interval"`
+	ArbitrationInterval cliconfig.Duration `json:"arbitration_interval"`
+	ReportInterval      cliconfig.Duration `json:"report_interval"` // 下发给客户端的位置上报间隔
+	Epsilon             float64            `json:"epsilon"`
+	InterestRadius      float64            `json:"interest_radius"` // 每个客户端只收到该距离内玩家的仲裁结果，0 表示不限制
+
+	HeartbeatInterval cliconfig.Duration `json:"heartbeat_interval"`
+	IdleTimeout       cliconfig.Duration `json:"idle_timeout"`
+	ReconnectGrace    cliconfig.Duration `json:"reconnect_grace"`
+
+	Snapshot         string             `json:"snapshot,omitempty"` // 世界快照文件，启动时存在则恢复
+	SnapshotInterval cliconfig.Duration `json:"snapshot_interval"`
+	Store            string             `json:"store,omitempty"` // 玩家状态存储文件，为空时使用内存
+
+	Movement protocol.MovementParams `json:"movement"`
+	Rooms    roomConfig              `json:"rooms"`
+}
+
+// roomConfig 房间和匹配设置
+type roomConfig struct {
+	GroupSize        int                `json:"group_size"`
+	MinGroupSize     int                `json:"min_group_size"`
+	MaxWait          cliconfig.Duration `json:"max_wait"`
+	MaxSkillSpread   float64            `json:"max_skill_spread"`
+	SkillRelaxStep   float64            `json:"skill_relax_step"`
+	RelaxInterval    cliconfig.Duration `json:"relax_interval"`
+	RegionRelaxAfter cliconfig.Duration `json:"region_relax_after"`
+	JoinTimeout      cliconfig.Duration `json:"join_timeout"`
+}
+
+// defaultConfig 默认配置，与服务器内置默认值一致
+func defaultConfig() *config {
+	defaults := server.DefaultConfig()
+	rules := server.DefaultMatchRules()
+	return &config{
+		Listen:    ":7777",
+		Transport: "tcp",
+		Overflow:  transport.DropNewest.String(),
+
+		TimeSyncInterval:    cliconfig.Duration(defaults.TimeSyncInterval),
+		ArbitrationInterval: cliconfig.Duration(defaults.ArbitrationInterval),
+		ReportInterval:      cliconfig.Duration(defaults.ReportInterval),
+		Epsilon:             defaults.ArbitrationEpsilon,
+
+		HeartbeatInterval: cliconfig.Duration(defaults.HeartbeatInterval),
+		IdleTimeout:       cliconfig.Duration(defaults.IdleTimeout),
+		ReconnectGrace:    cliconfig.Duration(defaults.ReconnectGrace),
+
+		SnapshotInterval: cliconfig.Duration(10 * time.Second),
+
+		Movement: defaults.Movement,
+		Rooms: roomConfig{
+			GroupSize:        rules.GroupSize,
+			MinGroupSize:     rules.MinGroupSize,
+			MaxWait:          cliconfig.Duration(rules.MaxWait),
+			MaxSkillSpread:   rules.MaxSkillSpread,
+			SkillRelaxStep:   rules.SkillRelaxStep,
+			RelaxInterval:    cliconfig.Duration(rules.RelaxInterval),
+			RegionRelaxAfter: cliconfig.Duration(rules.RegionRelaxAfter),
+			JoinTimeout:      cliconfig.Duration(rules.JoinTimeout),
+		},
+	}
+}
+
+// bindFlags 绑定命令行参数
+func (cfg *config) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&cfg.Listen, "listen", cfg.Listen, "监听地址")
+	fs.StringVar(&cfg.Transport, "transport", cfg.Transport, "传输层（tcp）")
+	fs.StringVar(&cfg.Record, "record", cfg.Record, "录制流量的文件")
+	fs.StringVar(&cfg.Metrics, "metrics", cfg.Metrics, "Prometheus 指标监听地址，如 127.0.0.1:9100")
+	fs.StringVar(&cfg.Overflow, "overflow", cfg.Overflow, "发送队列满时的处理策略：drop-newest、drop-oldest、disconnect 或 coalesce")
+
+	fs.Var(&cfg.TimeSyncInterval, "time-sync-interval", "时间同步广播间隔")
+	fs.Var(&cfg.ArbitrationInterval, "arbitration-interval", "位置仲裁间隔")
+	fs.Var(&cfg.ReportInterval, "report-interval", "客户端位置上报间隔，不能大于仲裁间隔")
+	fs.Float64Var(&cfg.Epsilon, "epsilon", cfg.Epsilon, "仲裁时判定位置一致的距离阈值")
+	fs.Float64Var(&cfg.InterestRadius, "interest-radius", cfg.InterestRadius, "兴趣范围，客户端只收到该距离内玩家的仲裁结果，0 表示不限制")
+
+	fs.Var(&cfg.HeartbeatInterval, "heartbeat-interval", "心跳间隔")
+	fs.Var(&cfg.IdleTimeout, "idle-timeout", "空闲超时")
+	fs.Var(&cfg.ReconnectGrace, "reconnect-grace", "断线重连宽限期")
+
+	fs.StringVar(&cfg.Snapshot, "snapshot", cfg.Snapshot, "世界快照文件")
+	fs.Var(&cfg.SnapshotInterval, "snapshot-interval", "快照间隔")
+	fs.StringVar(&cfg.Store, "store", cfg.Store, "玩家状态存储文件")
+
+	fs.StringVar(&cfg.Movement.Model, "movement-model", cfg.Movement.Model, "移动模型：instant 或 accelerated")
+	fs.Float64Var(&cfg.Movement.MaxSpeed, "max-speed", cfg.Movement.MaxSpeed, "最大速度（单位/秒）")
+	fs.Float64Var(&cfg.Movement.Acceleration, "acceleration", cfg.Movement.Acceleration, "加速度（单位/秒²）")
+	fs.Float64Var(&cfg.Movement.Deceleration, "deceleration", cfg.Movement.Deceleration, "减速度（单位/秒²）")
+
+	fs.IntVar(&cfg.Rooms.GroupSize, "group-size", cfg.Rooms.GroupSize, "匹配房间目标人数")
+	fs.IntVar(&cfg.Rooms.MinGroupSize, "min-group-size", cfg.Rooms.MinGroupSize, "等待超时后开局的最少人数")
+	fs.Var(&cfg.Rooms.MaxWait, "max-wait", "匹配最长等待时间")
+	fs.Var(&cfg.Rooms.JoinTimeout, "join-timeout", "匹配房间等待玩家加入的时间，超时无人加入则删除")
+}
+
+// serverConfig 转换为服务器配置
+func (cfg *config) serverConfig() server.Config {
+	return server.Config{
+		TimeSyncInterval:    time.Duration(cfg.TimeSyncInterval),
+		ArbitrationInterval: time.Duration(cfg.ArbitrationInterval),
+		ReportInterval:      time.Duration(cfg.ReportInterval),
+		ArbitrationEpsilon:  cfg.Epsilon,
+		InterestRadius:      cfg.InterestRadius,
+		HeartbeatInterval:   time.Duration(cfg.HeartbeatInterval),
+		IdleTimeout:         time.Duration(cfg.IdleTimeout),
+		ReconnectGrace:      time.Duration(cfg.ReconnectGrace),
+		Movement:            cfg.Movement,
+	}
+}
+
+// matchRules 转换为匹配规则
+func (r roomConfig) matchRules() server.MatchRules {
+	return server.MatchRules{
+		GroupSize:        r.GroupSize,
+		MinGroupSize:     r.MinGroupSize,
+		MaxWait:          time.Duration(r.MaxWait),
+		MaxSkillSpread:   r.MaxSkillSpread,
+		SkillRelaxStep:   r.SkillRelaxStep,
+		RelaxInterval:    time.Duration(r.RelaxInterval),
+		RegionRelaxAfter: time.Duration(r.RegionRelaxAfter),
+		JoinTimeout:      time.Duration(r.JoinTimeout),
+	}
+}
+
+func main() {
+	cfg := defaultConfig()
+	if err := cliconfig.Parse("syncserver", os.Args[1:], cfg, cfg.bindFlags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(2)
+	}
+
+	if err := run(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run 按配置启动服务器，收到中断信号后停止
+func run(cfg *config) error {
+	if cfg.Transport != "tcp" {
+		return fmt.Errorf("unsupported transport %q", cfg.Transport)
+	}
+	overflow, err := transport.ParseOverflowPolicy(cfg.Overflow)
+	if err != nil {
+		return err
+	}
+
+	tcp, err := transport.ListenTCP(cfg.Listen)
+	if err != nil {
+		return err
+	}
+	tcp.SetOverflowPolicy("", overflow)
+	var serverTransport transport.Transport = tcp
+
+	var recorder *transport.RecordingTransport
+	if cfg.Record != "" {
+		recorder, err = transport.NewRecordingTransport(tcp, cfg.Record)
+		if err != nil {
+			tcp.Close()
+			return err
+		}
+		serverTransport = recorder
+	}
+
+	gameServer := server.NewGameServer(serverTransport, server.WithConfig(cfg.serverConfig()))
+	tcp.SetDisconnectHandler(gameServer.Disconnect)
+	if recorder != nil {
+		recorder.SetGameClock(gameServer.GetGameTime)
+	}
+
+	if err := gameServer.Matchmaker().SetRules(cfg.Rooms.matchRules()); err != nil {
+		serverTransport.Close()
+		return fmt.Errorf("invalid room settings: %w", err)
+	}
+
+	if cfg.Store != "" {
+		store, err := server.NewFilePlayerStore(cfg.Store)
+		if err != nil {
+			serverTransport.Close()
+			return err
+		}
+		gameServer.SetPlayerStore(store)
+	}
+
+	if cfg.Snapshot != "" {
+		if snap, err := server.LoadSnapshot(cfg.Snapshot); err == nil {
+			if err := gameServer.RestoreSnapshot(snap); err != nil {
+				serverTransport.Close()
+				return err
+			}
+		} else if !os.IsNotExist(err) {
+			serverTransport.Close()
+			return err
+		}
+		gameServer.EnableSnapshots(cfg.Snapshot, time.Duration(cfg.SnapshotInterval))
+	}
+
+	if err := gameServer.Start(); err != nil {
+		serverTransport.Close()
+		return err
+	}
+	log.Printf("Sync server listening on %s", tcp.Addr())
+
+	if cfg.Metrics != "" {
+		addr, err := gameServer.ServeMetrics(cfg.Metrics)
+		if err != nil {
+			gameServer.Stop()
+			return err
+		}
+		log.Printf("Metrics available at http://%s/metrics", addr)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+
+	log.Printf("Shutting down")
+	gameServer.Stop()
+	return nil
+}
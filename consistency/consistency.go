@@ -0,0 +1,119 @@
+// Package consistency 检查多个客户端的世界视图是否一致。
+//
+// 所有客户端在同一游戏时间取快照，对每个实体计算各客户端位置之间的最大两两距离（偏差），
+// 并统计部分客户端看不到的实体。Tracker 记录多次采样，给出每个实体的百分位偏差，
+// 可用于测试断言，也可导出为 CSV/JSON 供长时间运行后分析。
+package consistency
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"syncServerDemo/client"
+)
+
+// Source 可以按游戏时间取快照的客户端，*client.GameClient 实现了该接口
+type Source interface {
+	Snapshot(atGameTime int64) client.WorldSnapshot
+}
+
+// Clients 将客户端列表转换为采样来源
+func Clients(clients []*client.GameClient) []Source {
+	sources := make([]Source, len(clients))
+	for i, c := range clients {
+		sources[i] = c
+	}
+	return sources
+}
+
+// EntityDeviation 一次采样中单个实体的偏差
+type EntityDeviation struct {
+	PlayerID  string  `json:"player_id"`
+	Deviation float64 `json:"deviation"` // 各客户端位置之间的最大两两距离
+	Clients   int     `json:"clients"`   // 看到该实体的客户端数
+	Missing   int     `json:"missing"`   // 没有看到该实体的客户端数
+}
+
+// Sample 一次采样结果
+type Sample struct {
+	GameTime     int64             `json:"game_time"`
+	Entities     []EntityDeviation `json:"entities"` // 按玩家ID排序
+	MaxDeviation float64           `json:"max_deviation"`
+}
+
+// Measure 在同一游戏时间对所有客户端取快照并计算每个实体的偏差
+func Measure(sources []Source, gameTime int64) Sample {
+	positions := make(map[string][][2]float64)
+	for _, source := range sources {
+		snap := source.Snapshot(gameTime)
+		for _, entity := range snap.Entities {
+			positions[entity.PlayerID] = append(positions[entity.PlayerID], [2]float64{entity.X, entity.Y})
+		}
+	}
+
+	sample := Sample{
+		GameTime: gameTime,
+		Entities: make([]EntityDeviation, 0, len(positions)),
+	}
+	for playerID, points := range positions {
+		deviation := 0.0
+		for i := range points {
+			for j := i + 1; j < len(points); j++ {
+				deviation = math.Max(deviation, math.Hypot(points[i][0]-points[j][0], points[i][1]-points[j][1]))
+			}
+		}
+		sample.Entities = append(sample.Entities, EntityDeviation{
+			PlayerID:  playerID,
+			Deviation: deviation,
+			Clients:   len(points),
+			Missing:   len(sources) - len(points),
+		})
+		sample.MaxDeviation = math.Max(sample.MaxDeviation, deviation)
+	}
+
+	sort.Slice(sample.Entities, func(i, j int) bool {
+		return sample.Entities[i].PlayerID < sample.Entities[j].PlayerID
+	})
+	return sample
+}
+
+// Entity 在采样中查找实体
+func (s *Sample) Entity(playerID string) (EntityDeviation, bool) {
+	i := sort.Search(len(s.Entities), func(i int) bool {
+		return s.Entities[i].PlayerID >= playerID
+	})
+	if i < len(s.Entities) && s.Entities[i].PlayerID == playerID {
+		return s.Entities[i], true
+	}
+	return EntityDeviation{}, false
+}
+
+// Check 检查采样是否一致：每个实体的偏差不超过 tolerance，且所有客户端都看到了该实体
+func (s *Sample) Check(tolerance float64) error {
+	var problems []string
+	for _, e := range s.Entities {
+		if e.Deviation > tolerance {
+			problems = append(problems, fmt.Sprintf("%s deviates by %.4f", e.PlayerID, e.Deviation))
+		}
+		if e.Missing > 0 {
+			problems = append(problems, fmt.Sprintf("%s missing on %d of %d clients", e.PlayerID, e.Missing, e.Clients+e.Missing))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("inconsistent at game time %d (tolerance %.4f): %s",
+			s.GameTime, tolerance, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// percentile 已排序数据的百分位（最近秩法），p 取值 0~1
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	rank = max(rank, 1)
+	rank = min(rank, len(sorted))
+	return sorted[rank-1]
+}
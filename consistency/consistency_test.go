@@ -0,0 +1,181 @@
+package consistency
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"syncServerDemo/client"
+	"testing"
+)
+
+// fakeSource 返回固定实体位置的采样来源
+type fakeSource map[string][2]float64
+
+func (f fakeSource) Snapshot(atGameTime int64) client.WorldSnapshot {
+	snap := client.WorldSnapshot{GameTime: atGameTime}
+	for playerID, pos := range f {
+		snap.Entities = append(snap.Entities, client.EntitySnapshot{PlayerID: playerID, X: pos[0], Y: pos[1]})
+	}
+	return snap
+}
+
+func TestMeasure(t *testing.T) {
+	sources := []Source{
+		fakeSource{"alice": {0, 0}, "bob": {10, 0}},
+		fakeSource{"alice": {3, 4}, "bob": {10, 0}},
+		fakeSource{"alice": {0, 0}},
+	}
+	sample := Measure(sources, 500)
+
+	want := []EntityDeviation{
+		{PlayerID: "alice", Deviation: 5, Clients: 3},
+		{PlayerID: "bob", Deviation: 0, Clients: 2, Missing: 1},
+	}
+	if !reflect.DeepEqual(sample.Entities, want) {
+		t.Errorf("entities = %+v, want %+v", sample.Entities, want)
+	}
+	if sample.GameTime != 500 || sample.MaxDeviation != 5 {
+		t.Errorf("game time %d max deviation %v, want 500 and 5", sample.GameTime, sample.MaxDeviation)
+	}
+
+	if e, ok := sample.Entity("bob"); !ok || e.Missing != 1 {
+		t.Errorf("Entity(bob) = %+v, %v", e, ok)
+	}
+	if _, ok := sample.Entity("charlie"); ok {
+		t.Error("Entity(charlie) found in sample")
+	}
+
+	err := sample.Check(1)
+	if err == nil {
+		t.Fatal("Check passed with a deviation of 5 and a missing entity")
+	}
+	for _, part := range []string{"alice deviates by 5.0000", "bob missing on 1 of 3 clients"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("Check error %q does not mention %q", err, part)
+		}
+	}
+	consistent := Measure(sources[:2], 500)
+	if err := consistent.Check(5); err != nil {
+		t.Errorf("Check within tolerance: %v", err)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 1},
+		{0.1, 1},
+		{0.5, 5},
+		{0.55, 6},
+		{0.9, 9},
+		{0.99, 10},
+		{1, 10},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(p=%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Errorf("percentile of no data = %v, want 0", got)
+	}
+}
+
+// trackerWith 构造记录了指定采样的 Tracker
+func trackerWith(samples ...Sample) *Tracker {
+	tracker := NewTracker()
+	for _, sample := range samples {
+		tracker.Record(sample)
+	}
+	return tracker
+}
+
+func testSamples() []Sample {
+	return []Sample{
+		{GameTime: 100, Entities: []EntityDeviation{
+			{PlayerID: "alice", Deviation: 0.5, Clients: 2},
+			{PlayerID: "bob", Deviation: 0, Clients: 1, Missing: 1},
+		}},
+		{GameTime: 200, Entities: []EntityDeviation{
+			{PlayerID: "alice", Deviation: 1.5, Clients: 2},
+			{PlayerID: "bob", Deviation: 0.25, Clients: 2},
+		}},
+	}
+}
+
+func TestTrackerCheck(t *testing.T) {
+	tracker := trackerWith(testSamples()...)
+
+	if err := tracker.Check(1, 2); err != nil {
+		t.Errorf("Check(p100, 2): %v", err)
+	}
+	if err := tracker.Check(0.5, 0.5); err != nil {
+		t.Errorf("Check(p50, 0.5): %v", err)
+	}
+	err := tracker.Check(1, 1)
+	if err == nil {
+		t.Fatal("Check(p100, 1) passed with alice at 1.5")
+	}
+	if !strings.Contains(err.Error(), "alice 1.5000") || strings.Contains(err.Error(), "bob") {
+		t.Errorf("Check error = %q, want only alice", err)
+	}
+}
+
+func TestTrackerOverall(t *testing.T) {
+	overall := trackerWith(testSamples()...).Overall()
+
+	// bob 在第一次采样中只有一个客户端看到，偏差不计入
+	if overall.Samples != 3 || overall.Missing != 1 {
+		t.Errorf("samples %d missing %d, want 3 and 1", overall.Samples, overall.Missing)
+	}
+	if math.Abs(overall.Mean-0.75) > 1e-9 || overall.Max != 1.5 {
+		t.Errorf("mean %v max %v, want 0.75 and 1.5", overall.Mean, overall.Max)
+	}
+}
+
+func TestTrackerWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := trackerWith(testSamples()...).WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"game_time", "player_id", "deviation", "clients", "missing"},
+		{"100", "alice", "0.500000", "2", "0"},
+		{"100", "bob", "0.000000", "1", "1"},
+		{"200", "alice", "1.500000", "2", "0"},
+		{"200", "bob", "0.250000", "2", "0"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("csv = %v, want %v", records, want)
+	}
+}
+
+func TestTrackerWriteJSON(t *testing.T) {
+	tracker := trackerWith(testSamples()...)
+	var buf bytes.Buffer
+	if err := tracker.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report, tracker.Report()) {
+		t.Errorf("decoded report = %+v, want %+v", report, tracker.Report())
+	}
+	if len(report.Entities) != 2 || report.Entities[0].PlayerID != "alice" || report.Entities[1].Missing != 1 {
+		t.Errorf("entities = %+v", report.Entities)
+	}
+}
@@ -0,0 +1,235 @@
+package consistency
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Stats 多次采样中偏差的分布
+type Stats struct {
+	PlayerID string  `json:"player_id,omitempty"` // 汇总全部实体时为空
+	Samples  int     `json:"samples"`
+	Mean     float64 `json:"mean"`
+	P50      float64 `json:"p50"`
+	P90      float64 `json:"p90"`
+	P99      float64 `json:"p99"`
+	Max      float64 `json:"max"`
+	Missing  int     `json:"missing"` // 有客户端看不到该实体的采样次数
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("n=%d p50=%.3f p90=%.3f p99=%.3f max=%.3f missing=%d",
+		s.Samples, s.P50, s.P90, s.P99, s.Max, s.Missing)
+}
+
+// newStats 由偏差序列计算分布
+func newStats(playerID string, deviations []float64, missing int) Stats {
+	stats := Stats{PlayerID: playerID, Samples: len(deviations), Missing: missing}
+	if len(deviations) == 0 {
+		return stats
+	}
+
+	sorted := append([]float64(nil), deviations...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, d := range sorted {
+		sum += d
+	}
+	stats.Mean = sum / float64(len(sorted))
+	stats.P50 = percentile(sorted, 0.50)
+	stats.P90 = percentile(sorted, 0.90)
+	stats.P99 = percentile(sorted, 0.99)
+	stats.Max = sorted[len(sorted)-1]
+	return stats
+}
+
+// Tracker 记录多次采样，跟踪偏差随时间的变化
+type Tracker struct {
+	samples []Sample
+	mu      sync.Mutex
+}
+
+// NewTracker 创建采样记录
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Sample 采样一次并记录
+func (t *Tracker) Sample(sources []Source, gameTime int64) Sample {
+	sample := Measure(sources, gameTime)
+	t.Record(sample)
+	return sample
+}
+
+// Record 记录一次采样
+func (t *Tracker) Record(sample Sample) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples = append(t.samples, sample)
+}
+
+// Samples 获取所有采样，按记录顺序
+func (t *Tracker) Samples() []Sample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Sample(nil), t.samples...)
+}
+
+// Reset 清除所有采样
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples = nil
+}
+
+// byEntity 按实体整理偏差序列和缺失次数
+func (t *Tracker) byEntity() (map[string][]float64, map[string]int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	deviations := make(map[string][]float64)
+	missing := make(map[string]int)
+	for _, sample := range t.samples {
+		for _, e := range sample.Entities {
+			deviations[e.PlayerID] = append(deviations[e.PlayerID], e.Deviation)
+			if e.Missing > 0 {
+				missing[e.PlayerID]++
+			}
+		}
+	}
+	return deviations, missing
+}
+
+// Entities 获取每个实体的偏差分布，按玩家ID排序
+func (t *Tracker) Entities() []Stats {
+	deviations, missing := t.byEntity()
+	result := make([]Stats, 0, len(deviations))
+	for playerID, values := range deviations {
+		result = append(result, newStats(playerID, values, missing[playerID]))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].PlayerID < result[j].PlayerID
+	})
+	return result
+}
+
+// Overall 获取所有实体所有采样合并后的偏差分布
+// 只有一个客户端看到的实体没有可比较的位置，其偏差不计入分布，缺失次数仍然计入
+func (t *Tracker) Overall() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var deviations []float64
+	missing := 0
+	for _, sample := range t.samples {
+		for _, e := range sample.Entities {
+			if e.Clients >= 2 {
+				deviations = append(deviations, e.Deviation)
+			}
+			if e.Missing > 0 {
+				missing++
+			}
+		}
+	}
+	return newStats("", deviations, missing)
+}
+
+// Check 检查每个实体在百分位 p（0~1）上的偏差不超过 tolerance
+func (t *Tracker) Check(p, tolerance float64) error {
+	deviations, _ := t.byEntity()
+	playerIDs := make([]string, 0, len(deviations))
+	for playerID := range deviations {
+		playerIDs = append(playerIDs, playerID)
+	}
+	sort.Strings(playerIDs)
+
+	var problems []string
+	for _, playerID := range playerIDs {
+		sorted := deviations[playerID]
+		sort.Float64s(sorted)
+		if value := percentile(sorted, p); value > tolerance {
+			problems = append(problems, fmt.Sprintf("%s %.4f", playerID, value))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("deviation above %.4f at p%v: %s", tolerance, p*100, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Report 导出的汇总结果
+type Report struct {
+	Overall  Stats    `json:"overall"`
+	Entities []Stats  `json:"entities"`
+	Samples  []Sample `json:"samples"`
+}
+
+// Report 生成汇总结果
+func (t *Tracker) Report() Report {
+	return Report{
+		Overall:  t.Overall(),
+		Entities: t.Entities(),
+		Samples:  t.Samples(),
+	}
+}
+
+// WriteJSON 以 JSON 导出汇总和全部采样
+func (t *Tracker) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t.Report())
+}
+
+// WriteCSV 以 CSV 导出偏差时间序列，每个采样的每个实体一行
+func (t *Tracker) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"game_time", "player_id", "deviation", "clients", "missing"}); err != nil {
+		return err
+	}
+	for _, sample := range t.Samples() {
+		for _, e := range sample.Entities {
+			record := []string{
+				strconv.FormatInt(sample.GameTime, 10),
+				e.PlayerID,
+				strconv.FormatFloat(e.Deviation, 'f', 6, 64),
+				strconv.Itoa(e.Clients),
+				strconv.Itoa(e.Missing),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// TB 测试断言需要的 testing.TB 子集
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertConsistent 对客户端采样一次，偏差超过 tolerance 或有实体缺失时标记测试失败
+func AssertConsistent(tb TB, sources []Source, gameTime int64, tolerance float64) Sample {
+	tb.Helper()
+	sample := Measure(sources, gameTime)
+	if err := sample.Check(tolerance); err != nil {
+		tb.Errorf("%v", err)
+	}
+	return sample
+}
+
+// Assert 任一实体在百分位 p 上的偏差超过 tolerance 时标记测试失败
+func (t *Tracker) Assert(tb TB, p, tolerance float64) {
+	tb.Helper()
+	if err := t.Check(p, tolerance); err != nil {
+		tb.Errorf("%v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package gamesync
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时间来源
+// 服务器和客户端默认使用系统时钟，模拟和测试中可以替换为手动推进的时钟
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock 系统时钟
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock 获取系统时钟
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// ManualClock 手动推进的时钟，只在调用 Advance 或 Set 时变化
+type ManualClock struct {
+	now time.Time
+	mu  sync.RWMutex
+}
+
+// NewManualClock 创建从指定时间开始的手动时钟
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now 获取当前时间
+func (c *ManualClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now
+}
+
+// Advance 向前推进时间
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set 设置当前时间，早于当前时间时忽略
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.now) {
+		c.now = t
+	}
+}
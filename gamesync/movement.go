@@ -0,0 +1,131 @@
+package gamesync
+
+import (
+	"fmt"
+	"math"
+	"syncServerDemo/protocol"
+)
+
+// MovementState 运动状态
+type MovementState struct {
+	X         float64
+	Y         float64
+	VelocityX float64
+	VelocityY float64
+}
+
+// MovementModel 移动模型
+// 根据输入方向推进运动状态，所有客户端使用相同的模型和参数，保证各自的模拟结果一致
+type MovementModel interface {
+	// Advance 在输入方向 (inputX, inputY) 不变的情况下将状态推进 dt 秒。
+	// 结果为闭式解，一次推进与分多段推进得到相同的状态；dt 为 0 时只应用输入
+	Advance(state MovementState, inputX, inputY, dt float64) MovementState
+
+	// Params 获取模型参数
+	Params() protocol.MovementParams
+}
+
+// DefaultMovementParams 默认移动参数：最大速度 10 单位/秒，0.25 秒加速到最大速度
+func DefaultMovementParams() protocol.MovementParams {
+	return protocol.MovementParams{
+		Model:        protocol.MovementModelAccelerated,
+		MaxSpeed:     10.0,
+		Acceleration: 40.0,
+		Deceleration: 60.0,
+	}
+}
+
+// NewMovementModel 根据参数创建移动模型
+func NewMovementModel(params protocol.MovementParams) (MovementModel, error) {
+	if params.MaxSpeed <= 0 {
+		return nil, fmt.Errorf("max speed must be positive, got %v", params.MaxSpeed)
+	}
+
+	switch params.Model {
+	case protocol.MovementModelInstant:
+		return &InstantMovement{params: params}, nil
+	case protocol.MovementModelAccelerated:
+		if params.Acceleration <= 0 || params.Deceleration <= 0 {
+			return nil, fmt.Errorf("acceleration and deceleration must be positive, got %v and %v",
+				params.Acceleration, params.Deceleration)
+		}
+		return &AcceleratedMovement{params: params}, nil
+	default:
+		return nil, fmt.Errorf("unknown movement model %q", params.Model)
+	}
+}
+
+// targetVelocity 输入方向对应的目标速度，大小不超过最大速度
+func targetVelocity(maxSpeed, inputX, inputY float64) (float64, float64) {
+	vx, vy := inputX*maxSpeed, inputY*maxSpeed
+	if speed := math.Hypot(vx, vy); speed > maxSpeed {
+		vx, vy = vx/speed*maxSpeed, vy/speed*maxSpeed
+	}
+	return vx, vy
+}
+
+// InstantMovement 瞬时移动模型，速度立即变为输入方向乘以最大速度
+type InstantMovement struct {
+	params protocol.MovementParams
+}
+
+func (m *InstantMovement) Advance(state MovementState, inputX, inputY, dt float64) MovementState {
+	vx, vy := targetVelocity(m.params.MaxSpeed, inputX, inputY)
+	return MovementState{
+		X:         state.X + vx*dt,
+		Y:         state.Y + vy*dt,
+		VelocityX: vx,
+		VelocityY: vy,
+	}
+}
+
+func (m *InstantMovement) Params() protocol.MovementParams {
+	return m.params
+}
+
+// AcceleratedMovement 加速移动模型
+// 速度以恒定的加速度沿直线趋近目标速度，没有输入时以减速度停下，到达目标速度后匀速运动
+type AcceleratedMovement struct {
+	params protocol.MovementParams
+}
+
+func (m *AcceleratedMovement) Advance(state MovementState, inputX, inputY, dt float64) MovementState {
+	// 向过去推进时无法还原加速过程，按当前速度线性回推
+	if dt <= 0 {
+		state.X += state.VelocityX * dt
+		state.Y += state.VelocityY * dt
+		return state
+	}
+
+	targetX, targetY := targetVelocity(m.params.MaxSpeed, inputX, inputY)
+	gapX, gapY := targetX-state.VelocityX, targetY-state.VelocityY
+	gap := math.Hypot(gapX, gapY)
+
+	rate := m.params.Acceleration
+	if targetX == 0 && targetY == 0 {
+		rate = m.params.Deceleration
+	}
+
+	// 加速阶段
+	accelTime := 0.0
+	if gap > 0 {
+		accelTime = math.Min(dt, gap/rate)
+		ax, ay := gapX/gap*rate, gapY/gap*rate
+		state.X += state.VelocityX*accelTime + 0.5*ax*accelTime*accelTime
+		state.Y += state.VelocityY*accelTime + 0.5*ay*accelTime*accelTime
+		state.VelocityX += ax * accelTime
+		state.VelocityY += ay * accelTime
+	}
+
+	// 匀速阶段
+	if accelTime < dt {
+		state.VelocityX, state.VelocityY = targetX, targetY
+		state.X += targetX * (dt - accelTime)
+		state.Y += targetY * (dt - accelTime)
+	}
+	return state
+}
+
+func (m *AcceleratedMovement) Params() protocol.MovementParams {
+	return m.params
+}
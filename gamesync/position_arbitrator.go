@@ -18,16 +18,28 @@ func NewPositionArbitrator(epsilon float64) *PositionArbitrator {
 	}
 }
 
+// ArbitrationResult 一次仲裁的详细结果
+type ArbitrationResult struct {
+	Position     *protocol.PositionData // 仲裁后的位置，没有上报时为空
+	ClusterSizes []int                  // 每个位置簇包含的上报数，按聚类顺序
+	Consensus    bool                   // 最大的簇是否超过半数上报
+}
+
 // Arbitrate 仲裁位置
 // 输入：多个客户端上报的同一玩家的位置
 // 输出：仲裁后的位置
 func (pa *PositionArbitrator) Arbitrate(positions []protocol.PositionData) *protocol.PositionData {
+	return pa.ArbitrateDetailed(positions).Position
+}
+
+// ArbitrateDetailed 仲裁位置并返回聚类情况，用于统计
+func (pa *PositionArbitrator) ArbitrateDetailed(positions []protocol.PositionData) ArbitrationResult {
 	if len(positions) == 0 {
-		return nil
+		return ArbitrationResult{}
 	}
 
 	if len(positions) == 1 {
-		return &positions[0]
+		return ArbitrationResult{Position: &positions[0], ClusterSizes: []int{1}, Consensus: true}
 	}
 
 	// 聚类：将相似的位置分组
@@ -35,14 +47,20 @@ func (pa *PositionArbitrator) Arbitrate(positions []protocol.PositionData) *prot
 
 	// 找到最大的簇（多数投票）
 	maxCluster := clusters[0]
-	for _, cluster := range clusters {
+	sizes := make([]int, len(clusters))
+	for i, cluster := range clusters {
+		sizes[i] = len(cluster)
 		if len(cluster) > len(maxCluster) {
 			maxCluster = cluster
 		}
 	}
 
 	// 计算簇的平均位置
-	return pa.averagePosition(maxCluster)
+	return ArbitrationResult{
+		Position:     pa.averagePosition(maxCluster),
+		ClusterSizes: sizes,
+		Consensus:    len(maxCluster)*2 > len(positions),
+	}
 }
 
 // clusterPositions 将位置聚类
@@ -8,14 +8,21 @@ import (
 // TimeSynchronizer 游戏时间同步器
 // 确保所有客户端使用相同的游戏时间基准
 type TimeSynchronizer struct {
+	clock     Clock
 	startTime time.Time // 游戏开始的真实时间
 	mu        sync.RWMutex
 }
 
-// NewTimeSynchronizer 创建时间同步器
+// NewTimeSynchronizer 创建使用系统时钟的时间同步器
 func NewTimeSynchronizer() *TimeSynchronizer {
+	return NewTimeSynchronizerWithClock(SystemClock())
+}
+
+// NewTimeSynchronizerWithClock 创建使用指定时钟的时间同步器
+func NewTimeSynchronizerWithClock(clock Clock) *TimeSynchronizer {
 	return &TimeSynchronizer{
-		startTime: time.Now(),
+		clock:     clock,
+		startTime: clock.Now(),
 	}
 }
 
@@ -24,7 +31,7 @@ func (ts *TimeSynchronizer) GetGameTime() int64 {
 	ts.mu.RLock()
 	defer ts.mu.RUnlock()
 
-	elapsed := time.Since(ts.startTime)
+	elapsed := ts.clock.Now().Sub(ts.startTime)
 	return elapsed.Milliseconds()
 }
 
@@ -33,7 +40,7 @@ func (ts *TimeSynchronizer) Reset() {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 
-	ts.startTime = time.Now()
+	ts.startTime = ts.clock.Now()
 }
 
 // SetGameTime 设置游戏时间（用于同步）
@@ -41,5 +48,5 @@ func (ts *TimeSynchronizer) SetGameTime(gameTime int64) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 
-	ts.startTime = time.Now().Add(-time.Duration(gameTime) * time.Millisecond)
+	ts.startTime = ts.clock.Now().Add(-time.Duration(gameTime) * time.Millisecond)
 }
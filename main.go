@@ -3,8 +3,8 @@ package main
 import (
 	"fmt"
 	"log"
-	"math"
 	"syncServerDemo/client"
+	"syncServerDemo/consistency"
 	"syncServerDemo/server"
 	"syncServerDemo/transport"
 	"time"
@@ -91,7 +91,7 @@ func main() {
 
 	// 验证一致性
 	fmt.Println("\n=== 验证一致性 ===")
-	checkConsistency(clients, playerIDs)
+	checkConsistency(clients, gameServer.GetGameTime())
 
 	// 再运行一段时间
 	time.Sleep(2 * time.Second)
@@ -122,38 +122,17 @@ func main() {
 	fmt.Println("✓ 可替换传输层 - 当前用本地内存，可轻松替换为TCP/UDP/WebSocket")
 }
 
-// checkConsistency 检查各客户端视图的一致性
-func checkConsistency(clients []*client.GameClient, playerIDs []string) {
-	for _, pid := range playerIDs {
-		positions := make([][2]float64, 0)
-		for _, c := range clients {
-			x, y, ok := c.GetPlayerPosition(pid)
-			if ok {
-				positions = append(positions, [2]float64{x, y})
-			}
-		}
-
-		if len(positions) < 2 {
-			continue
-		}
-
-		// 计算最大偏差
-		maxDeviation := 0.0
-		for i := 0; i < len(positions); i++ {
-			for j := i + 1; j < len(positions); j++ {
-				dx := positions[i][0] - positions[j][0]
-				dy := positions[i][1] - positions[j][1]
-				deviation := math.Sqrt(dx*dx + dy*dy)
-				if deviation > maxDeviation {
-					maxDeviation = deviation
-				}
-			}
-		}
-
-		if maxDeviation < 1.0 {
-			fmt.Printf("✓ %s: 一致性良好 (最大偏差: %.4f)\n", pid, maxDeviation)
-		} else {
-			fmt.Printf("⚠ %s: 存在较大偏差 (最大偏差: %.4f)\n", pid, maxDeviation)
+// checkConsistency 在同一游戏时间检查各客户端视图的一致性
+func checkConsistency(clients []*client.GameClient, gameTime int64) {
+	sample := consistency.Measure(consistency.Clients(clients), gameTime)
+	for _, e := range sample.Entities {
+		switch {
+		case e.Missing > 0:
+			fmt.Printf("⚠ %s: %d 个客户端看不到该玩家\n", e.PlayerID, e.Missing)
+		case e.Deviation < 1.0:
+			fmt.Printf("✓ %s: 一致性良好 (最大偏差: %.4f)\n", e.PlayerID, e.Deviation)
+		default:
+			fmt.Printf("⚠ %s: 存在较大偏差 (最大偏差: %.4f)\n", e.PlayerID, e.Deviation)
 		}
 	}
 }
@@ -6,6 +6,10 @@ const (
 	MsgTypeJoin         = "join"          // 加入游戏
 	MsgTypeMove         = "move"          // 移动指令
 	MsgTypePositionSync = "position_sync" // 位置同步上报
+	MsgTypeQueueJoin    = "queue_join"    // 加入匹配队列
+	MsgTypeQueueLeave   = "queue_leave"   // 离开匹配队列
+	MsgTypeLeave        = "leave"         // 主动离开游戏
+	MsgTypePong         = "pong"          // 心跳响应
 
 	// 服务器 -> 客户端
 	MsgTypeWelcome        = "welcome"         // 欢迎消息
@@ -13,12 +17,54 @@ const (
 	MsgTypePlayerLeft     = "player_left"     // 玩家离开
 	MsgTypeMoveCommand    = "move_command"    // 移动指令广播
 	MsgTypeTimeSync       = "time_sync"       // 游戏时间同步
-	MsgTypePositionUpdate = "position_update" // 位置仲裁结果
+	MsgTypePositionUpdate = "position_update" // 单个玩家的位置仲裁结果
+	MsgTypePositionBatch  = "position_batch"  // 一轮仲裁的全部位置结果
+	MsgTypeMatchFound     = "match_found"     // 匹配成功
+	MsgTypePlayerStatus   = "player_status"   // 玩家连接状态变化
+	MsgTypePing           = "ping"            // 心跳请求
+	MsgTypeAttributes     = "attributes"      // 玩家属性变化
+	MsgTypeSyncParams     = "sync_params"     // 同步参数变化
 )
 
+// 玩家连接状态
+const (
+	PlayerStatusOnline       = "online"       // 在线
+	PlayerStatusReconnecting = "reconnecting" // 断线等待重连
+)
+
+// 移动模型
+const (
+	MovementModelInstant     = "instant"     // 速度立即变为目标速度
+	MovementModelAccelerated = "accelerated" // 以恒定加速度趋近目标速度
+)
+
+// MovementParams 移动模型参数，由服务器在欢迎消息中下发，所有客户端按相同参数模拟
+type MovementParams struct {
+	Model        string  `json:"model"`
+	MaxSpeed     float64 `json:"max_speed"`              // 最大速度（单位/秒）
+	Acceleration float64 `json:"acceleration,omitempty"` // 有输入时趋近目标速度的加速度（单位/秒²）
+	Deceleration float64 `json:"deceleration,omitempty"` // 无输入时的减速度（摩擦）
+}
+
+// SyncParams 服务器与客户端需要一致的同步参数，随欢迎消息下发，运行时变化时广播
+type SyncParams struct {
+	TimeSyncInterval    int64   `json:"time_sync_interval_ms"`   // 时间同步广播间隔（毫秒）
+	ArbitrationInterval int64   `json:"arbitration_interval_ms"` // 位置仲裁间隔（毫秒）
+	ReportInterval      int64   `json:"report_interval_ms"`      // 客户端上报位置的间隔（毫秒）
+	ArbitrationEpsilon  float64 `json:"arbitration_epsilon"`     // 仲裁时判定位置一致的距离阈值
+}
+
 // JoinData 加入游戏数据
 type JoinData struct {
 	PlayerID string `json:"player_id"`
+	RoomID   string `json:"room_id,omitempty"` // 目标房间，为空时进入默认房间
+
+	SessionToken string `json:"session_token,omitempty"` // 重连时携带的会话令牌
+}
+
+// LeaveData 主动离开数据
+type LeaveData struct {
+	PlayerID string `json:"player_id"`
 }
 
 // MoveData 移动数据
@@ -26,7 +72,11 @@ type MoveData struct {
 	PlayerID string  `json:"player_id"`
 	VectorX  float64 `json:"vector_x"`
 	VectorY  float64 `json:"vector_y"`
-	GameTime int64   `json:"game_time"` // 游戏时间戳
+	GameTime int64   `json:"game_time"`     // 游戏时间戳
+	Seq      uint32  `json:"seq,omitempty"` // 客户端输入序号，服务器原样转发用于确认
+
+	VelocityX float64 `json:"velocity_x,omitempty"` // 指令生效时的速度，仅用于欢迎快照
+	VelocityY float64 `json:"velocity_y,omitempty"`
 }
 
 // PositionData 位置数据
@@ -46,14 +96,37 @@ type PositionSyncData struct {
 // WelcomeData 欢迎数据
 type WelcomeData struct {
 	PlayerID  string         `json:"player_id"`
+	RoomID    string         `json:"room_id"`
 	GameTime  int64          `json:"game_time"`
 	Players   []string       `json:"players"`   // 当前在线玩家
 	Positions []PositionData `json:"positions"` // 当前位置
+
+	SessionToken string             `json:"session_token"`      // 会话令牌，断线重连时使用
+	Resumed      bool               `json:"resumed"`            // 是否为恢复的会话
+	Movements    []MoveData         `json:"movements"`          // 每个玩家最后的移动指令
+	Statuses     []PlayerStatusData `json:"statuses,omitempty"` // 非在线状态的玩家
+	Movement     MovementParams     `json:"movement"`           // 默认移动模型参数
+	Attributes   []AttributesData   `json:"attributes"`         // 每个玩家的移动属性，包括尚未生效的变化
+	Sync         SyncParams         `json:"sync"`               // 同步参数
 }
 
 // PlayerJoinedData 玩家加入数据
+// 携带玩家加入时的位置和移动状态，快照恢复或从存储加载的玩家不在原点出现
 type PlayerJoinedData struct {
-	PlayerID string `json:"player_id"`
+	PlayerID string         `json:"player_id"`
+	Movement MovementParams `json:"movement"` // 玩家的移动属性
+
+	X        float64  `json:"x"`
+	Y        float64  `json:"y"`
+	GameTime int64    `json:"game_time"` // 位置对应的游戏时间
+	Move     MoveData `json:"move"`      // 最后的移动指令及其生效时的速度
+}
+
+// AttributesData 玩家属性数据，所有客户端在 GameTime 时刻同时切换到新属性
+type AttributesData struct {
+	PlayerID string         `json:"player_id"`
+	Movement MovementParams `json:"movement"`
+	GameTime int64          `json:"game_time"` // 生效的游戏时间
 }
 
 // PlayerLeftData 玩家离开数据
@@ -61,6 +134,12 @@ type PlayerLeftData struct {
 	PlayerID string `json:"player_id"`
 }
 
+// PlayerStatusData 玩家连接状态数据
+type PlayerStatusData struct {
+	PlayerID string `json:"player_id"`
+	Status   string `json:"status"`
+}
+
 // TimeSyncData 时间同步数据
 type TimeSyncData struct {
 	GameTime int64 `json:"game_time"`
@@ -73,3 +152,39 @@ type PositionUpdateData struct {
 	Y        float64 `json:"y"`
 	GameTime int64   `json:"game_time"`
 }
+
+// PositionBatchData 一轮仲裁的位置结果，客户端整体应用
+// 启用兴趣范围时只包含接收者附近的玩家
+type PositionBatchData struct {
+	GameTime  int64                `json:"game_time"` // 仲裁开始时的服务器游戏时间，此后到达的上报属于下一轮
+	Positions []PositionUpdateData `json:"positions"` // 按玩家ID排序
+}
+
+// MatchAttributes 匹配属性
+type MatchAttributes struct {
+	Region string  `json:"region,omitempty"` // 区域
+	Skill  float64 `json:"skill,omitempty"`  // 技能分
+}
+
+// QueueJoinData 加入匹配队列数据
+type QueueJoinData struct {
+	PlayerID   string          `json:"player_id"`
+	Attributes MatchAttributes `json:"attributes"`
+}
+
+// QueueLeaveData 离开匹配队列数据
+type QueueLeaveData struct {
+	PlayerID string `json:"player_id"`
+}
+
+// MatchFoundData 匹配成功数据，客户端收到后使用 RoomID 发起加入
+type MatchFoundData struct {
+	RoomID  string   `json:"room_id"`
+	Players []string `json:"players"` // 同一房间的玩家
+}
+
+// PingData 心跳数据，客户端原样返回用于计算往返时延
+type PingData struct {
+	Seq    uint64 `json:"seq"`
+	SentAt int64  `json:"sent_at"` // 服务器发送时间（Unix 纳秒）
+}
@@ -0,0 +1,481 @@
+// Package replay 将录制的传输层流量回放到一个全新的 GameServer，
+// 逐条重现客户端消息和仲裁时刻，并对比录制与回放的仲裁结果，用于定位不同步问题。
+//
+// 服务器内部定时触发的行为按录制中的输出推断：位置更新标志一次仲裁，
+// “重连中”状态标志一次断线。仲裁与消息处理在录制时是并发的，客户端消息先缓存，
+// 到推断出的事件时刻再按游戏时间决定在事件之前还是之后回放。
+// 匹配和会话过期依赖真实时间，不在回放范围内。
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"syncServerDemo/protocol"
+	"syncServerDemo/server"
+	"syncServerDemo/transport"
+)
+
+// Options 回放选项
+type Options struct {
+	Tolerance float64 // 仲裁结果允许的位置误差
+}
+
+// ArbitrationResult 一次仲裁中某个玩家的结果
+type ArbitrationResult struct {
+	Tick     int
+	PlayerID string
+	X        float64
+	Y        float64
+	GameTime int64
+}
+
+// Diff 录制与回放不一致的仲裁结果，缺失的一方为 nil
+type Diff struct {
+	Tick     int
+	PlayerID string
+	Recorded *ArbitrationResult
+	Replayed *ArbitrationResult
+	Distance float64
+}
+
+func (d Diff) String() string {
+	switch {
+	case d.Recorded == nil:
+		return fmt.Sprintf("tick %d %s: only in replay (%.2f, %.2f)",
+			d.Tick, d.PlayerID, d.Replayed.X, d.Replayed.Y)
+	case d.Replayed == nil:
+		return fmt.Sprintf("tick %d %s: only in recording (%.2f, %.2f)",
+			d.Tick, d.PlayerID, d.Recorded.X, d.Recorded.Y)
+	default:
+		return fmt.Sprintf("tick %d %s: recorded (%.2f, %.2f) replayed (%.2f, %.2f) distance %.4f",
+			d.Tick, d.PlayerID, d.Recorded.X, d.Recorded.Y, d.Replayed.X, d.Replayed.Y, d.Distance)
+	}
+}
+
+// VirtualClient 回放中的虚拟客户端，收集回放服务器发给它的消息
+type VirtualClient struct {
+	ID       string
+	PlayerID string
+	Received []transport.RecordEntry
+}
+
+// Result 回放结果
+type Result struct {
+	Inbound  int                   // 回放的客户端消息数
+	Recorded [][]ArbitrationResult // 录制中每次仲裁的结果
+	Replayed [][]ArbitrationResult // 回放中每次仲裁的结果
+	Diffs    []Diff
+	Clients  map[string]*VirtualClient
+}
+
+// Run 回放录制
+func Run(entries []transport.RecordEntry, opts Options) (*Result, error) {
+	capture := newCaptureTransport()
+	srv := server.NewGameServer(capture)
+	defer srv.Stop()
+
+	r := &replayer{
+		srv:       srv,
+		capture:   capture,
+		result:    &Result{Clients: make(map[string]*VirtualClient)},
+		clientOf:  make(map[string]string),
+		seenInfer: make(map[string]bool),
+	}
+
+	for i := range entries {
+		if err := r.step(&entries[i]); err != nil {
+			return r.result, err
+		}
+	}
+	if err := r.flushInbound(math.MaxInt64); err != nil {
+		return r.result, err
+	}
+	r.finishTick()
+
+	r.collectClients()
+	r.result.Diffs = diffTicks(r.result.Recorded, r.result.Replayed, opts.Tolerance)
+	return r.result, nil
+}
+
+// replayer 回放状态
+type replayer struct {
+	srv     *server.GameServer
+	capture *captureTransport
+	result  *Result
+
+	clientOf map[string]string        // [playerID]clientID
+	inbound  []*transport.RecordEntry // 尚未回放的客户端消息
+
+	// 当前录制仲裁的状态
+	inTick    bool
+	tickTime  int64              // 批量结果中的仲裁时间
+	tickSeen  map[[2]string]bool // [接收方, 玩家] 在当前仲裁中出现过
+	tickByID  map[string]bool
+	recorded  []ArbitrationResult
+	seenInfer map[string]bool // 已推断过的断线事件
+}
+
+// step 回放一条记录
+func (r *replayer) step(entry *transport.RecordEntry) error {
+	switch entry.Direction {
+	case transport.RecordRegister:
+		return r.capture.Register(entry.ClientID)
+	case transport.RecordUnregister:
+		return r.capture.Unregister(entry.ClientID)
+	case transport.RecordIn:
+		// 录制中仲裁结果之前的消息可能在仲裁开始之后才到达，等到下一个推断事件再决定顺序
+		r.inbound = append(r.inbound, entry)
+		return nil
+	case transport.RecordOut:
+		return r.inferFromOutbound(entry)
+	}
+	return nil
+}
+
+// flushInbound 按顺序回放游戏时间不晚于 gameTime 的缓存消息，更晚的消息继续缓存
+func (r *replayer) flushInbound(gameTime int64) error {
+	remaining := r.inbound[:0]
+	for _, entry := range r.inbound {
+		if entry.GameTime > gameTime {
+			remaining = append(remaining, entry)
+			continue
+		}
+		if err := r.replayInbound(entry); err != nil {
+			return fmt.Errorf("entry %d: %w", entry.Seq, err)
+		}
+	}
+	r.inbound = remaining
+	return nil
+}
+
+// replayInbound 将客户端消息交给回放服务器
+func (r *replayer) replayInbound(entry *transport.RecordEntry) error {
+	msg := entry.Message()
+
+	if entry.Type == protocol.MsgTypeJoin {
+		var join protocol.JoinData
+		if err := json.Unmarshal(entry.Data, &join); err != nil {
+			return err
+		}
+		r.clientOf[join.PlayerID] = entry.ClientID
+		delete(r.seenInfer, join.PlayerID)
+
+		// 会话令牌是随机生成的，替换为回放服务器下发的令牌
+		if join.SessionToken != "" {
+			join.SessionToken = r.capture.sessionToken(join.PlayerID)
+			msg = transport.NewMessage(entry.Type, join)
+		}
+	}
+
+	r.srv.SetGameTime(entry.GameTime)
+	r.srv.HandleMessage(entry.ClientID, msg)
+	r.result.Inbound++
+	return nil
+}
+
+// inferFromOutbound 根据录制的服务器输出推断服务器内部定时事件
+func (r *replayer) inferFromOutbound(entry *transport.RecordEntry) error {
+	switch entry.Type {
+	case protocol.MsgTypePositionBatch:
+		var batch protocol.PositionBatchData
+		if err := json.Unmarshal(entry.Data, &batch); err != nil {
+			return fmt.Errorf("entry %d: %w", entry.Seq, err)
+		}
+		// 同一轮仲裁的所有批量消息带有相同的仲裁时间
+		if !r.inTick || batch.GameTime != r.tickTime {
+			if err := r.nextTick(batch.GameTime); err != nil {
+				return err
+			}
+		}
+		for _, update := range batch.Positions {
+			r.recordUpdate(entry, update)
+		}
+
+	case protocol.MsgTypePositionUpdate:
+		var update protocol.PositionUpdateData
+		if err := json.Unmarshal(entry.Data, &update); err != nil {
+			return fmt.Errorf("entry %d: %w", entry.Seq, err)
+		}
+		// 早期录制中每个玩家的结果是单独的消息，同一接收方再次收到同一玩家的结果说明进入了下一次仲裁
+		key := [2]string{entry.ClientID, update.PlayerID}
+		if !r.inTick || r.tickSeen[key] {
+			if err := r.nextTick(entry.GameTime); err != nil {
+				return err
+			}
+		}
+		r.recordUpdate(entry, update)
+
+	case protocol.MsgTypePlayerStatus:
+		var status protocol.PlayerStatusData
+		if err := json.Unmarshal(entry.Data, &status); err != nil {
+			return fmt.Errorf("entry %d: %w", entry.Seq, err)
+		}
+		if status.Status == protocol.PlayerStatusReconnecting && !r.seenInfer[status.PlayerID] {
+			if err := r.flushInbound(entry.GameTime); err != nil {
+				return err
+			}
+			r.seenInfer[status.PlayerID] = true
+			r.srv.SetGameTime(entry.GameTime)
+			r.srv.Disconnect(r.clientOf[status.PlayerID])
+		}
+	}
+	return nil
+}
+
+// nextTick 结束当前录制的仲裁，回放仲裁开始前到达的消息后在同一时刻触发回放服务器仲裁
+func (r *replayer) nextTick(gameTime int64) error {
+	r.finishTick()
+	if err := r.flushInbound(gameTime); err != nil {
+		return err
+	}
+	r.startTick(gameTime)
+	return nil
+}
+
+// recordUpdate 记录录制中当前仲裁的一个结果
+func (r *replayer) recordUpdate(entry *transport.RecordEntry, update protocol.PositionUpdateData) {
+	r.tickSeen[[2]string{entry.ClientID, update.PlayerID}] = true
+
+	if !r.tickByID[update.PlayerID] {
+		r.tickByID[update.PlayerID] = true
+		r.recorded = append(r.recorded, ArbitrationResult{
+			Tick:     len(r.result.Recorded),
+			PlayerID: update.PlayerID,
+			X:        update.X,
+			Y:        update.Y,
+			GameTime: update.GameTime,
+		})
+	}
+}
+
+// decodeUpdates 解析单个或批量的仲裁结果，早期录制中每个玩家的结果是单独的消息
+func decodeUpdates(msgType string, data json.RawMessage) ([]protocol.PositionUpdateData, error) {
+	if msgType == protocol.MsgTypePositionBatch {
+		var batch protocol.PositionBatchData
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return nil, err
+		}
+		return batch.Positions, nil
+	}
+	var update protocol.PositionUpdateData
+	if err := json.Unmarshal(data, &update); err != nil {
+		return nil, err
+	}
+	return []protocol.PositionUpdateData{update}, nil
+}
+
+// startTick 在录制的仲裁时刻触发回放服务器仲裁
+func (r *replayer) startTick(gameTime int64) {
+	r.inTick = true
+	r.tickTime = gameTime
+	r.tickSeen = make(map[[2]string]bool)
+	r.tickByID = make(map[string]bool)
+	r.recorded = nil
+
+	tick := len(r.result.Replayed)
+	mark := r.capture.mark()
+	r.srv.SetGameTime(gameTime)
+	r.srv.ArbitrateNow()
+
+	var replayed []ArbitrationResult
+	seen := make(map[string]bool)
+	for _, out := range r.capture.since(mark) {
+		if out.Type != protocol.MsgTypePositionUpdate && out.Type != protocol.MsgTypePositionBatch {
+			continue
+		}
+		updates, err := decodeUpdates(out.Type, out.Data)
+		if err != nil {
+			continue
+		}
+		for _, update := range updates {
+			if seen[update.PlayerID] {
+				continue
+			}
+			seen[update.PlayerID] = true
+			replayed = append(replayed, ArbitrationResult{
+				Tick:     tick,
+				PlayerID: update.PlayerID,
+				X:        update.X,
+				Y:        update.Y,
+				GameTime: update.GameTime,
+			})
+		}
+	}
+	r.result.Replayed = append(r.result.Replayed, replayed)
+}
+
+// finishTick 结束当前录制的仲裁
+func (r *replayer) finishTick() {
+	if !r.inTick {
+		return
+	}
+	r.result.Recorded = append(r.result.Recorded, r.recorded)
+	r.inTick = false
+}
+
+// collectClients 按接收方整理回放服务器的输出
+func (r *replayer) collectClients() {
+	playerOf := make(map[string]string, len(r.clientOf))
+	for playerID, clientID := range r.clientOf {
+		playerOf[clientID] = playerID
+	}
+
+	for _, out := range r.capture.since(0) {
+		vc, exists := r.result.Clients[out.ClientID]
+		if !exists {
+			vc = &VirtualClient{ID: out.ClientID, PlayerID: playerOf[out.ClientID]}
+			r.result.Clients[out.ClientID] = vc
+		}
+		vc.Received = append(vc.Received, out)
+	}
+}
+
+// diffTicks 对比每次仲裁的结果
+func diffTicks(recorded, replayed [][]ArbitrationResult, tolerance float64) []Diff {
+	var diffs []Diff
+	ticks := max(len(recorded), len(replayed))
+
+	for tick := 0; tick < ticks; tick++ {
+		rec := indexResults(recorded, tick)
+		rep := indexResults(replayed, tick)
+
+		ids := make([]string, 0, len(rec)+len(rep))
+		for id := range rec {
+			ids = append(ids, id)
+		}
+		for id := range rep {
+			if _, ok := rec[id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			a, b := rec[id], rep[id]
+			if a == nil || b == nil {
+				diffs = append(diffs, Diff{Tick: tick, PlayerID: id, Recorded: a, Replayed: b})
+				continue
+			}
+			distance := math.Hypot(a.X-b.X, a.Y-b.Y)
+			if distance > tolerance {
+				diffs = append(diffs, Diff{Tick: tick, PlayerID: id, Recorded: a, Replayed: b, Distance: distance})
+			}
+		}
+	}
+	return diffs
+}
+
+// indexResults 按玩家索引某次仲裁的结果
+func indexResults(ticks [][]ArbitrationResult, tick int) map[string]*ArbitrationResult {
+	index := make(map[string]*ArbitrationResult)
+	if tick >= len(ticks) {
+		return index
+	}
+	for i := range ticks[tick] {
+		index[ticks[tick][i].PlayerID] = &ticks[tick][i]
+	}
+	return index
+}
+
+// captureTransport 回放用的传输层，记录服务器的所有输出
+type captureTransport struct {
+	clients map[string]bool
+	sent    []transport.RecordEntry
+	tokens  map[string]string // [playerID]回放服务器下发的会话令牌
+	mu      sync.Mutex
+}
+
+func newCaptureTransport() *captureTransport {
+	return &captureTransport{
+		clients: make(map[string]bool),
+		tokens:  make(map[string]string),
+	}
+}
+
+func (t *captureTransport) capture(clientID string, msg transport.Message) {
+	data, err := json.Marshal(msg.GetData())
+	if err != nil {
+		return
+	}
+
+	if msg.GetType() == protocol.MsgTypeWelcome {
+		var welcome protocol.WelcomeData
+		if json.Unmarshal(data, &welcome) == nil {
+			t.tokens[welcome.PlayerID] = welcome.SessionToken
+		}
+	}
+
+	t.sent = append(t.sent, transport.RecordEntry{
+		Seq:       uint64(len(t.sent) + 1),
+		Direction: transport.RecordOut,
+		ClientID:  clientID,
+		Type:      msg.GetType(),
+		Data:      data,
+	})
+}
+
+func (t *captureTransport) Send(clientID string, msg transport.Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.capture(clientID, msg)
+	return nil
+}
+
+func (t *captureTransport) Broadcast(msg transport.Message, excludeID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for clientID := range t.clients {
+		if clientID != excludeID {
+			t.capture(clientID, msg)
+		}
+	}
+	return nil
+}
+
+func (t *captureTransport) Receive() (string, transport.Message, error) {
+	return "", nil, fmt.Errorf("replay transport does not receive")
+}
+
+func (t *captureTransport) Register(clientID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clients[clientID] = true
+	return nil
+}
+
+func (t *captureTransport) Unregister(clientID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.clients, clientID)
+	return nil
+}
+
+func (t *captureTransport) Close() error {
+	return nil
+}
+
+// mark 返回当前输出位置
+func (t *captureTransport) mark() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.sent)
+}
+
+// since 返回指定位置之后的输出
+func (t *captureTransport) since(mark int) []transport.RecordEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]transport.RecordEntry(nil), t.sent[mark:]...)
+}
+
+// sessionToken 获取回放服务器为玩家下发的会话令牌
+func (t *captureTransport) sessionToken(playerID string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tokens[playerID]
+}
@@ -0,0 +1,104 @@
+package replay
+
+import (
+	"encoding/json"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+)
+
+// recording 按顺序构造录制记录
+type recording []transport.RecordEntry
+
+func (r *recording) add(direction, clientID string, gameTime int64, msgType string, data interface{}) {
+	entry := transport.RecordEntry{
+		Seq:       uint64(len(*r) + 1),
+		Direction: direction,
+		ClientID:  clientID,
+		GameTime:  gameTime,
+	}
+	if msgType != "" {
+		entry.Type = msgType
+		entry.Data, _ = json.Marshal(data)
+	}
+	*r = append(*r, entry)
+}
+
+func (r *recording) report(clientID string, gameTime int64, aliceX float64) {
+	r.add(transport.RecordIn, clientID, gameTime, protocol.MsgTypePositionSync, protocol.PositionSyncData{
+		GameTime: gameTime,
+		Positions: []protocol.PositionData{
+			{PlayerID: "alice", X: aliceX, GameTime: gameTime},
+			{PlayerID: "bob", GameTime: gameTime},
+		},
+	})
+}
+
+func (r *recording) batch(tickTime int64, aliceX float64) {
+	for _, clientID := range []string{"alice", "bob"} {
+		r.add(transport.RecordOut, clientID, tickTime+2, protocol.MsgTypePositionBatch, protocol.PositionBatchData{
+			GameTime: tickTime,
+			Positions: []protocol.PositionUpdateData{
+				{PlayerID: "alice", X: aliceX},
+				{PlayerID: "bob"},
+			},
+		})
+	}
+}
+
+func TestReportDuringArbitration(t *testing.T) {
+	var rec recording
+	for _, playerID := range []string{"alice", "bob"} {
+		rec.add(transport.RecordRegister, playerID, 0, "", nil)
+		rec.add(transport.RecordIn, playerID, 0, protocol.MsgTypeJoin, protocol.JoinData{PlayerID: playerID})
+	}
+	rec.report("alice", 100, 10)
+	rec.report("bob", 100, 10)
+
+	// 仲裁在 500 开始，alice 在结果发出之前又上报了一次，这次上报属于下一轮
+	rec.report("alice", 501, 20)
+	rec.batch(500, 10)
+	rec.report("bob", 600, 20)
+	rec.batch(1000, 20)
+
+	result, err := Run(rec, Options{Tolerance: 1e-6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Recorded) != 2 || len(result.Replayed) != 2 {
+		t.Fatalf("recorded %d / replayed %d ticks, want 2", len(result.Recorded), len(result.Replayed))
+	}
+	for _, diff := range result.Diffs {
+		t.Errorf("replay diverged: %v", diff)
+	}
+	if result.Inbound != 6 {
+		t.Errorf("replayed %d client messages, want 6", result.Inbound)
+	}
+}
+
+func TestLegacyPositionUpdates(t *testing.T) {
+	var rec recording
+	rec.add(transport.RecordRegister, "alice", 0, "", nil)
+	rec.add(transport.RecordIn, "alice", 0, protocol.MsgTypeJoin, protocol.JoinData{PlayerID: "alice"})
+
+	// 早期录制中每个玩家的结果是单独的消息，同一接收方再次收到同一玩家的结果即为下一轮
+	for _, tick := range []int64{500, 1000, 1500} {
+		rec.add(transport.RecordIn, "alice", tick-100, protocol.MsgTypePositionSync, protocol.PositionSyncData{
+			Positions: []protocol.PositionData{{PlayerID: "alice", X: float64(tick), GameTime: tick - 100}},
+		})
+		rec.add(transport.RecordOut, "alice", tick, protocol.MsgTypePositionUpdate, protocol.PositionUpdateData{
+			PlayerID: "alice", X: float64(tick), GameTime: tick - 100,
+		})
+	}
+
+	result, err := Run(rec, Options{Tolerance: 1e-6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Recorded) != 3 || len(result.Replayed) != 3 {
+		t.Fatalf("recorded %d / replayed %d ticks, want 3", len(result.Recorded), len(result.Replayed))
+	}
+	for _, diff := range result.Diffs {
+		t.Errorf("replay diverged: %v", diff)
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"syncServerDemo/gamesync"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+)
+
+// initMovement 为没有移动属性的玩家设置服务器默认属性（调用方需持有 s.mu 写锁）
+func (s *GameServer) initMovement(player *PlayerState) {
+	if player.movement != nil {
+		return
+	}
+
+	if model, err := gamesync.NewMovementModel(player.Movement); err == nil {
+		player.movement = model
+		return
+	}
+	player.movement = s.movement
+	player.Movement = s.movement.Params()
+}
+
+// settleMovement 应用在指定时间之前生效的属性变化（调用方需持有 s.mu 写锁）
+// 切换前按旧属性把速度推进到生效时间，保证欢迎快照中的速度与客户端模拟一致
+func (s *GameServer) settleMovement(player *PlayerState, gameTime int64) {
+	for len(player.scheduled) > 0 && player.scheduled[0].GameTime <= gameTime {
+		change := player.scheduled[0]
+		player.scheduled = player.scheduled[1:]
+
+		model, err := gamesync.NewMovementModel(change.Movement)
+		if err != nil {
+			continue
+		}
+
+		state := player.movement.Advance(gamesync.MovementState{
+			VelocityX: player.VelocityX,
+			VelocityY: player.VelocityY,
+		}, player.VectorX, player.VectorY, float64(change.GameTime-player.MoveTime)/1000.0)
+		state = model.Advance(state, player.VectorX, player.VectorY, 0)
+
+		player.VelocityX = state.VelocityX
+		player.VelocityY = state.VelocityY
+		player.MoveTime = change.GameTime
+		player.Movement = change.Movement
+		player.MovementTime = change.GameTime
+		player.movement = model
+	}
+}
+
+// movementAttributes 玩家当前及尚未生效的移动属性（调用方需持有 s.mu 锁）
+func movementAttributes(player *PlayerState) []protocol.AttributesData {
+	attrs := []protocol.AttributesData{{
+		PlayerID: player.PlayerID,
+		Movement: player.Movement,
+		GameTime: player.MovementTime,
+	}}
+	return append(attrs, player.scheduled...)
+}
+
+// SetPlayerMovement 修改玩家的移动属性（如坐骑、加速效果）
+// 属性在 effectiveAt 游戏时间生效，早于当前游戏时间时返回错误：客户端已经按旧属性模拟过这段时间，
+// 服务器追溯修改会与客户端产生分歧。变化会广播给房间内所有客户端，应预留足够的提前量使所有客户端在生效前收到
+func (s *GameServer) SetPlayerMovement(playerID string, params protocol.MovementParams, effectiveAt int64) error {
+	if _, err := gamesync.NewMovementModel(params); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	player, exists := s.players[playerID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("player %s not found", playerID)
+	}
+
+	if now := s.timeSyncer.GetGameTime(); effectiveAt < now {
+		s.mu.Unlock()
+		return fmt.Errorf("effective time %d is before current game time %d", effectiveAt, now)
+	}
+
+	change := protocol.AttributesData{
+		PlayerID: playerID,
+		Movement: params,
+		GameTime: effectiveAt,
+	}
+	i := sort.Search(len(player.scheduled), func(i int) bool {
+		return player.scheduled[i].GameTime > effectiveAt
+	})
+	player.scheduled = append(player.scheduled, protocol.AttributesData{})
+	copy(player.scheduled[i+1:], player.scheduled[i:])
+	player.scheduled[i] = change
+	roomID := player.RoomID
+	s.mu.Unlock()
+
+	attrMsg := transport.NewMessage(protocol.MsgTypeAttributes, change)
+	s.broadcastToRoom(roomID, attrMsg, "")
+
+	log.Printf("Player %s movement set to %s (max speed %.2f) at game time %d",
+		playerID, params.Model, params.MaxSpeed, effectiveAt)
+	return nil
+}
+
+// GetPlayerMovement 获取玩家当前生效的移动属性
+func (s *GameServer) GetPlayerMovement(playerID string) (protocol.MovementParams, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player, exists := s.players[playerID]
+	if !exists {
+		return protocol.MovementParams{}, false
+	}
+	s.settleMovement(player, s.timeSyncer.GetGameTime())
+	return player.Movement, true
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"syncServerDemo/gamesync"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"time"
+)
+
+// Config 服务器配置
+type Config struct {
+	TimeSyncInterval    time.Duration // 时间同步广播间隔
+	ArbitrationInterval time.Duration // 位置仲裁间隔
+	ReportInterval      time.Duration // 客户端上报位置的间隔，下发给客户端，不能大于仲裁间隔
+	ArbitrationEpsilon  float64       // 仲裁时判定位置一致的距离阈值
+	InterestRadius      float64       // 每个客户端只收到该距离内玩家的仲裁结果，0 表示房间内全部玩家
+
+	HeartbeatInterval time.Duration // 心跳间隔
+	IdleTimeout       time.Duration // 超过该时间未收到任何消息的客户端视为断线
+	ReconnectGrace    time.Duration // 断线后保留玩家的时间
+
+	Movement protocol.MovementParams // 新加入玩家的默认移动属性
+
+	Clock gamesync.Clock // 时间来源，为空时使用系统时钟；只在创建时生效
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() Config {
+	return Config{
+		TimeSyncInterval:    1 * time.Second,
+		ArbitrationInterval: 500 * time.Millisecond,
+		ReportInterval:      200 * time.Millisecond,
+		ArbitrationEpsilon:  1.0,
+
+		HeartbeatInterval: 1 * time.Second,
+		IdleTimeout:       5 * time.Second,
+		ReconnectGrace:    30 * time.Second,
+
+		Movement: gamesync.DefaultMovementParams(),
+	}
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	if c.TimeSyncInterval <= 0 || c.ArbitrationInterval <= 0 || c.ReportInterval <= 0 {
+		return fmt.Errorf("tick intervals must be positive, got time sync %v, arbitration %v, report %v",
+			c.TimeSyncInterval, c.ArbitrationInterval, c.ReportInterval)
+	}
+	// 每轮仲裁都需要收到上报
+	if c.ReportInterval > c.ArbitrationInterval {
+		return fmt.Errorf("report interval %v must not exceed arbitration interval %v",
+			c.ReportInterval, c.ArbitrationInterval)
+	}
+	if c.ArbitrationEpsilon <= 0 {
+		return fmt.Errorf("arbitration epsilon must be positive, got %v", c.ArbitrationEpsilon)
+	}
+	if c.InterestRadius < 0 {
+		return fmt.Errorf("interest radius must not be negative, got %v", c.InterestRadius)
+	}
+	if c.HeartbeatInterval <= 0 {
+		return fmt.Errorf("heartbeat interval must be positive, got %v", c.HeartbeatInterval)
+	}
+	if c.IdleTimeout <= c.HeartbeatInterval {
+		return fmt.Errorf("idle timeout %v must exceed heartbeat interval %v", c.IdleTimeout, c.HeartbeatInterval)
+	}
+	if c.ReconnectGrace < 0 {
+		return fmt.Errorf("reconnect grace must not be negative")
+	}
+	if _, err := gamesync.NewMovementModel(c.Movement); err != nil {
+		return fmt.Errorf("invalid movement params: %w", err)
+	}
+	return nil
+}
+
+// syncParams 需要下发给客户端的同步参数
+func (c Config) syncParams() protocol.SyncParams {
+	return protocol.SyncParams{
+		TimeSyncInterval:    c.TimeSyncInterval.Milliseconds(),
+		ArbitrationInterval: c.ArbitrationInterval.Milliseconds(),
+		ReportInterval:      c.ReportInterval.Milliseconds(),
+		ArbitrationEpsilon:  c.ArbitrationEpsilon,
+	}
+}
+
+// Option 服务器配置项
+type Option func(*Config)
+
+// WithConfig 使用完整配置替换当前配置
+func WithConfig(cfg Config) Option {
+	return func(c *Config) {
+		*c = cfg
+	}
+}
+
+// WithTickIntervals 设置时间同步和位置仲裁的间隔
+func WithTickIntervals(timeSync, arbitration time.Duration) Option {
+	return func(c *Config) {
+		c.TimeSyncInterval = timeSync
+		c.ArbitrationInterval = arbitration
+	}
+}
+
+// WithReportInterval 设置客户端上报位置的间隔
+func WithReportInterval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.ReportInterval = interval
+	}
+}
+
+// WithArbitrationEpsilon 设置仲裁时判定位置一致的距离阈值
+func WithArbitrationEpsilon(epsilon float64) Option {
+	return func(c *Config) {
+		c.ArbitrationEpsilon = epsilon
+	}
+}
+
+// WithInterestRadius 设置兴趣范围，每个客户端只收到该距离内玩家的仲裁结果
+// 客户端自己的玩家总是包含在内；0 表示不限制，同一房间的客户端收到相同的结果
+func WithInterestRadius(radius float64) Option {
+	return func(c *Config) {
+		c.InterestRadius = radius
+	}
+}
+
+// WithHeartbeat 设置心跳间隔和空闲超时
+func WithHeartbeat(interval, idleTimeout time.Duration) Option {
+	return func(c *Config) {
+		c.HeartbeatInterval = interval
+		c.IdleTimeout = idleTimeout
+	}
+}
+
+// WithReconnectGrace 设置断线重连宽限期
+func WithReconnectGrace(grace time.Duration) Option {
+	return func(c *Config) {
+		c.ReconnectGrace = grace
+	}
+}
+
+// WithMovement 设置新加入玩家的默认移动属性
+// 参数随欢迎消息下发给客户端，所有客户端按相同的模型模拟
+func WithMovement(params protocol.MovementParams) Option {
+	return func(c *Config) {
+		c.Movement = params
+	}
+}
+
+// WithClock 设置时间来源，用于模拟和测试
+func WithClock(clock gamesync.Clock) Option {
+	return func(c *Config) {
+		c.Clock = clock
+	}
+}
+
+// applyConfig 校验并应用配置（调用方需持有 s.mu 写锁）
+func (s *GameServer) applyConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	movement, err := gamesync.NewMovementModel(cfg.Movement)
+	if err != nil {
+		return err
+	}
+
+	s.config = cfg
+	s.movement = movement
+	s.arbitrator = gamesync.NewPositionArbitrator(cfg.ArbitrationEpsilon)
+	return nil
+}
+
+// UpdateConfig 在运行时修改配置，校验失败时保持原配置不变
+// 间隔变化在下一次触发时生效；同步参数变化会广播给所有客户端；
+// 默认移动属性只影响之后加入的玩家，已在游戏中的玩家使用 SetPlayerMovement 修改
+func (s *GameServer) UpdateConfig(opts ...Option) error {
+	s.mu.Lock()
+	cfg := s.config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.Clock = s.config.Clock
+	oldParams := s.config.syncParams()
+	if err := s.applyConfig(cfg); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	newParams := cfg.syncParams()
+
+	// 唤醒各个循环重新读取间隔
+	close(s.configUpdated)
+	s.configUpdated = make(chan struct{})
+	s.mu.Unlock()
+
+	if newParams != oldParams {
+		s.broadcast(transport.NewMessage(protocol.MsgTypeSyncParams, newParams))
+		log.Printf("Sync params updated: time sync %dms, arbitration %dms, report %dms, epsilon %.2f",
+			newParams.TimeSyncInterval, newParams.ArbitrationInterval, newParams.ReportInterval, newParams.ArbitrationEpsilon)
+	}
+	return nil
+}
+
+// Config 获取当前配置
+func (s *GameServer) Config() Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// tickInterval 读取间隔和配置变化通知，循环在通知关闭后重新读取
+func (s *GameServer) tickInterval(get func(Config) time.Duration) (time.Duration, <-chan struct{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return get(s.config), s.configUpdated
+}
+
+// 各循环读取的间隔
+func timeSyncInterval(c Config) time.Duration    { return c.TimeSyncInterval }
+func arbitrationInterval(c Config) time.Duration { return c.ArbitrationInterval }
+func heartbeatInterval(c Config) time.Duration   { return c.HeartbeatInterval }
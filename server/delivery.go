@@ -0,0 +1,60 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"syncServerDemo/transport"
+)
+
+// watchOverflow 传输层支持溢出策略时，统计因队列满被丢弃的已入队消息
+func (s *GameServer) watchOverflow() {
+	if oc, ok := s.transport.(transport.OverflowController); ok {
+		oc.SetOverflowHandler(func(clientID string, dropped transport.Message, policy transport.OverflowPolicy) {
+			s.metrics.SendOverflow.Inc(policy.String(), dropped.GetType())
+		})
+	}
+}
+
+// handleSendFailure 记录发送失败，并将已被传输层断开的客户端视为断线
+// 返回失败的接收者数，整个发送失败（如传输层已关闭）时返回 0
+func (s *GameServer) handleSendFailure(msg transport.Message, err error) int {
+	failures := transport.SendFailures(err)
+	if len(failures) == 0 {
+		s.metrics.SendErrors.Inc(msg.GetType(), sendErrorReason(err))
+		return 0
+	}
+
+	for _, failure := range failures {
+		s.metrics.SendErrors.Inc(msg.GetType(), sendErrorReason(failure.Err))
+		if isConnectionLost(failure.Err) {
+			log.Printf("Send to %s failed: %v", failure.ClientID, failure.Err)
+			s.Disconnect(failure.ClientID)
+		}
+	}
+	return len(failures)
+}
+
+// isConnectionLost 发送失败是否意味着客户端已不可达
+// 队列满只丢一条消息，连接仍然保留
+func isConnectionLost(err error) bool {
+	return errors.Is(err, transport.ErrSlowConsumer) ||
+		errors.Is(err, transport.ErrDisconnected) ||
+		errors.Is(err, transport.ErrClientNotFound)
+}
+
+// sendErrorReason 发送失败原因的指标标签
+func sendErrorReason(err error) string {
+	switch {
+	case errors.Is(err, transport.ErrQueueFull):
+		return "queue_full"
+	case errors.Is(err, transport.ErrSlowConsumer):
+		return "slow_consumer"
+	case errors.Is(err, transport.ErrDisconnected):
+		return "disconnected"
+	case errors.Is(err, transport.ErrClientNotFound):
+		return "not_found"
+	case errors.Is(err, transport.ErrClosed):
+		return "closed"
+	}
+	return "other"
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"strings"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+)
+
+func TestSlowConsumerDisconnected(t *testing.T) {
+	s, lt := newTestServer(t)
+	join(s, lt, "alice", "bob")
+
+	// 两个客户端都不读取，bob 积压的可靠事件填满了发送队列
+	for i := 0; i < 150; i++ {
+		lt.Send("bob", transport.NewMessage(protocol.MsgTypeMoveCommand, protocol.MoveData{PlayerID: "alice", Seq: uint32(i)}))
+	}
+	lt.SetOverflowPolicy("bob", transport.DisconnectSlow)
+	s.SyncTimeNow()
+
+	if got := s.metrics.SendErrors.Value(protocol.MsgTypeTimeSync, "slow_consumer"); got != 1 {
+		t.Errorf("slow consumer errors = %v, want 1", got)
+	}
+	var out strings.Builder
+	s.metrics.Registry.Write(&out)
+	for _, line := range []string{"syncserver_players_online 1", "syncserver_players_reconnecting 1"} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("metrics output missing %q", line)
+		}
+	}
+}
@@ -2,7 +2,11 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"math"
+	"net/http"
+	"sort"
 	"sync"
 	"syncServerDemo/gamesync"
 	"syncServerDemo/protocol"
@@ -13,15 +17,38 @@ import (
 // GameServer 游戏服务器
 type GameServer struct {
 	transport  transport.Transport
+	clock      gamesync.Clock
 	timeSyncer *gamesync.TimeSynchronizer
 	arbitrator *gamesync.PositionArbitrator
+	movement   gamesync.MovementModel // 新加入玩家的默认移动模型
+
+	config        Config
+	configErr     error         // 创建时的配置错误，由 Start 返回
+	configUpdated chan struct{} // 配置变化时关闭并替换
 
 	players map[string]*PlayerState // 玩家状态
+	clients map[string]string       // [clientID]playerID
+	rooms   map[string]*Room        // 房间
+	roomSeq int
 	mu      sync.RWMutex
 
+	returning        map[string]*PlayerState // 从快照恢复、尚未重新加入的玩家
+	store            PlayerStore             // 玩家状态存储（异步写入）
+	snapshotPath     string
+	snapshotInterval time.Duration
+
+	matchmaker *Matchmaker
+
 	positionReports map[string]map[string]protocol.PositionData // [playerID][reporterID]position
 	reportMu        sync.RWMutex
 
+	heartbeats map[string]*clientHeartbeat // [clientID]心跳状态
+	hbMu       sync.RWMutex
+	pingSeq    uint64
+
+	metrics        *Metrics
+	metricsServers []*http.Server
+
 	running  bool
 	stopChan chan struct{}
 }
@@ -29,25 +56,74 @@ type GameServer struct {
 // PlayerState 玩家状态
 type PlayerState struct {
 	PlayerID string
+	ClientID string
+	RoomID   string
 	X        float64
 	Y        float64
 	LastSync int64 // 最后同步时间
+
+	VectorX  float64 // 最后一次移动指令的方向
+	VectorY  float64
+	MoveTime int64 // 最后一次移动指令的游戏时间
+
+	VelocityX float64 // 最后一次移动指令生效时的速度
+	VelocityY float64
+
+	Movement     protocol.MovementParams   // 当前生效的移动属性
+	MovementTime int64                     // 移动属性生效的游戏时间
+	movement     gamesync.MovementModel    // 由 Movement 创建的移动模型
+	scheduled    []protocol.AttributesData // 尚未生效的属性变化，按生效时间排序
+
+	SessionToken   string    // 会话令牌
+	Status         string    // 连接状态
+	DisconnectedAt time.Time // 断线时间
 }
 
 // NewGameServer 创建游戏服务器
-func NewGameServer(transport transport.Transport) *GameServer {
-	return &GameServer{
+// 未指定的配置项使用 DefaultConfig 中的默认值；配置无效时 Start 返回错误
+func NewGameServer(transport transport.Transport, opts ...Option) *GameServer {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	clock := cfg.Clock
+	if clock == nil {
+		clock = gamesync.SystemClock()
+	}
+
+	s := &GameServer{
 		transport:       transport,
-		timeSyncer:      gamesync.NewTimeSynchronizer(),
-		arbitrator:      gamesync.NewPositionArbitrator(1.0), // 1.0单位的误差容忍
+		clock:           clock,
+		timeSyncer:      gamesync.NewTimeSynchronizerWithClock(clock),
+		configUpdated:   make(chan struct{}),
 		players:         make(map[string]*PlayerState),
+		clients:         make(map[string]string),
+		rooms:           make(map[string]*Room),
+		returning:       make(map[string]*PlayerState),
+		store:           newAsyncPlayerStore(NewMemoryPlayerStore()),
+		matchmaker:      NewMatchmaker(DefaultMatchRules()),
 		positionReports: make(map[string]map[string]protocol.PositionData),
+		heartbeats:      make(map[string]*clientHeartbeat),
 		stopChan:        make(chan struct{}),
 	}
+	s.rooms[DefaultRoomID] = newRoom(DefaultRoomID, "", nil)
+	s.metrics = newMetrics(s)
+	s.watchOverflow()
+
+	if err := s.applyConfig(cfg); err != nil {
+		s.configErr = fmt.Errorf("invalid server config: %w", err)
+		defaults := DefaultConfig()
+		defaults.Clock = cfg.Clock
+		s.applyConfig(defaults)
+	}
+	return s
 }
 
 // Start 启动服务器
 func (s *GameServer) Start() error {
+	if s.configErr != nil {
+		return s.configErr
+	}
 	s.running = true
 
 	// 启动消息处理协程
@@ -59,6 +135,20 @@ func (s *GameServer) Start() error {
 	// 启动位置仲裁协程
 	go s.arbitrationLoop()
 
+	// 启动匹配协程
+	go s.matchmakingLoop()
+
+	// 启动会话过期检查协程
+	go s.sessionLoop()
+
+	// 启动心跳协程
+	go s.heartbeatLoop()
+
+	// 启动定期快照协程
+	if s.snapshotPath != "" && s.snapshotInterval > 0 {
+		go s.snapshotLoop(s.snapshotPath, s.snapshotInterval)
+	}
+
 	log.Println("Game server started")
 	return nil
 }
@@ -67,6 +157,18 @@ func (s *GameServer) Start() error {
 func (s *GameServer) Stop() {
 	s.running = false
 	close(s.stopChan)
+
+	if s.snapshotPath != "" {
+		if err := s.SaveSnapshot(s.snapshotPath); err != nil {
+			log.Printf("Error saving snapshot: %v", err)
+		}
+	}
+
+	if err := s.store.Close(); err != nil {
+		log.Printf("Error closing player store: %v", err)
+	}
+
+	s.closeMetrics()
 	s.transport.Close()
 	log.Println("Game server stopped")
 }
@@ -82,10 +184,18 @@ func (s *GameServer) messageLoop() {
 			break
 		}
 
-		s.handleMessage(clientID, msg)
+		s.HandleMessage(clientID, msg)
 	}
 }
 
+// HandleMessage 处理一条客户端消息
+// 正常运行时由消息循环调用，回放和测试可以直接调用以逐条驱动服务器
+func (s *GameServer) HandleMessage(clientID string, msg transport.Message) {
+	s.metrics.MessagesIn.Inc(msg.GetType())
+	s.handleMessage(clientID, msg)
+	s.touchClient(clientID, s.clock.Now())
+}
+
 // handleMessage 处理消息
 func (s *GameServer) handleMessage(clientID string, msg transport.Message) {
 	switch msg.GetType() {
@@ -95,6 +205,14 @@ func (s *GameServer) handleMessage(clientID string, msg transport.Message) {
 		s.handleMove(clientID, msg)
 	case protocol.MsgTypePositionSync:
 		s.handlePositionSync(clientID, msg)
+	case protocol.MsgTypeQueueJoin:
+		s.handleQueueJoin(clientID, msg)
+	case protocol.MsgTypeQueueLeave:
+		s.handleQueueLeave(clientID, msg)
+	case protocol.MsgTypeLeave:
+		s.handleLeave(clientID, msg)
+	case protocol.MsgTypePong:
+		s.handlePong(clientID, msg)
 	default:
 		log.Printf("Unknown message type: %s", msg.GetType())
 	}
@@ -110,45 +228,199 @@ func (s *GameServer) handleJoin(clientID string, msg transport.Message) {
 
 	joinData := data.(*protocol.JoinData)
 	playerID := joinData.PlayerID
+	roomID := joinData.RoomID
+	if roomID == "" {
+		roomID = DefaultRoomID
+	}
+
+	// 加载持久化的玩家状态，在加锁之前完成以免慢存储阻塞其他消息
+	stored, hasStored, err := s.store.Load(playerID)
+	if err != nil {
+		log.Printf("Error loading player %s from store: %v", playerID, err)
+	}
 
 	s.mu.Lock()
-	s.players[playerID] = &PlayerState{
+
+	// 一个客户端只能绑定一个玩家，换用其他玩家需要先离开
+	if boundID, bound := s.clients[clientID]; bound && boundID != playerID {
+		s.mu.Unlock()
+		log.Printf("Player %s rejected: client %s is already playing as %s", playerID, clientID, boundID)
+		return
+	}
+
+	// 已存在的玩家只能凭会话令牌恢复
+	if existing, ok := s.players[playerID]; ok {
+		if joinData.SessionToken == "" || joinData.SessionToken != existing.SessionToken {
+			s.mu.Unlock()
+			log.Printf("Player %s rejected: invalid session token", playerID)
+			return
+		}
+
+		// 指定了其他房间时（如匹配成功后）先离开原房间
+		oldRoomID := ""
+		if joinData.RoomID != "" && joinData.RoomID != existing.RoomID {
+			room, exists := s.rooms[joinData.RoomID]
+			if !exists || !room.canJoin(playerID) {
+				s.mu.Unlock()
+				log.Printf("Player %s rejected from room %s", playerID, joinData.RoomID)
+				return
+			}
+			oldRoomID = s.movePlayer(existing, room)
+			s.matchmaker.Remove(playerID)
+		}
+
+		s.resumePlayer(existing, clientID)
+		welcome := s.buildWelcome(existing, true)
+		joined := playerJoinedData(existing)
+		s.mu.Unlock()
+
+		s.send(clientID, transport.NewMessage(protocol.MsgTypeWelcome, welcome))
+
+		if oldRoomID != "" {
+			leftMsg := transport.NewMessage(protocol.MsgTypePlayerLeft, protocol.PlayerLeftData{
+				PlayerID: playerID,
+			})
+			s.broadcastToRoom(oldRoomID, leftMsg, "")
+
+			joinedMsg := transport.NewMessage(protocol.MsgTypePlayerJoined, joined)
+			s.broadcastToRoom(joinData.RoomID, joinedMsg, clientID)
+
+			log.Printf("Player %s moved from room %s to room %s", playerID, oldRoomID, joinData.RoomID)
+			return
+		}
+
+		statusMsg := transport.NewMessage(protocol.MsgTypePlayerStatus, protocol.PlayerStatusData{
+			PlayerID: playerID,
+			Status:   protocol.PlayerStatusOnline,
+		})
+		s.broadcastToRoom(existing.RoomID, statusMsg, clientID)
+
+		log.Printf("Player %s resumed session in room %s", playerID, existing.RoomID)
+		return
+	}
+
+	// 快照恢复的玩家未指定房间时回到原房间
+	returning, isReturning := s.returning[playerID]
+	if isReturning && joinData.RoomID == "" {
+		if _, ok := s.rooms[returning.RoomID]; ok {
+			roomID = returning.RoomID
+		}
+	}
+
+	room, exists := s.rooms[roomID]
+	if !exists || !room.canJoin(playerID) {
+		s.mu.Unlock()
+		log.Printf("Player %s rejected from room %s", playerID, roomID)
+		return
+	}
+
+	player := &PlayerState{
 		PlayerID: playerID,
 		X:        0,
 		Y:        0,
 		LastSync: s.timeSyncer.GetGameTime(),
 	}
+	if isReturning {
+		player, _ = s.takeReturningPlayer(playerID)
+		log.Printf("Player %s returning at (%.2f, %.2f)", playerID, player.X, player.Y)
+	} else if hasStored {
+		// 存储中的时间可能来自之前的游戏时间基准，只恢复位置
+		player.X = stored.X
+		player.Y = stored.Y
+		player.MoveTime = player.LastSync
+		log.Printf("Player %s loaded from store at (%.2f, %.2f)", playerID, player.X, player.Y)
+	}
+	s.initMovement(player)
+	player.ClientID = clientID
+	player.RoomID = roomID
+	player.SessionToken = newSessionToken()
+	player.Status = protocol.PlayerStatusOnline
+	s.players[playerID] = player
+	s.clients[clientID] = playerID
+	room.players[playerID] = struct{}{}
+	s.matchmaker.Remove(playerID)
+
+	welcome := s.buildWelcome(player, false)
+	joined := playerJoinedData(player)
+	s.mu.Unlock()
+
+	// 发送欢迎消息
+	welcomeMsg := transport.NewMessage(protocol.MsgTypeWelcome, welcome)
+	s.send(clientID, welcomeMsg)
+
+	// 广播新玩家加入
+	joinedMsg := transport.NewMessage(protocol.MsgTypePlayerJoined, joined)
+	s.broadcastToRoom(roomID, joinedMsg, clientID)
+
+	log.Printf("Player %s joined room %s", playerID, roomID)
+}
+
+// buildWelcome 构造欢迎数据，包含房间内完整的状态快照（调用方需持有 s.mu 写锁）
+func (s *GameServer) buildWelcome(player *PlayerState, resumed bool) protocol.WelcomeData {
+	welcome := protocol.WelcomeData{
+		PlayerID:     player.PlayerID,
+		RoomID:       player.RoomID,
+		GameTime:     s.timeSyncer.GetGameTime(),
+		SessionToken: player.SessionToken,
+		Resumed:      resumed,
+		Movement:     s.movement.Params(),
+		Sync:         s.config.syncParams(),
+	}
 
-	// 获取当前所有玩家
-	players := make([]string, 0, len(s.players))
-	positions := make([]protocol.PositionData, 0, len(s.players))
-	for _, p := range s.players {
-		players = append(players, p.PlayerID)
-		positions = append(positions, protocol.PositionData{
+	room, exists := s.rooms[player.RoomID]
+	if !exists {
+		return welcome
+	}
+
+	for id := range room.players {
+		p := s.players[id]
+		s.settleMovement(p, welcome.GameTime)
+		welcome.Attributes = append(welcome.Attributes, movementAttributes(p)...)
+		welcome.Players = append(welcome.Players, p.PlayerID)
+		welcome.Positions = append(welcome.Positions, protocol.PositionData{
 			PlayerID: p.PlayerID,
 			X:        p.X,
 			Y:        p.Y,
 			GameTime: p.LastSync,
 		})
-	}
-	s.mu.Unlock()
+		welcome.Movements = append(welcome.Movements, protocol.MoveData{
+			PlayerID: p.PlayerID,
+			VectorX:  p.VectorX,
+			VectorY:  p.VectorY,
+			GameTime: p.MoveTime,
 
-	// 发送欢迎消息
-	welcomeMsg := transport.NewMessage(protocol.MsgTypeWelcome, protocol.WelcomeData{
-		PlayerID:  playerID,
-		GameTime:  s.timeSyncer.GetGameTime(),
-		Players:   players,
-		Positions: positions,
-	})
-	s.transport.Send(clientID, welcomeMsg)
+			VelocityX: p.VelocityX,
+			VelocityY: p.VelocityY,
+		})
+		if p.Status != protocol.PlayerStatusOnline {
+			welcome.Statuses = append(welcome.Statuses, protocol.PlayerStatusData{
+				PlayerID: p.PlayerID,
+				Status:   p.Status,
+			})
+		}
+	}
+	return welcome
+}
 
-	// 广播新玩家加入
-	joinedMsg := transport.NewMessage(protocol.MsgTypePlayerJoined, protocol.PlayerJoinedData{
-		PlayerID: playerID,
-	})
-	s.transport.Broadcast(joinedMsg, clientID)
+// playerJoinedData 构造玩家加入通知，包含玩家当前的位置和移动状态
+// 调用方需持有 s.mu 写锁，并已通过 buildWelcome 应用到期的属性变化
+func playerJoinedData(p *PlayerState) protocol.PlayerJoinedData {
+	return protocol.PlayerJoinedData{
+		PlayerID: p.PlayerID,
+		Movement: p.Movement,
+		X:        p.X,
+		Y:        p.Y,
+		GameTime: p.LastSync,
+		Move: protocol.MoveData{
+			PlayerID: p.PlayerID,
+			VectorX:  p.VectorX,
+			VectorY:  p.VectorY,
+			GameTime: p.MoveTime,
 
-	log.Printf("Player %s joined the game", playerID)
+			VelocityX: p.VelocityX,
+			VelocityY: p.VelocityY,
+		},
+	}
 }
 
 // handleMove 处理移动指令
@@ -161,9 +433,15 @@ func (s *GameServer) handleMove(clientID string, msg transport.Message) {
 
 	moveData := data.(*protocol.MoveData)
 
+	roomID, ok := s.recordMove(clientID, moveData)
+	if !ok {
+		log.Printf("Ignoring move command from client %s without a player", clientID)
+		return
+	}
+
 	// 服务器只转发移动指令，不计算位置
 	broadcastMsg := transport.NewMessage(protocol.MsgTypeMoveCommand, moveData)
-	s.transport.Broadcast(broadcastMsg, "")
+	s.broadcastToRoom(roomID, broadcastMsg, "")
 
 	log.Printf("Broadcasting move command from %s: vector(%.2f, %.2f) at time %d",
 		moveData.PlayerID, moveData.VectorX, moveData.VectorY, moveData.GameTime)
@@ -179,8 +457,22 @@ func (s *GameServer) handlePositionSync(clientID string, msg transport.Message)
 
 	syncData := data.(*protocol.PositionSyncData)
 
-	s.reportMu.Lock()
+	// 只接受同一房间内玩家的上报
+	s.mu.RLock()
+	reporterRoom := ""
+	if reporter, ok := s.players[s.clients[clientID]]; ok {
+		reporterRoom = reporter.RoomID
+	}
+	valid := make([]protocol.PositionData, 0, len(syncData.Positions))
 	for _, pos := range syncData.Positions {
+		if player, ok := s.players[pos.PlayerID]; ok && player.RoomID == reporterRoom {
+			valid = append(valid, pos)
+		}
+	}
+	s.mu.RUnlock()
+
+	s.reportMu.Lock()
+	for _, pos := range valid {
 		if s.positionReports[pos.PlayerID] == nil {
 			s.positionReports[pos.PlayerID] = make(map[string]protocol.PositionData)
 		}
@@ -194,32 +486,45 @@ func (s *GameServer) handlePositionSync(clientID string, msg transport.Message)
 
 // timeSyncLoop 时间同步循环
 func (s *GameServer) timeSyncLoop() {
-	ticker := time.NewTicker(1 * time.Second)
+	interval, updated := s.tickInterval(timeSyncInterval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			gameTime := s.timeSyncer.GetGameTime()
-			syncMsg := transport.NewMessage(protocol.MsgTypeTimeSync, protocol.TimeSyncData{
-				GameTime: gameTime,
-			})
-			s.transport.Broadcast(syncMsg, "")
+			s.SyncTimeNow()
+		case <-updated:
+			interval, updated = s.tickInterval(timeSyncInterval)
+			ticker.Reset(interval)
 		case <-s.stopChan:
 			return
 		}
 	}
 }
 
+// SyncTimeNow 立即向所有客户端广播一次游戏时间
+func (s *GameServer) SyncTimeNow() {
+	// 只有最新的游戏时间有意义，发送队列中未发出的旧时间同步会被取代
+	syncMsg := transport.WithCoalesceKey(transport.NewMessage(protocol.MsgTypeTimeSync, protocol.TimeSyncData{
+		GameTime: s.timeSyncer.GetGameTime(),
+	}), protocol.MsgTypeTimeSync)
+	s.broadcast(syncMsg)
+}
+
 // arbitrationLoop 位置仲裁循环
 func (s *GameServer) arbitrationLoop() {
-	ticker := time.NewTicker(500 * time.Millisecond)
+	interval, updated := s.tickInterval(arbitrationInterval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
 			s.performArbitration()
+		case <-updated:
+			interval, updated = s.tickInterval(arbitrationInterval)
+			ticker.Reset(interval)
 		case <-s.stopChan:
 			return
 		}
@@ -228,46 +533,222 @@ func (s *GameServer) arbitrationLoop() {
 
 // performArbitration 执行位置仲裁
 func (s *GameServer) performArbitration() {
+	// 取出上报时记下仲裁时间，之后到达的上报属于下一轮；回放据此判断上报与仲裁的先后
 	s.reportMu.Lock()
 	reports := s.positionReports
 	s.positionReports = make(map[string]map[string]protocol.PositionData)
+	tickTime := s.timeSyncer.GetGameTime()
 	s.reportMu.Unlock()
 
 	if len(reports) == 0 {
 		return
 	}
 
-	for playerID, reportMap := range reports {
+	// 耗时统计使用真实时间，与注入的时钟无关
+	start := time.Now()
+	defer func() {
+		s.metrics.ArbitrationTime.Observe(time.Since(start).Seconds())
+	}()
+
+	s.mu.RLock()
+	arbitrator := s.arbitrator
+	s.mu.RUnlock()
+
+	// 按玩家排序，保证仲裁结果的发送顺序确定
+	playerIDs := make([]string, 0, len(reports))
+	for playerID := range reports {
+		playerIDs = append(playerIDs, playerID)
+	}
+	sort.Strings(playerIDs)
+
+	// 仲裁结果按房间汇总，每轮每个客户端只收到一条批量消息
+	batches := make(map[string][]protocol.PositionUpdateData)
+	for _, playerID := range playerIDs {
+		reportMap := reports[playerID]
+		// 按上报者排序，保证相同输入得到相同的聚类结果（回放依赖这一点）
+		reporters := make([]string, 0, len(reportMap))
+		for reporterID := range reportMap {
+			reporters = append(reporters, reporterID)
+		}
+		sort.Strings(reporters)
+
 		positions := make([]protocol.PositionData, 0, len(reportMap))
-		for _, pos := range reportMap {
-			positions = append(positions, pos)
+		for _, reporterID := range reporters {
+			positions = append(positions, reportMap[reporterID])
 		}
 
 		// 仲裁位置
-		arbitratedPos := s.arbitrator.Arbitrate(positions)
+		result := arbitrator.ArbitrateDetailed(positions)
+		arbitratedPos := result.Position
+		s.recordArbitration(result, positions)
 		if arbitratedPos != nil {
 			// 更新服务器状态
 			s.mu.Lock()
-			if player, exists := s.players[playerID]; exists {
+			player, exists := s.players[playerID]
+			roomID := ""
+			if exists {
 				player.X = arbitratedPos.X
 				player.Y = arbitratedPos.Y
 				player.LastSync = arbitratedPos.GameTime
+				roomID = player.RoomID
+
+				// 异步写入存储，不阻塞仲裁；在锁内入队，玩家离开后的删除一定排在这次写入之后
+				s.store.Save(PlayerRecord{
+					PlayerID: playerID,
+					RoomID:   roomID,
+					X:        arbitratedPos.X,
+					Y:        arbitratedPos.Y,
+					LastSync: arbitratedPos.GameTime,
+				})
 			}
 			s.mu.Unlock()
 
-			// 广播仲裁结果
-			updateMsg := transport.NewMessage(protocol.MsgTypePositionUpdate, protocol.PositionUpdateData{
+			if !exists {
+				continue
+			}
+
+			batches[roomID] = append(batches[roomID], protocol.PositionUpdateData{
 				PlayerID: arbitratedPos.PlayerID,
 				X:        arbitratedPos.X,
 				Y:        arbitratedPos.Y,
 				GameTime: arbitratedPos.GameTime,
 			})
-			s.transport.Broadcast(updateMsg, "")
 
 			log.Printf("Arbitrated position for %s: (%.2f, %.2f) based on %d reports",
 				playerID, arbitratedPos.X, arbitratedPos.Y, len(positions))
 		}
 	}
+
+	s.sendPositionBatches(tickTime, batches)
+}
+
+// recordArbitration 记录一次仲裁的聚类情况和每份上报需要校正的幅度
+func (s *GameServer) recordArbitration(result gamesync.ArbitrationResult, positions []protocol.PositionData) {
+	for _, size := range result.ClusterSizes {
+		s.metrics.ClusterSize.Observe(float64(size))
+	}
+	if !result.Consensus {
+		s.metrics.NoConsensus.Inc()
+	}
+	if result.Position == nil {
+		return
+	}
+	for _, pos := range positions {
+		s.metrics.CorrectionLength.Observe(math.Hypot(pos.X-result.Position.X, pos.Y-result.Position.Y))
+	}
+}
+
+// handleQueueJoin 处理加入匹配队列
+func (s *GameServer) handleQueueJoin(clientID string, msg transport.Message) {
+	data, err := s.parseData(msg, &protocol.QueueJoinData{})
+	if err != nil {
+		log.Printf("Error parsing queue join data: %v", err)
+		return
+	}
+
+	queueData := data.(*protocol.QueueJoinData)
+
+	// 已在房间中的客户端需要先离开，未加入的客户端不能冒用已有玩家的ID
+	// 检查和入队在同一次加锁内完成，期间不会有加入请求绑定该客户端或占用该玩家ID
+	playerID := queueData.PlayerID
+	s.mu.Lock()
+	if seatedID, seated := s.clients[clientID]; seated {
+		s.mu.Unlock()
+		log.Printf("Player %s rejected from matchmaking: already in a room", seatedID)
+		return
+	}
+	if _, taken := s.players[playerID]; taken {
+		s.mu.Unlock()
+		log.Printf("Client %s rejected from matchmaking: player %s already exists", clientID, playerID)
+		return
+	}
+	err = s.matchmaker.Enqueue(clientID, playerID, queueData.Attributes, s.clock.Now())
+	s.mu.Unlock()
+	if err != nil {
+		log.Printf("Error queueing player %s: %v", playerID, err)
+		return
+	}
+
+	log.Printf("Player %s queued for matchmaking (region: %q, skill: %.0f)",
+		playerID, queueData.Attributes.Region, queueData.Attributes.Skill)
+}
+
+// handleQueueLeave 处理离开匹配队列
+func (s *GameServer) handleQueueLeave(clientID string, msg transport.Message) {
+	data, err := s.parseData(msg, &protocol.QueueLeaveData{})
+	if err != nil {
+		log.Printf("Error parsing queue leave data: %v", err)
+		return
+	}
+
+	// 只能移除自己的排队记录
+	leaveData := data.(*protocol.QueueLeaveData)
+	if queuedID, queued := s.matchmaker.QueuedPlayer(clientID); !queued || queuedID != leaveData.PlayerID {
+		log.Printf("Client %s rejected from leaving matchmaking: player %s is not queued by this client", clientID, leaveData.PlayerID)
+		return
+	}
+	if _, removed := s.matchmaker.RemoveClient(clientID); removed {
+		log.Printf("Player %s left matchmaking queue", leaveData.PlayerID)
+	}
+}
+
+// matchmakingLoop 匹配循环
+func (s *GameServer) matchmakingLoop() {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.performMatchmaking()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// performMatchmaking 执行一次匹配，为每个分组创建房间并通知成员
+func (s *GameServer) performMatchmaking() {
+	now := s.clock.Now()
+	s.expireRooms(now, s.matchmaker.joinTimeout())
+	groups := s.matchmaker.FormGroups(now)
+
+	for _, group := range groups {
+		playerIDs := make([]string, 0, len(group.Members))
+		for _, member := range group.Members {
+			playerIDs = append(playerIDs, member.PlayerID)
+		}
+
+		s.mu.Lock()
+		room := s.createRoom(group.Region, playerIDs)
+		s.mu.Unlock()
+
+		foundMsg := transport.NewMessage(protocol.MsgTypeMatchFound, protocol.MatchFoundData{
+			RoomID:  room.ID,
+			Players: playerIDs,
+		})
+		for _, member := range group.Members {
+			s.send(member.ClientID, foundMsg)
+		}
+
+		log.Printf("Matched %d players into room %s: %v", len(playerIDs), room.ID, playerIDs)
+	}
+}
+
+// SetPlayerStore 设置玩家状态存储，需在 Start 之前调用
+// 存储会被包装为异步写入，服务器停止时关闭
+func (s *GameServer) SetPlayerStore(store PlayerStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.store
+	s.store = newAsyncPlayerStore(store)
+	old.Close()
+}
+
+// Matchmaker 获取匹配器
+func (s *GameServer) Matchmaker() *Matchmaker {
+	return s.matchmaker
 }
 
 // parseData 解析消息数据
@@ -285,6 +766,21 @@ func (s *GameServer) parseData(msg transport.Message, target interface{}) (inter
 	return target, nil
 }
 
+// ArbitrateNow 立即执行一次位置仲裁
+func (s *GameServer) ArbitrateNow() {
+	s.performArbitration()
+}
+
+// GetGameTime 获取服务器当前游戏时间
+func (s *GameServer) GetGameTime() int64 {
+	return s.timeSyncer.GetGameTime()
+}
+
+// SetGameTime 设置服务器游戏时间（用于回放）
+func (s *GameServer) SetGameTime(gameTime int64) {
+	s.timeSyncer.SetGameTime(gameTime)
+}
+
 // GetPlayerCount 获取在线玩家数
 func (s *GameServer) GetPlayerCount() int {
 	s.mu.RLock()
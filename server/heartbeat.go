@@ -0,0 +1,199 @@
+package server
+
+import (
+	"log"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"time"
+)
+
+// RTTStats 客户端往返时延统计
+type RTTStats struct {
+	Last     time.Duration // 最近一次
+	Smoothed time.Duration // 平滑值
+	Min      time.Duration
+	Max      time.Duration
+	Samples  int
+	LastSeen time.Time // 最后一次收到该客户端消息的时间
+}
+
+// clientHeartbeat 客户端心跳状态
+type clientHeartbeat struct {
+	lastSeen time.Time
+	rtt      RTTStats
+}
+
+// touchClient 记录客户端活跃时间
+// 只跟踪已加入的客户端，未加入就发送消息的连接不占用心跳状态
+func (s *GameServer) touchClient(clientID string, now time.Time) {
+	s.mu.RLock()
+	_, joined := s.clients[clientID]
+	s.mu.RUnlock()
+	if !joined {
+		return
+	}
+
+	s.hbMu.Lock()
+	defer s.hbMu.Unlock()
+
+	hb, exists := s.heartbeats[clientID]
+	if !exists {
+		hb = &clientHeartbeat{}
+		s.heartbeats[clientID] = hb
+	}
+	hb.lastSeen = now
+}
+
+// forgetClient 清除客户端心跳状态
+func (s *GameServer) forgetClient(clientID string) {
+	s.hbMu.Lock()
+	defer s.hbMu.Unlock()
+	delete(s.heartbeats, clientID)
+}
+
+// handlePong 处理心跳响应，更新往返时延
+func (s *GameServer) handlePong(clientID string, msg transport.Message) {
+	data, err := s.parseData(msg, &protocol.PingData{})
+	if err != nil {
+		log.Printf("Error parsing pong data: %v", err)
+		return
+	}
+
+	pongData := data.(*protocol.PingData)
+	rtt := s.clock.Now().Sub(time.Unix(0, pongData.SentAt))
+	if rtt < 0 {
+		return
+	}
+
+	s.hbMu.Lock()
+	defer s.hbMu.Unlock()
+
+	hb, exists := s.heartbeats[clientID]
+	if !exists {
+		return
+	}
+
+	stats := &hb.rtt
+	stats.Last = rtt
+	if stats.Samples == 0 {
+		stats.Smoothed = rtt
+		stats.Min = rtt
+		stats.Max = rtt
+	} else {
+		// 与 TCP SRTT 相同的平滑系数 1/8
+		stats.Smoothed += (rtt - stats.Smoothed) / 8
+		stats.Min = min(stats.Min, rtt)
+		stats.Max = max(stats.Max, rtt)
+	}
+	stats.Samples++
+}
+
+// heartbeatLoop 心跳循环
+func (s *GameServer) heartbeatLoop() {
+	interval, updated := s.tickInterval(heartbeatInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.checkIdleClients(s.clock.Now())
+			s.sendPings()
+		case <-updated:
+			interval, updated = s.tickInterval(heartbeatInterval)
+			ticker.Reset(interval)
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// sendPings 向所有已加入的客户端发送心跳
+func (s *GameServer) sendPings() {
+	s.mu.RLock()
+	clientIDs := make([]string, 0, len(s.clients))
+	for clientID := range s.clients {
+		clientIDs = append(clientIDs, clientID)
+	}
+	s.mu.RUnlock()
+
+	s.pingSeq++
+	pingMsg := transport.NewMessage(protocol.MsgTypePing, protocol.PingData{
+		Seq:    s.pingSeq,
+		SentAt: s.clock.Now().UnixNano(),
+	})
+	for _, clientID := range clientIDs {
+		s.send(clientID, pingMsg)
+	}
+}
+
+// checkIdleClients 将超过空闲超时未发送任何消息的客户端视为断线
+func (s *GameServer) checkIdleClients(now time.Time) {
+	s.mu.RLock()
+	timeout := s.config.IdleTimeout
+	clientIDs := make([]string, 0, len(s.clients))
+	for clientID := range s.clients {
+		clientIDs = append(clientIDs, clientID)
+	}
+	s.mu.RUnlock()
+
+	var idle []string
+	s.hbMu.Lock()
+	// 清理在记录活跃时间之后断开的客户端
+	joined := make(map[string]bool, len(clientIDs))
+	for _, clientID := range clientIDs {
+		joined[clientID] = true
+	}
+	for clientID := range s.heartbeats {
+		if !joined[clientID] {
+			delete(s.heartbeats, clientID)
+		}
+	}
+	for _, clientID := range clientIDs {
+		hb, exists := s.heartbeats[clientID]
+		if !exists {
+			// 尚未收到过消息的客户端从现在开始计时
+			s.heartbeats[clientID] = &clientHeartbeat{lastSeen: now}
+			continue
+		}
+		if now.Sub(hb.lastSeen) >= timeout {
+			idle = append(idle, clientID)
+		}
+	}
+	s.hbMu.Unlock()
+
+	// 空闲的连接可能已经半开，关闭它，客户端才能用同一个ID重新连接
+	for _, clientID := range idle {
+		log.Printf("Client %s idle for more than %v", clientID, timeout)
+		s.Disconnect(clientID)
+		s.transport.Unregister(clientID)
+	}
+}
+
+// GetClientRTT 获取指定客户端的往返时延统计
+func (s *GameServer) GetClientRTT(clientID string) (RTTStats, bool) {
+	s.hbMu.RLock()
+	defer s.hbMu.RUnlock()
+
+	hb, exists := s.heartbeats[clientID]
+	if !exists {
+		return RTTStats{}, false
+	}
+	stats := hb.rtt
+	stats.LastSeen = hb.lastSeen
+	return stats, true
+}
+
+// GetRTTStats 获取所有客户端的往返时延统计
+func (s *GameServer) GetRTTStats() map[string]RTTStats {
+	s.hbMu.RLock()
+	defer s.hbMu.RUnlock()
+
+	result := make(map[string]RTTStats, len(s.heartbeats))
+	for clientID, hb := range s.heartbeats {
+		stats := hb.rtt
+		stats.LastSeen = hb.lastSeen
+		result[clientID] = stats
+	}
+	return result
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+)
+
+func TestHeartbeatsTrackJoinedClients(t *testing.T) {
+	s, lt := newTestServer(t)
+
+	// 从未加入的客户端发来的消息不产生心跳状态
+	s.HandleMessage("ghost", transport.NewMessage(protocol.MsgTypePong, protocol.PingData{Seq: 1}))
+
+	join(s, lt, "alice")
+	if _, ok := s.GetClientRTT("alice"); !ok {
+		t.Error("joined client alice has no heartbeat state")
+	}
+
+	s.HandleMessage("alice", transport.NewMessage(protocol.MsgTypeLeave, protocol.LeaveData{PlayerID: "alice"}))
+	if stats := s.GetRTTStats(); len(stats) != 0 {
+		t.Errorf("heartbeat state for %d clients after leave, want none", len(stats))
+	}
+}
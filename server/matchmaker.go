@@ -0,0 +1,266 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"syncServerDemo/protocol"
+	"time"
+)
+
+// MatchRules 匹配规则
+type MatchRules struct {
+	GroupSize        int           // 房间目标人数
+	MinGroupSize     int           // 等待超过 MaxWait 后允许开局的最少人数
+	MaxWait          time.Duration // 最长等待时间
+	MaxSkillSpread   float64       // 初始允许的组内最大技能差
+	SkillRelaxStep   float64       // 每个放宽周期增加的技能差
+	RelaxInterval    time.Duration // 放宽周期，0 表示不放宽
+	RegionRelaxAfter time.Duration // 等待超过该时间后允许跨区域匹配，0 表示不允许
+	JoinTimeout      time.Duration // 匹配成功后房间等待玩家加入的时间，超时仍无人加入的房间被删除，0 表示不删除
+}
+
+// DefaultMatchRules 默认匹配规则
+func DefaultMatchRules() MatchRules {
+	return MatchRules{
+		GroupSize:        4,
+		MinGroupSize:     2,
+		MaxWait:          30 * time.Second,
+		MaxSkillSpread:   100,
+		SkillRelaxStep:   50,
+		RelaxInterval:    5 * time.Second,
+		RegionRelaxAfter: 20 * time.Second,
+		JoinTimeout:      30 * time.Second,
+	}
+}
+
+// Validate 校验匹配规则
+func (r MatchRules) Validate() error {
+	if r.GroupSize < 1 {
+		return fmt.Errorf("group size must be positive, got %d", r.GroupSize)
+	}
+	if r.MinGroupSize < 1 || r.MinGroupSize > r.GroupSize {
+		return fmt.Errorf("min group size must be in [1, %d], got %d", r.GroupSize, r.MinGroupSize)
+	}
+	if r.MaxSkillSpread < 0 || r.SkillRelaxStep < 0 {
+		return fmt.Errorf("skill spread must not be negative")
+	}
+	if r.JoinTimeout < 0 {
+		return fmt.Errorf("join timeout must not be negative, got %v", r.JoinTimeout)
+	}
+	return nil
+}
+
+// skillSpreadFor 根据等待时间计算允许的技能差
+func (r MatchRules) skillSpreadFor(wait time.Duration) float64 {
+	if r.RelaxInterval <= 0 {
+		return r.MaxSkillSpread
+	}
+	steps := float64(wait / r.RelaxInterval)
+	return r.MaxSkillSpread + steps*r.SkillRelaxStep
+}
+
+// QueueEntry 匹配队列条目
+type QueueEntry struct {
+	ClientID   string
+	PlayerID   string
+	Attributes protocol.MatchAttributes
+	EnqueuedAt time.Time
+}
+
+// MatchGroup 匹配成功的一组玩家
+type MatchGroup struct {
+	Region  string
+	Members []*QueueEntry
+}
+
+// Matchmaker 匹配器
+// 维护等待队列，按规则将等待中的玩家组成房间
+type Matchmaker struct {
+	rules MatchRules
+	queue []*QueueEntry // 按入队时间排序
+	mu    sync.Mutex
+}
+
+// NewMatchmaker 创建匹配器
+func NewMatchmaker(rules MatchRules) *Matchmaker {
+	return &Matchmaker{
+		rules: rules,
+	}
+}
+
+// SetRules 更新匹配规则
+func (m *Matchmaker) SetRules(rules MatchRules) error {
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = rules
+	return nil
+}
+
+// Enqueue 加入匹配队列
+func (m *Matchmaker) Enqueue(clientID, playerID string, attrs protocol.MatchAttributes, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, entry := range m.queue {
+		if entry.PlayerID == playerID {
+			return fmt.Errorf("player %s already queued", playerID)
+		}
+		if entry.ClientID == clientID {
+			return fmt.Errorf("client %s already queued as %s", clientID, entry.PlayerID)
+		}
+	}
+
+	m.queue = append(m.queue, &QueueEntry{
+		ClientID:   clientID,
+		PlayerID:   playerID,
+		Attributes: attrs,
+		EnqueuedAt: now,
+	})
+	return nil
+}
+
+// Remove 从匹配队列移除玩家
+func (m *Matchmaker) Remove(playerID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, entry := range m.queue {
+		if entry.PlayerID == playerID {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// QueuedPlayer 获取客户端在队列中的玩家ID
+func (m *Matchmaker) QueuedPlayer(clientID string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, entry := range m.queue {
+		if entry.ClientID == clientID {
+			return entry.PlayerID, true
+		}
+	}
+	return "", false
+}
+
+// RemoveClient 从匹配队列移除客户端，返回被移除的玩家ID
+func (m *Matchmaker) RemoveClient(clientID string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, entry := range m.queue {
+		if entry.ClientID == clientID {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return entry.PlayerID, true
+		}
+	}
+	return "", false
+}
+
+// joinTimeout 获取房间等待玩家加入的时间
+func (m *Matchmaker) joinTimeout() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rules.JoinTimeout
+}
+
+// QueueLength 获取队列长度
+func (m *Matchmaker) QueueLength() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queue)
+}
+
+// FormGroups 尝试组队，返回匹配成功的分组并将其移出队列
+// 以等待最久的玩家为锚点，优先选择技能最接近的玩家
+func (m *Matchmaker) FormGroups(now time.Time) []MatchGroup {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var groups []MatchGroup
+	taken := make(map[*QueueEntry]bool)
+
+	for _, anchor := range m.queue {
+		if taken[anchor] {
+			continue
+		}
+
+		wait := now.Sub(anchor.EnqueuedAt)
+		members := m.collectGroup(anchor, wait, taken)
+
+		if len(members) >= m.rules.GroupSize ||
+			(wait >= m.rules.MaxWait && len(members) >= m.rules.MinGroupSize) {
+			for _, member := range members {
+				taken[member] = true
+			}
+			groups = append(groups, MatchGroup{
+				Region:  anchor.Attributes.Region,
+				Members: members,
+			})
+		}
+	}
+
+	if len(groups) > 0 {
+		remaining := m.queue[:0]
+		for _, entry := range m.queue {
+			if !taken[entry] {
+				remaining = append(remaining, entry)
+			}
+		}
+		m.queue = remaining
+	}
+
+	return groups
+}
+
+// collectGroup 以锚点为中心挑选满足条件的玩家
+func (m *Matchmaker) collectGroup(anchor *QueueEntry, wait time.Duration, taken map[*QueueEntry]bool) []*QueueEntry {
+	spread := m.rules.skillSpreadFor(wait)
+	anyRegion := m.rules.RegionRelaxAfter > 0 && wait >= m.rules.RegionRelaxAfter
+
+	candidates := make([]*QueueEntry, 0, len(m.queue))
+	for _, entry := range m.queue {
+		if entry == anchor || taken[entry] {
+			continue
+		}
+		if !anyRegion && entry.Attributes.Region != anchor.Attributes.Region {
+			continue
+		}
+		if math.Abs(entry.Attributes.Skill-anchor.Attributes.Skill) > spread {
+			continue
+		}
+		candidates = append(candidates, entry)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return math.Abs(candidates[i].Attributes.Skill-anchor.Attributes.Skill) <
+			math.Abs(candidates[j].Attributes.Skill-anchor.Attributes.Skill)
+	})
+
+	members := []*QueueEntry{anchor}
+	minSkill, maxSkill := anchor.Attributes.Skill, anchor.Attributes.Skill
+	for _, candidate := range candidates {
+		if len(members) >= m.rules.GroupSize {
+			break
+		}
+
+		skill := candidate.Attributes.Skill
+		if math.Max(maxSkill, skill)-math.Min(minSkill, skill) > spread {
+			continue
+		}
+		minSkill = math.Min(minSkill, skill)
+		maxSkill = math.Max(maxSkill, skill)
+		members = append(members, candidate)
+	}
+
+	return members
+}
@@ -0,0 +1,353 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syncServerDemo/protocol"
+	"time"
+)
+
+// Registry 指标注册表，以 Prometheus 文本格式导出
+// 只实现服务器需要的计数器、瞬时值和直方图，不依赖外部库
+type Registry struct {
+	metrics []collector // 按注册顺序导出
+	names   map[string]bool
+	mu      sync.Mutex
+}
+
+// collector 可导出的指标
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// register 注册指标，名称重复时 panic（属于编程错误）
+func (r *Registry) register(name string, m collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic(fmt.Sprintf("metric %s already registered", name))
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Write 以 Prometheus 文本格式写出所有指标
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]collector(nil), r.metrics...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP 实现 http.Handler
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.Write(w)
+}
+
+// writeHeader 写出 HELP 和 TYPE 行
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// formatLabels 格式化标签，names 和 values 一一对应
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf(`%s="%s"`, name, escape.Replace(values[i]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// CounterVec 按标签区分的单调递增计数器，没有标签时只有一条时间序列
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+	values map[string]float64  // [标签值拼接]计数
+	keys   map[string][]string // [标签值拼接]标签值
+	mu     sync.Mutex
+}
+
+// NewCounter 注册计数器
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+		keys:   make(map[string][]string),
+	}
+	if len(labels) == 0 {
+		// 没有标签的计数器从 0 开始导出，便于计算速率
+		c.values[""] = 0
+	}
+	r.register(name, c)
+	return c
+}
+
+// Add 增加计数，标签值的个数必须与注册时的标签一致
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if len(labelValues) != len(c.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", c.name, len(c.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.keys[key]; !ok {
+		c.keys[key] = append([]string(nil), labelValues...)
+	}
+	c.values[key] += v
+}
+
+// Inc 计数加一
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Value 获取计数
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[strings.Join(labelValues, "\xff")]
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, c.keys[key]), formatFloat(c.values[key]))
+	}
+}
+
+// GaugeFunc 导出时才计算的瞬时值
+type GaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// NewGaugeFunc 注册瞬时值，每次导出时调用 fn
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, fn: fn}
+	r.register(name, g)
+	return g
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+}
+
+// Histogram 累积分桶的直方图
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64 // 升序的桶上界，不含 +Inf
+	counts  []uint64  // 每个桶（非累积）的计数，最后一个为 +Inf
+	count   uint64
+	sum     float64
+	mu      sync.Mutex
+}
+
+// NewHistogram 注册直方图，buckets 为升序的桶上界
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	h := &Histogram{
+		name:    name,
+		help:    help,
+		buckets: sorted,
+		counts:  make([]uint64, len(sorted)+1),
+	}
+	r.register(name, h)
+	return h
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.count++
+	h.sum += v
+}
+
+// Count 观测次数
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// Sum 观测值之和
+func (h *Histogram) Sum() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sum
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	var cumulative uint64
+	for i, upper := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, formatFloat(upper), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.name, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.name, h.count)
+}
+
+// Metrics 服务器运行指标
+type Metrics struct {
+	Registry *Registry
+
+	MessagesIn       *CounterVec // 收到的客户端消息，按类型
+	MessagesOut      *CounterVec // 发出的消息，广播按接收者计数，按类型
+	SendErrors       *CounterVec // 传输层拒绝的发送，广播按失败的接收者计数，按类型和原因
+	SendOverflow     *CounterVec // 发送队列满时被丢弃或替换的已入队消息，按溢出策略和类型
+	BroadcastFanout  *Histogram  // 每次广播的接收者数
+	ArbitrationTime  *Histogram  // 每轮仲裁的耗时（秒）
+	ClusterSize      *Histogram  // 仲裁时每个位置簇包含的上报数
+	NoConsensus      *CounterVec // 最大簇没有超过半数上报的仲裁次数
+	CorrectionLength *Histogram  // 每份上报与仲裁结果之间的距离，即上报者需要校正的幅度
+}
+
+// newMetrics 创建服务器指标，在线人数等瞬时值在导出时从服务器读取
+func newMetrics(s *GameServer) *Metrics {
+	r := NewRegistry()
+	m := &Metrics{
+		Registry: r,
+		MessagesIn: r.NewCounter("syncserver_messages_in_total",
+			"Messages received from clients.", "type"),
+		MessagesOut: r.NewCounter("syncserver_messages_out_total",
+			"Messages sent to clients, counted per recipient.", "type"),
+		SendErrors: r.NewCounter("syncserver_send_errors_total",
+			"Sends rejected by the transport, counted per failed recipient.", "type", "reason"),
+		SendOverflow: r.NewCounter("syncserver_send_overflow_total",
+			"Queued messages dropped or replaced when a send queue was full.", "policy", "type"),
+		BroadcastFanout: r.NewHistogram("syncserver_broadcast_fanout",
+			"Recipients per broadcast.", []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}),
+		ArbitrationTime: r.NewHistogram("syncserver_arbitration_duration_seconds",
+			"Time spent in one arbitration tick.", []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5}),
+		ClusterSize: r.NewHistogram("syncserver_arbitration_cluster_size",
+			"Reports per position cluster during arbitration.", []float64{1, 2, 3, 4, 5, 10, 20, 50, 100}),
+		NoConsensus: r.NewCounter("syncserver_arbitration_no_consensus_total",
+			"Arbitrations where the largest cluster held no more than half of the reports."),
+		CorrectionLength: r.NewHistogram("syncserver_arbitration_correction",
+			"Distance between each report and the arbitrated position.", []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 50}),
+	}
+
+	r.NewGaugeFunc("syncserver_players_online", "Players currently connected.", func() float64 {
+		return float64(s.countPlayers(protocol.PlayerStatusOnline))
+	})
+	r.NewGaugeFunc("syncserver_players_reconnecting", "Players disconnected within the reconnect grace period.", func() float64 {
+		return float64(s.countPlayers(protocol.PlayerStatusReconnecting))
+	})
+	r.NewGaugeFunc("syncserver_rooms", "Rooms currently open.", func() float64 {
+		return float64(s.GetRoomCount())
+	})
+	return m
+}
+
+// countPlayers 统计指定连接状态的玩家数
+func (s *GameServer) countPlayers(status string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := 0
+	for _, player := range s.players {
+		if player.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Metrics 获取服务器指标
+func (s *GameServer) Metrics() *Metrics {
+	return s.metrics
+}
+
+// ServeMetrics 在 addr 上以 Prometheus 文本格式提供 /metrics，服务器停止时关闭
+// 建议只监听本地地址（如 127.0.0.1:9100），返回实际监听的地址
+func (s *GameServer) ServeMetrics(addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.metrics.Registry)
+	httpServer := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	s.mu.Lock()
+	s.metricsServers = append(s.metricsServers, httpServer)
+	s.mu.Unlock()
+
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Metrics server error: %v", err)
+		}
+	}()
+	return listener.Addr(), nil
+}
+
+// closeMetrics 关闭指标 HTTP 服务
+func (s *GameServer) closeMetrics() {
+	s.mu.Lock()
+	servers := s.metricsServers
+	s.metricsServers = nil
+	s.mu.Unlock()
+
+	for _, httpServer := range servers {
+		httpServer.Close()
+	}
+}
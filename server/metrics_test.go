@@ -0,0 +1,64 @@
+package server
+
+import (
+	"strings"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+)
+
+func TestServerMetrics(t *testing.T) {
+	s, lt := newTestServer(t)
+	players := []string{"alice", "bob", "charlie", "mallory"}
+	join(s, lt, players...)
+
+	// 三个客户端上报相同的位置，作弊者把所有玩家都报在 (50, 50)
+	var honest, cheat []protocol.PositionData
+	for _, playerID := range players {
+		honest = append(honest, protocol.PositionData{PlayerID: playerID})
+		cheat = append(cheat, protocol.PositionData{PlayerID: playerID, X: 50, Y: 50})
+	}
+	for _, clientID := range players {
+		reports := honest
+		if clientID == "mallory" {
+			reports = cheat
+		}
+		s.HandleMessage(clientID, transport.NewMessage(protocol.MsgTypePositionSync, protocol.PositionSyncData{Positions: reports}))
+	}
+	s.ArbitrateNow()
+
+	metrics := s.Metrics()
+	for msgType, want := range map[string]float64{protocol.MsgTypeJoin: 4, protocol.MsgTypePositionSync: 4} {
+		if got := metrics.MessagesIn.Value(msgType); got != want {
+			t.Errorf("messages in %s = %v, want %v", msgType, got, want)
+		}
+	}
+	for msgType, want := range map[string]float64{protocol.MsgTypeWelcome: 4, protocol.MsgTypePositionBatch: 4} {
+		if got := metrics.MessagesOut.Value(msgType); got != want {
+			t.Errorf("messages out %s = %v, want %v", msgType, got, want)
+		}
+	}
+
+	// 作弊者的上报单独成簇，每个玩家需要校正约 70.7
+	if metrics.NoConsensus.Value() != 0 {
+		t.Errorf("no consensus = %v, want 0 with a single cheater", metrics.NoConsensus.Value())
+	}
+	if sum := metrics.CorrectionLength.Sum(); sum < 4*70 || sum > 4*71 {
+		t.Errorf("correction sum = %v, want the cheater's offsets to be counted", sum)
+	}
+
+	var out strings.Builder
+	if err := metrics.Registry.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`syncserver_messages_in_total{type="join"} 4`,
+		"# TYPE syncserver_arbitration_duration_seconds histogram",
+		"syncserver_arbitration_no_consensus_total 0",
+		"syncserver_players_online 4",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("metrics output missing %q", line)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"time"
+)
+
+// DefaultRoomID 未指定房间时加入的默认房间
+const DefaultRoomID = "default"
+
+// Room 游戏房间
+// 同一房间内的玩家互相可见，移动指令和仲裁结果只在房间内广播
+type Room struct {
+	ID        string
+	Region    string
+	CreatedAt time.Time
+
+	players  map[string]struct{} // 房间内的玩家
+	expected map[string]struct{} // 匹配分配到该房间的玩家，为空表示不限制
+}
+
+// newRoom 创建房间
+func newRoom(id, region string, expected []string) *Room {
+	room := &Room{
+		ID:        id,
+		Region:    region,
+		CreatedAt: time.Now(),
+		players:   make(map[string]struct{}),
+	}
+	if len(expected) > 0 {
+		room.expected = make(map[string]struct{}, len(expected))
+		for _, playerID := range expected {
+			room.expected[playerID] = struct{}{}
+		}
+	}
+	return room
+}
+
+// canJoin 判断玩家是否允许加入该房间
+func (r *Room) canJoin(playerID string) bool {
+	if r.expected == nil {
+		return true
+	}
+	_, ok := r.expected[playerID]
+	return ok
+}
+
+// PlayerIDs 获取房间内的玩家
+func (r *Room) PlayerIDs() []string {
+	ids := make([]string, 0, len(r.players))
+	for id := range r.players {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// createRoom 创建房间（调用方需持有 s.mu 写锁）
+func (s *GameServer) createRoom(region string, expected []string) *Room {
+	s.roomSeq++
+	room := newRoom(fmt.Sprintf("room-%d", s.roomSeq), region, expected)
+	room.CreatedAt = s.clock.Now()
+	s.rooms[room.ID] = room
+	return room
+}
+
+// movePlayer 将玩家移到另一个房间，返回原房间ID（调用方需持有 s.mu 写锁）
+// 原房间为空时与 removePlayer 一样删除
+func (s *GameServer) movePlayer(player *PlayerState, room *Room) string {
+	oldRoomID := player.RoomID
+	if oldRoom, ok := s.rooms[oldRoomID]; ok {
+		delete(oldRoom.players, player.PlayerID)
+		if len(oldRoom.players) == 0 && oldRoom.ID != DefaultRoomID {
+			delete(s.rooms, oldRoom.ID)
+		}
+	}
+	player.RoomID = room.ID
+	room.players[player.PlayerID] = struct{}{}
+	return oldRoomID
+}
+
+// expireRooms 删除创建后超过 timeout 仍无人加入的匹配房间
+// 快照恢复的玩家尚未返回的房间保留
+func (s *GameServer) expireRooms(now time.Time, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	awaited := make(map[string]bool, len(s.returning))
+	for _, player := range s.returning {
+		awaited[player.RoomID] = true
+	}
+	var expired []string
+	for id, room := range s.rooms {
+		if id == DefaultRoomID || len(room.players) > 0 || awaited[id] {
+			continue
+		}
+		if now.Sub(room.CreatedAt) >= timeout {
+			delete(s.rooms, id)
+			expired = append(expired, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range expired {
+		log.Printf("Room %s expired: expected players never joined", id)
+	}
+}
+
+// roomClientIDs 获取房间内所有玩家的客户端ID（调用方需持有 s.mu 读锁）
+func (s *GameServer) roomClientIDs(roomID string) []string {
+	room, exists := s.rooms[roomID]
+	if !exists {
+		return nil
+	}
+
+	clientIDs := make([]string, 0, len(room.players))
+	for playerID := range room.players {
+		if player, ok := s.players[playerID]; ok && player.Status == protocol.PlayerStatusOnline {
+			clientIDs = append(clientIDs, player.ClientID)
+		}
+	}
+	return clientIDs
+}
+
+// broadcastToRoom 广播消息到房间内所有客户端（排除指定客户端ID）
+func (s *GameServer) broadcastToRoom(roomID string, msg transport.Message, excludeClientID string) {
+	s.mu.RLock()
+	clientIDs := s.roomClientIDs(roomID)
+	s.mu.RUnlock()
+
+	recipients := 0
+	for _, clientID := range clientIDs {
+		if clientID == excludeClientID {
+			continue
+		}
+		s.send(clientID, msg)
+		recipients++
+	}
+	s.metrics.BroadcastFanout.Observe(float64(recipients))
+}
+
+// sendPositionBatches 发送一轮仲裁的结果，每个房间的结果合并为一条消息
+// 启用兴趣范围时按接收者筛选，每个客户端只收到自己附近的玩家
+func (s *GameServer) sendPositionBatches(gameTime int64, batches map[string][]protocol.PositionUpdateData) {
+	s.mu.RLock()
+	radius := s.config.InterestRadius
+	s.mu.RUnlock()
+
+	roomIDs := make([]string, 0, len(batches))
+	for roomID := range batches {
+		roomIDs = append(roomIDs, roomID)
+	}
+	sort.Strings(roomIDs)
+
+	for _, roomID := range roomIDs {
+		if radius <= 0 {
+			s.broadcastToRoom(roomID, positionBatchMessage(gameTime, batches[roomID]), "")
+			continue
+		}
+		s.sendByInterest(roomID, gameTime, batches[roomID], radius)
+	}
+}
+
+// sendByInterest 按兴趣范围为房间内每个客户端筛选仲裁结果，客户端自己的玩家总是包含在内
+func (s *GameServer) sendByInterest(roomID string, gameTime int64, positions []protocol.PositionUpdateData, radius float64) {
+	type viewer struct {
+		clientID string
+		playerID string
+		x, y     float64
+	}
+
+	s.mu.RLock()
+	var viewers []viewer
+	if room, exists := s.rooms[roomID]; exists {
+		for playerID := range room.players {
+			if player, ok := s.players[playerID]; ok && player.Status == protocol.PlayerStatusOnline {
+				viewers = append(viewers, viewer{player.ClientID, playerID, player.X, player.Y})
+			}
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(viewers, func(i, j int) bool { return viewers[i].playerID < viewers[j].playerID })
+
+	recipients := 0
+	for _, v := range viewers {
+		var visible []protocol.PositionUpdateData
+		for _, pos := range positions {
+			if pos.PlayerID == v.playerID || math.Hypot(pos.X-v.x, pos.Y-v.y) <= radius {
+				visible = append(visible, pos)
+			}
+		}
+		if len(visible) == 0 {
+			continue
+		}
+		s.send(v.clientID, positionBatchMessage(gameTime, visible))
+		recipients++
+	}
+	s.metrics.BroadcastFanout.Observe(float64(recipients))
+}
+
+// positionBatchMessage 创建批量位置消息，发送队列中未发出的上一批会并入这一批
+func positionBatchMessage(gameTime int64, positions []protocol.PositionUpdateData) transport.Message {
+	return transport.WithCoalesceMerge(transport.NewMessage(protocol.MsgTypePositionBatch, protocol.PositionBatchData{
+		GameTime:  gameTime,
+		Positions: positions,
+	}), protocol.MsgTypePositionBatch, mergePositionBatches)
+}
+
+// mergePositionBatches 合并两批仲裁结果，每个玩家保留游戏时间最新的一条
+// 只出现在上一批中的玩家（本轮没有上报，或已离开接收方的兴趣范围）不会丢失
+func mergePositionBatches(pending, newer transport.Message) transport.Message {
+	older, ok := pending.GetData().(protocol.PositionBatchData)
+	if !ok {
+		return newer
+	}
+	latest, ok := newer.GetData().(protocol.PositionBatchData)
+	if !ok {
+		return newer
+	}
+
+	byPlayer := make(map[string]protocol.PositionUpdateData, len(older.Positions)+len(latest.Positions))
+	for _, update := range older.Positions {
+		byPlayer[update.PlayerID] = update
+	}
+	for _, update := range latest.Positions {
+		if prev, exists := byPlayer[update.PlayerID]; !exists || update.GameTime >= prev.GameTime {
+			byPlayer[update.PlayerID] = update
+		}
+	}
+
+	positions := make([]protocol.PositionUpdateData, 0, len(byPlayer))
+	for _, update := range byPlayer {
+		positions = append(positions, update)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].PlayerID < positions[j].PlayerID })
+	return transport.NewMessage(protocol.MsgTypePositionBatch, protocol.PositionBatchData{
+		GameTime:  max(older.GameTime, latest.GameTime),
+		Positions: positions,
+	})
+}
+
+// send 发送消息到指定客户端并记录指标
+func (s *GameServer) send(clientID string, msg transport.Message) error {
+	if err := s.transport.Send(clientID, msg); err != nil {
+		s.handleSendFailure(msg, err)
+		return err
+	}
+	s.metrics.MessagesOut.Inc(msg.GetType())
+	return nil
+}
+
+// broadcast 通过传输层广播消息到所有已连接的客户端并记录指标
+// 传输层按自己注册的客户端展开广播，这里以服务器记录的连接数作为接收者数
+func (s *GameServer) broadcast(msg transport.Message) error {
+	s.mu.RLock()
+	recipients := len(s.clients)
+	s.mu.RUnlock()
+	s.metrics.BroadcastFanout.Observe(float64(recipients))
+
+	err := s.transport.Broadcast(msg, "")
+	delivered := recipients
+	if err != nil {
+		failed := s.handleSendFailure(msg, err)
+		if failed == 0 {
+			// 整个广播失败（如传输层已关闭）
+			return err
+		}
+		delivered = max(recipients-failed, 0)
+	}
+	s.metrics.MessagesOut.Add(float64(delivered), msg.GetType())
+	return err
+}
+
+// GetRoomCount 获取房间数
+func (s *GameServer) GetRoomCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.rooms)
+}
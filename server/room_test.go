@@ -0,0 +1,71 @@
+package server
+
+import (
+	"reflect"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+)
+
+// batchPlayers 读取客户端收到的下一条批量位置消息中的玩家
+func batchPlayers(t *testing.T, lt *transport.LocalTransport, clientID string) []string {
+	t.Helper()
+	var players []string
+	batch := nextMessage(t, lt, clientID, protocol.MsgTypePositionBatch).GetData().(protocol.PositionBatchData)
+	for _, update := range batch.Positions {
+		players = append(players, update.PlayerID)
+	}
+	return players
+}
+
+func TestPositionBatchInterest(t *testing.T) {
+	tests := []struct {
+		name   string
+		radius float64
+		want   map[string][]string
+	}{
+		{"whole room", 0, map[string][]string{
+			"alice": {"alice", "bob", "charlie"},
+			"bob":   {"alice", "bob", "charlie"},
+		}},
+		{"interest radius", 20, map[string][]string{
+			"alice": {"alice", "charlie"},
+			"bob":   {"bob"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, lt := newTestServer(t, WithInterestRadius(tt.radius))
+			join(s, lt, "alice", "bob", "charlie")
+			reportAll(s, map[string][2]float64{"alice": {0, 0}, "bob": {100, 0}, "charlie": {10, 10}})
+			s.ArbitrateNow()
+
+			for clientID, want := range tt.want {
+				if got := batchPlayers(t, lt, clientID); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s received %v, want %v", clientID, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPositionBatchesMerge(t *testing.T) {
+	s, lt := newTestServer(t, WithInterestRadius(20))
+	join(s, lt, "alice", "bob")
+
+	// alice 不读取消息，两批结果都留在发送队列中；第二批时 bob 已离开 alice 的兴趣范围
+	reportAll(s, map[string][2]float64{"alice": {0, 0}, "bob": {10, 0}})
+	s.ArbitrateNow()
+	reportAll(s, map[string][2]float64{"alice": {1, 0}, "bob": {50, 0}})
+	s.ArbitrateNow()
+
+	batch := nextMessage(t, lt, "alice", protocol.MsgTypePositionBatch).GetData().(protocol.PositionBatchData)
+	var got [][2]float64
+	for _, update := range batch.Positions {
+		got = append(got, [2]float64{update.X, update.Y})
+	}
+	if want := [][2]float64{{1, 0}, {10, 0}}; !reflect.DeepEqual(got, want) || batch.Positions[1].PlayerID != "bob" {
+		t.Errorf("alice received %+v, want her latest position and bob's last one in range", batch.Positions)
+	}
+}
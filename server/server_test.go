@@ -0,0 +1,65 @@
+package server
+
+import (
+	"io"
+	"log"
+	"os"
+	"syncServerDemo/gamesync"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// 服务器日志在测试中没有意义，失败信息由断言给出
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testEpoch 测试时钟的起点
+var testEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestServer 创建使用本地传输层和手动时钟的服务器，不启动后台循环
+func newTestServer(t *testing.T, opts ...Option) (*GameServer, *transport.LocalTransport) {
+	t.Helper()
+	lt := transport.NewLocalTransport()
+	t.Cleanup(func() { lt.Close() })
+	opts = append([]Option{WithClock(gamesync.NewManualClock(testEpoch))}, opts...)
+	return NewGameServer(lt, opts...), lt
+}
+
+// join 注册客户端并以同名玩家加入
+func join(s *GameServer, lt *transport.LocalTransport, playerIDs ...string) {
+	for _, playerID := range playerIDs {
+		lt.Register(playerID)
+		s.HandleMessage(playerID, transport.NewMessage(protocol.MsgTypeJoin, protocol.JoinData{PlayerID: playerID}))
+	}
+}
+
+// nextMessage 读取客户端收到的下一条指定类型的消息，跳过其他消息
+func nextMessage(t *testing.T, lt *transport.LocalTransport, clientID, msgType string) transport.Message {
+	t.Helper()
+	ch, err := lt.GetClientChannel(clientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for msg := range ch {
+		if msg.GetType() == msgType {
+			return msg
+		}
+	}
+	t.Fatalf("%s: channel closed before %s", clientID, msgType)
+	return nil
+}
+
+// reportAll 每个客户端上报所有玩家的位置，仲裁结果即为这些位置
+func reportAll(s *GameServer, positions map[string][2]float64) {
+	var reports []protocol.PositionData
+	for playerID, pos := range positions {
+		reports = append(reports, protocol.PositionData{PlayerID: playerID, X: pos[0], Y: pos[1]})
+	}
+	for playerID := range positions {
+		s.HandleMessage(playerID, transport.NewMessage(protocol.MsgTypePositionSync, protocol.PositionSyncData{Positions: reports}))
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"syncServerDemo/gamesync"
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"time"
+)
+
+// newSessionToken 生成随机会话令牌
+func newSessionToken() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("Error generating session token: %v", err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// resumePlayer 将保留的玩家重新绑定到新的客户端连接（调用方需持有 s.mu 写锁）
+func (s *GameServer) resumePlayer(player *PlayerState, clientID string) {
+	if player.ClientID != clientID {
+		delete(s.clients, player.ClientID)
+	}
+	player.ClientID = clientID
+	player.Status = protocol.PlayerStatusOnline
+	player.DisconnectedAt = time.Time{}
+	s.clients[clientID] = player.PlayerID
+}
+
+// recordMove 记录玩家最后的移动指令，用于向新加入或重连的客户端下发快照
+// 指令归属于客户端当前绑定的玩家，消息中的 PlayerID 被改写为该玩家
+func (s *GameServer) recordMove(clientID string, moveData *protocol.MoveData) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player, exists := s.players[s.clients[clientID]]
+	if !exists {
+		return "", false
+	}
+	moveData.PlayerID = player.PlayerID
+
+	// 按上一条指令推进到新指令的时间，记录此刻的速度
+	s.settleMovement(player, moveData.GameTime)
+	state := player.movement.Advance(gamesync.MovementState{
+		VelocityX: player.VelocityX,
+		VelocityY: player.VelocityY,
+	}, player.VectorX, player.VectorY, float64(moveData.GameTime-player.MoveTime)/1000.0)
+	state = player.movement.Advance(state, moveData.VectorX, moveData.VectorY, 0)
+
+	player.VectorX = moveData.VectorX
+	player.VectorY = moveData.VectorY
+	player.MoveTime = moveData.GameTime
+	player.VelocityX = state.VelocityX
+	player.VelocityY = state.VelocityY
+	return player.RoomID, true
+}
+
+// Disconnect 处理客户端断线
+// 玩家实体在宽限期内保留，其他客户端看到的是“重连中”状态而不是离开；
+// 只在匹配队列中、尚未加入的客户端直接移出队列，以免与在线玩家组成房间
+func (s *GameServer) Disconnect(clientID string) {
+	s.mu.Lock()
+	if queuedID, queued := s.matchmaker.RemoveClient(clientID); queued {
+		log.Printf("Player %s removed from matchmaking queue: client disconnected", queuedID)
+	}
+	playerID, exists := s.clients[clientID]
+	if !exists {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.clients, clientID)
+
+	player := s.players[playerID]
+	player.Status = protocol.PlayerStatusReconnecting
+	player.DisconnectedAt = s.clock.Now()
+	roomID := player.RoomID
+	grace := s.config.ReconnectGrace
+	s.mu.Unlock()
+
+	s.forgetClient(clientID)
+
+	statusMsg := transport.NewMessage(protocol.MsgTypePlayerStatus, protocol.PlayerStatusData{
+		PlayerID: playerID,
+		Status:   protocol.PlayerStatusReconnecting,
+	})
+	s.broadcastToRoom(roomID, statusMsg, "")
+
+	log.Printf("Player %s disconnected, reserved for %v", playerID, grace)
+}
+
+// handleLeave 处理主动离开
+func (s *GameServer) handleLeave(clientID string, msg transport.Message) {
+	s.mu.RLock()
+	playerID, exists := s.clients[clientID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return
+	}
+
+	// 主动离开才删除持久化状态，超时未重连的玩家下次加入仍回到原位置
+	// 先移除玩家再删除，之后的仲裁不会再写入该玩家的记录
+	s.removePlayer(playerID)
+	s.store.Delete(playerID)
+}
+
+// removePlayer 移除玩家并通知房间内其他玩家
+func (s *GameServer) removePlayer(playerID string) {
+	s.mu.Lock()
+	player, exists := s.players[playerID]
+	if !exists {
+		s.mu.Unlock()
+		return
+	}
+
+	delete(s.players, playerID)
+	if s.clients[player.ClientID] == playerID {
+		delete(s.clients, player.ClientID)
+	}
+	if room, ok := s.rooms[player.RoomID]; ok {
+		delete(room.players, playerID)
+		if len(room.players) == 0 && room.ID != DefaultRoomID {
+			delete(s.rooms, room.ID)
+		}
+	}
+	s.mu.Unlock()
+
+	s.forgetClient(player.ClientID)
+
+	leftMsg := transport.NewMessage(protocol.MsgTypePlayerLeft, protocol.PlayerLeftData{
+		PlayerID: playerID,
+	})
+	s.broadcastToRoom(player.RoomID, leftMsg, "")
+
+	log.Printf("Player %s left room %s", playerID, player.RoomID)
+}
+
+// sessionLoop 会话过期检查循环
+func (s *GameServer) sessionLoop() {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.expireSessions(s.clock.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// expireSessions 移除超过宽限期仍未重连的玩家
+func (s *GameServer) expireSessions(now time.Time) {
+	s.mu.RLock()
+	var expired []string
+	for id, player := range s.players {
+		if player.Status == protocol.PlayerStatusReconnecting &&
+			now.Sub(player.DisconnectedAt) >= s.config.ReconnectGrace {
+			expired = append(expired, id)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, playerID := range expired {
+		s.removePlayer(playerID)
+	}
+}
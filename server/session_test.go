@@ -0,0 +1,118 @@
+package server
+
+import (
+	"syncServerDemo/protocol"
+	"syncServerDemo/transport"
+	"testing"
+)
+
+// queueJoin 注册客户端并加入匹配队列
+func queueJoin(s *GameServer, lt *transport.LocalTransport, clientID, playerID string) {
+	lt.Register(clientID)
+	s.HandleMessage(clientID, transport.NewMessage(protocol.MsgTypeQueueJoin, protocol.QueueJoinData{PlayerID: playerID}))
+}
+
+func TestQueueLeaveRequiresOwner(t *testing.T) {
+	s, lt := newTestServer(t)
+	queueJoin(s, lt, "c1", "alice")
+	lt.Register("c2")
+
+	// 其他连接不能把 alice 移出队列，同一连接也不能再以其他玩家排队
+	s.HandleMessage("c2", transport.NewMessage(protocol.MsgTypeQueueLeave, protocol.QueueLeaveData{PlayerID: "alice"}))
+	s.HandleMessage("c1", transport.NewMessage(protocol.MsgTypeQueueJoin, protocol.QueueJoinData{PlayerID: "bob"}))
+	if n := s.Matchmaker().QueueLength(); n != 1 {
+		t.Fatalf("queue length = %d, want 1", n)
+	}
+
+	s.HandleMessage("c1", transport.NewMessage(protocol.MsgTypeQueueLeave, protocol.QueueLeaveData{PlayerID: "alice"}))
+	if n := s.Matchmaker().QueueLength(); n != 0 {
+		t.Errorf("queue length = %d after alice left, want 0", n)
+	}
+}
+
+func TestDisconnectLeavesQueue(t *testing.T) {
+	s, lt := newTestServer(t)
+	queueJoin(s, lt, "c1", "alice")
+	queueJoin(s, lt, "c2", "bob")
+
+	// 只排队、尚未加入的客户端断线后不再参与匹配
+	s.Disconnect("c1")
+	if playerID, ok := s.Matchmaker().QueuedPlayer("c1"); ok {
+		t.Errorf("disconnected client still queued as %s", playerID)
+	}
+	if n := s.Matchmaker().QueueLength(); n != 1 {
+		t.Errorf("queue length = %d, want 1", n)
+	}
+}
+
+func TestJoinRejectsSecondPlayerOnClient(t *testing.T) {
+	s, lt := newTestServer(t)
+	join(s, lt, "alice", "bob")
+	welcome := nextMessage(t, lt, "bob", protocol.MsgTypeWelcome).GetData().(protocol.WelcomeData)
+
+	// alice 的连接既不能加入新玩家，也不能凭令牌接管 bob
+	s.HandleMessage("alice", transport.NewMessage(protocol.MsgTypeJoin, protocol.JoinData{PlayerID: "mallory"}))
+	s.HandleMessage("alice", transport.NewMessage(protocol.MsgTypeJoin, protocol.JoinData{
+		PlayerID:     "bob",
+		SessionToken: welcome.SessionToken,
+	}))
+
+	s.mu.RLock()
+	players, bob := len(s.players), *s.players["bob"]
+	s.mu.RUnlock()
+	if players != 2 || bob.ClientID != "bob" {
+		t.Fatalf("%d players, bob on client %s; want 2 players and bob on client bob", players, bob.ClientID)
+	}
+
+	// 断线仍然作用于 alice 原来的玩家
+	s.Disconnect("alice")
+	s.mu.RLock()
+	status := s.players["alice"].Status
+	s.mu.RUnlock()
+	if status != protocol.PlayerStatusReconnecting {
+		t.Errorf("alice status = %s after disconnect, want %s", status, protocol.PlayerStatusReconnecting)
+	}
+}
+
+func TestResumeIntoMatchedRoom(t *testing.T) {
+	s, lt := newTestServer(t)
+
+	// 匹配房间由快照恢复，alice 已被分配到该房间
+	err := s.RestoreSnapshot(&WorldSnapshot{
+		Version: SnapshotVersion,
+		RoomSeq: 1,
+		Rooms:   []RoomSnapshot{{ID: "room-1", Expected: []string{"alice"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	join(s, lt, "alice", "carol")
+	welcome := nextMessage(t, lt, "alice", protocol.MsgTypeWelcome).GetData().(protocol.WelcomeData)
+
+	// 已在房间中的客户端不能排队
+	s.HandleMessage("alice", transport.NewMessage(protocol.MsgTypeQueueJoin, protocol.QueueJoinData{PlayerID: "alice"}))
+	if n := s.Matchmaker().QueueLength(); n != 0 {
+		t.Errorf("queue length = %d, want 0 for a seated client", n)
+	}
+
+	s.HandleMessage("alice", transport.NewMessage(protocol.MsgTypeJoin, protocol.JoinData{
+		PlayerID:     "alice",
+		RoomID:       "room-1",
+		SessionToken: welcome.SessionToken,
+	}))
+
+	moved := nextMessage(t, lt, "alice", protocol.MsgTypeWelcome).GetData().(protocol.WelcomeData)
+	if moved.RoomID != "room-1" || !moved.Resumed {
+		t.Errorf("welcome room = %q, resumed = %v, want room-1 resumed", moved.RoomID, moved.Resumed)
+	}
+	left := nextMessage(t, lt, "carol", protocol.MsgTypePlayerLeft).GetData().(protocol.PlayerLeftData)
+	if left.PlayerID != "alice" {
+		t.Errorf("carol saw %s leave, want alice", left.PlayerID)
+	}
+	for _, p := range s.Snapshot().Players {
+		if p.PlayerID == "alice" && p.RoomID != "room-1" {
+			t.Errorf("alice is in room %s, want room-1", p.RoomID)
+		}
+	}
+}
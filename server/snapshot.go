@@ -0,0 +1,226 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"syncServerDemo/protocol"
+	"time"
+)
+
+// SnapshotVersion 当前快照文件格式版本
+const SnapshotVersion = 1
+
+// WorldSnapshot 服务器世界状态快照
+type WorldSnapshot struct {
+	Version  int              `json:"version"`
+	SavedAt  time.Time        `json:"saved_at"`
+	GameTime int64            `json:"game_time"`
+	RoomSeq  int              `json:"room_seq"`
+	Rooms    []RoomSnapshot   `json:"rooms"`
+	Players  []PlayerSnapshot `json:"players"`
+}
+
+// RoomSnapshot 房间快照
+type RoomSnapshot struct {
+	ID        string    `json:"id"`
+	Region    string    `json:"region,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Players   []string  `json:"players"`            // 快照时房间内的玩家
+	Expected  []string  `json:"expected,omitempty"` // 匹配分配到房间的玩家
+}
+
+// PlayerSnapshot 玩家快照
+type PlayerSnapshot struct {
+	PlayerID string  `json:"player_id"`
+	RoomID   string  `json:"room_id"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	LastSync int64   `json:"last_sync"`
+	VectorX  float64 `json:"vector_x"`
+	VectorY  float64 `json:"vector_y"`
+	MoveTime int64   `json:"move_time"`
+
+	Movement protocol.MovementParams `json:"movement"` // 移动属性，旧版本快照中为空时使用默认属性
+}
+
+// Snapshot 生成当前世界状态快照
+func (s *GameServer) Snapshot() *WorldSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap := &WorldSnapshot{
+		Version:  SnapshotVersion,
+		SavedAt:  time.Now(),
+		GameTime: s.timeSyncer.GetGameTime(),
+		RoomSeq:  s.roomSeq,
+	}
+
+	for _, room := range s.rooms {
+		roomSnap := RoomSnapshot{
+			ID:        room.ID,
+			Region:    room.Region,
+			CreatedAt: room.CreatedAt,
+			Players:   room.PlayerIDs(),
+		}
+		for playerID := range room.expected {
+			roomSnap.Expected = append(roomSnap.Expected, playerID)
+		}
+		snap.Rooms = append(snap.Rooms, roomSnap)
+	}
+
+	// 尚未回归的玩家也要保留，避免连续重启丢失
+	for _, players := range []map[string]*PlayerState{s.players, s.returning} {
+		for _, p := range players {
+			snap.Players = append(snap.Players, PlayerSnapshot{
+				PlayerID: p.PlayerID,
+				RoomID:   p.RoomID,
+				X:        p.X,
+				Y:        p.Y,
+				LastSync: p.LastSync,
+				VectorX:  p.VectorX,
+				VectorY:  p.VectorY,
+				MoveTime: p.MoveTime,
+				Movement: p.Movement,
+			})
+		}
+	}
+
+	return snap
+}
+
+// SaveSnapshot 将当前世界状态原子写入文件
+// 先写入同目录下的临时文件再重命名，避免崩溃时留下不完整的快照
+func (s *GameServer) SaveSnapshot(path string) error {
+	return WriteSnapshot(path, s.Snapshot())
+}
+
+// WriteSnapshot 原子写入快照文件
+func WriteSnapshot(path string, snap *WorldSnapshot) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	encoder := json.NewEncoder(tmp)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snap); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// LoadSnapshot 从文件读取快照
+func LoadSnapshot(path string) (*WorldSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snap WorldSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	if snap.Version < 1 || snap.Version > SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	return &snap, nil
+}
+
+// RestoreSnapshot 从快照恢复世界状态，需在 Start 之前调用
+// 游戏时间从快照时间继续，快照中的玩家在重新加入时回到最后仲裁的位置
+func (s *GameServer) RestoreSnapshot(snap *WorldSnapshot) error {
+	if snap.Version < 1 || snap.Version > SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.players) > 0 {
+		return fmt.Errorf("cannot restore snapshot with %d players online", len(s.players))
+	}
+
+	s.timeSyncer.SetGameTime(snap.GameTime)
+	s.roomSeq = snap.RoomSeq
+
+	for _, roomSnap := range snap.Rooms {
+		if roomSnap.ID == DefaultRoomID {
+			continue
+		}
+		// 快照时在房间内的玩家仍然允许回到该房间
+		expected := append(append([]string{}, roomSnap.Expected...), roomSnap.Players...)
+		room := newRoom(roomSnap.ID, roomSnap.Region, expected)
+		room.CreatedAt = roomSnap.CreatedAt
+		s.rooms[room.ID] = room
+	}
+
+	// 回归的玩家从最后仲裁的位置静止开始，不延续重启前的移动指令
+	s.returning = make(map[string]*PlayerState, len(snap.Players))
+	for _, p := range snap.Players {
+		s.returning[p.PlayerID] = &PlayerState{
+			PlayerID: p.PlayerID,
+			RoomID:   p.RoomID,
+			X:        p.X,
+			Y:        p.Y,
+			LastSync: p.LastSync,
+			MoveTime: p.LastSync,
+			Movement: p.Movement,
+		}
+	}
+
+	log.Printf("Restored snapshot from %s: game time %d, %d rooms, %d players",
+		snap.SavedAt.Format(time.RFC3339), snap.GameTime, len(snap.Rooms), len(snap.Players))
+	return nil
+}
+
+// EnableSnapshots 开启定期快照，需在 Start 之前调用
+// 服务器停止时也会写入最后一次快照
+func (s *GameServer) EnableSnapshots(path string, interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshotPath = path
+	s.snapshotInterval = interval
+}
+
+// snapshotLoop 定期快照循环
+func (s *GameServer) snapshotLoop(path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.SaveSnapshot(path); err != nil {
+				log.Printf("Error saving snapshot: %v", err)
+			}
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// takeReturningPlayer 取出快照中恢复的玩家状态（调用方需持有 s.mu 写锁）
+func (s *GameServer) takeReturningPlayer(playerID string) (*PlayerState, bool) {
+	player, exists := s.returning[playerID]
+	if exists {
+		delete(s.returning, playerID)
+	}
+	return player, exists
+}
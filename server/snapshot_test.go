@@ -0,0 +1,101 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"syncServerDemo/protocol"
+	"testing"
+)
+
+// snapshotPlayers 快照中的玩家位置，按玩家ID索引
+func snapshotPlayers(snap *WorldSnapshot) map[string][2]float64 {
+	players := make(map[string][2]float64, len(snap.Players))
+	for _, p := range snap.Players {
+		players[p.PlayerID] = [2]float64{p.X, p.Y}
+	}
+	return players
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	s, lt := newTestServer(t)
+	s.SetGameTime(5000)
+	join(s, lt, "alice", "bob")
+	positions := map[string][2]float64{"alice": {3, 4}, "bob": {7, 8}}
+	reportAll(s, positions)
+	s.ArbitrateNow()
+
+	// 固定时间戳，去掉单调时钟读数以便比较
+	snap := s.Snapshot()
+	snap.SavedAt = testEpoch
+	for i := range snap.Rooms {
+		snap.Rooms[i].CreatedAt = testEpoch
+	}
+	sort.Slice(snap.Players, func(i, j int) bool { return snap.Players[i].PlayerID < snap.Players[j].PlayerID })
+	path := filepath.Join(t.TempDir(), "world.json")
+	if err := WriteSnapshot(path, snap); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, snap) {
+		t.Fatalf("loaded snapshot =\n%+v\nwant\n%+v", loaded, snap)
+	}
+
+	restored, lt2 := newTestServer(t)
+	if err := restored.RestoreSnapshot(loaded); err != nil {
+		t.Fatal(err)
+	}
+	if gameTime := restored.GetGameTime(); gameTime != 5000 {
+		t.Errorf("restored game time = %d, want 5000", gameTime)
+	}
+	// 尚未回来的玩家仍然保留在下一次快照中
+	if got := snapshotPlayers(restored.Snapshot()); !reflect.DeepEqual(got, positions) {
+		t.Errorf("restored players = %v, want %v", got, positions)
+	}
+
+	// 回来的玩家从最后仲裁的位置开始
+	join(restored, lt2, "alice")
+	welcome := nextMessage(t, lt2, "alice", protocol.MsgTypeWelcome).GetData().(protocol.WelcomeData)
+	if len(welcome.Positions) != 1 || welcome.Positions[0].X != 3 || welcome.Positions[0].Y != 4 {
+		t.Errorf("welcome positions = %+v, want alice at (3, 4)", welcome.Positions)
+	}
+}
+
+func TestSnapshotVersionRejected(t *testing.T) {
+	dir := t.TempDir()
+	for _, version := range []int{0, SnapshotVersion + 1} {
+		path := filepath.Join(dir, "world.json")
+		if err := WriteSnapshot(path, &WorldSnapshot{Version: version}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSnapshot(path); err == nil || !strings.Contains(err.Error(), "unsupported snapshot version") {
+			t.Errorf("LoadSnapshot(version %d) error = %v", version, err)
+		}
+
+		s, _ := newTestServer(t)
+		if err := s.RestoreSnapshot(&WorldSnapshot{Version: version}); err == nil {
+			t.Errorf("RestoreSnapshot accepted version %d", version)
+		}
+	}
+
+	path := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "rooms": [`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnapshot(path); err == nil || !strings.Contains(err.Error(), "invalid snapshot") {
+		t.Errorf("LoadSnapshot(corrupt) error = %v", err)
+	}
+}
+
+func TestRestoreSnapshotRequiresEmptyServer(t *testing.T) {
+	s, lt := newTestServer(t)
+	join(s, lt, "alice")
+	if err := s.RestoreSnapshot(&WorldSnapshot{Version: SnapshotVersion}); err == nil {
+		t.Error("RestoreSnapshot succeeded with a player online")
+	}
+}
@@ -0,0 +1,442 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// PlayerRecord 持久化的玩家状态
+type PlayerRecord struct {
+	PlayerID string  `json:"player_id"`
+	RoomID   string  `json:"room_id"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	LastSync int64   `json:"last_sync"`
+}
+
+// PlayerStore 玩家状态存储接口
+// 加入时加载，仲裁后保存，离开时删除
+type PlayerStore interface {
+	// Load 加载玩家状态，不存在时返回 false
+	Load(playerID string) (PlayerRecord, bool, error)
+
+	// Save 保存玩家状态
+	Save(record PlayerRecord) error
+
+	// Delete 删除玩家状态
+	Delete(playerID string) error
+
+	// Close 关闭存储
+	Close() error
+}
+
+// MemoryPlayerStore 内存存储
+type MemoryPlayerStore struct {
+	records map[string]PlayerRecord
+	mu      sync.RWMutex
+}
+
+// NewMemoryPlayerStore 创建内存存储
+func NewMemoryPlayerStore() *MemoryPlayerStore {
+	return &MemoryPlayerStore{
+		records: make(map[string]PlayerRecord),
+	}
+}
+
+func (m *MemoryPlayerStore) Load(playerID string) (PlayerRecord, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	record, exists := m.records[playerID]
+	return record, exists, nil
+}
+
+func (m *MemoryPlayerStore) Save(record PlayerRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records[record.PlayerID] = record
+	return nil
+}
+
+func (m *MemoryPlayerStore) Delete(playerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, playerID)
+	return nil
+}
+
+func (m *MemoryPlayerStore) Close() error {
+	return nil
+}
+
+// fileStoreEntry 追加日志中的一条记录
+type fileStoreEntry struct {
+	Op     string        `json:"op"` // save 或 delete
+	Record *PlayerRecord `json:"record,omitempty"`
+	ID     string        `json:"player_id,omitempty"`
+}
+
+// FilePlayerStore 追加写文件存储
+// 每次写操作追加一行 JSON，启动时重放日志重建状态；
+// 日志中的过期记录超过阈值后自动压缩
+type FilePlayerStore struct {
+	path    string
+	file    *os.File
+	writer  *bufio.Writer
+	records map[string]PlayerRecord
+	entries int // 日志中的记录条数
+	mu      sync.Mutex
+
+	compactRatio int // 日志条数超过存活记录数的倍数时压缩
+}
+
+// NewFilePlayerStore 打开或创建文件存储
+func NewFilePlayerStore(path string) (*FilePlayerStore, error) {
+	fs := &FilePlayerStore{
+		path:         path,
+		records:      make(map[string]PlayerRecord),
+		compactRatio: 4,
+	}
+
+	if err := fs.replay(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	fs.file = file
+	fs.writer = bufio.NewWriter(file)
+	return fs, nil
+}
+
+// replay 重放日志
+func (fs *FilePlayerStore) replay() error {
+	file, err := os.Open(fs.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	var offset, valid int64 // valid 为最后一条完整记录之后的位置
+	for scanner.Scan() {
+		line++
+		offset += int64(len(scanner.Bytes())) + 1
+		var entry fileStoreEntry
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err == nil && offset > info.Size() {
+			err = io.ErrUnexpectedEOF // 没有写完换行符
+		}
+		if err != nil {
+			// 崩溃可能留下写了一半的最后一行，跳过即可
+			log.Printf("Skipping corrupt player store entry at %s:%d: %v", fs.path, line, err)
+			continue
+		}
+		fs.apply(entry)
+		fs.entries++
+		valid = offset
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// 截掉末尾不完整的记录，否则之后追加的记录会接在它后面一起损坏
+	if valid < info.Size() {
+		log.Printf("Truncating torn tail of player store %s at %d bytes", fs.path, valid)
+		return os.Truncate(fs.path, valid)
+	}
+	return nil
+}
+
+// apply 应用一条日志记录
+func (fs *FilePlayerStore) apply(entry fileStoreEntry) {
+	switch entry.Op {
+	case "save":
+		if entry.Record != nil {
+			fs.records[entry.Record.PlayerID] = *entry.Record
+		}
+	case "delete":
+		delete(fs.records, entry.ID)
+	}
+}
+
+// append 追加一条日志记录（调用方需持有 fs.mu）
+func (fs *FilePlayerStore) append(entry fileStoreEntry) error {
+	if fs.file == nil {
+		return fmt.Errorf("player store is closed")
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := fs.writer.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := fs.writer.Flush(); err != nil {
+		return err
+	}
+
+	fs.apply(entry)
+	fs.entries++
+
+	if fs.entries > 64 && fs.entries > fs.compactRatio*len(fs.records) {
+		return fs.compact()
+	}
+	return nil
+}
+
+func (fs *FilePlayerStore) Load(playerID string) (PlayerRecord, bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	record, exists := fs.records[playerID]
+	return record, exists, nil
+}
+
+func (fs *FilePlayerStore) Save(record PlayerRecord) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.append(fileStoreEntry{Op: "save", Record: &record})
+}
+
+func (fs *FilePlayerStore) Delete(playerID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, exists := fs.records[playerID]; !exists {
+		return nil
+	}
+	return fs.append(fileStoreEntry{Op: "delete", ID: playerID})
+}
+
+// Compact 压缩日志，只保留每个玩家的最新状态
+func (fs *FilePlayerStore) Compact() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.compact()
+}
+
+// compact 将存活记录写入临时文件后原子替换日志（调用方需持有 fs.mu）
+func (fs *FilePlayerStore) compact() error {
+	if fs.file == nil {
+		return fmt.Errorf("player store is closed")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fs.path), filepath.Base(fs.path)+".compact-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	writer := bufio.NewWriter(tmp)
+	for _, record := range fs.records {
+		data, err := json.Marshal(fileStoreEntry{Op: "save", Record: &record})
+		if err == nil {
+			_, err = writer.Write(append(data, '\n'))
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, fs.path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// 重新打开新的日志文件继续追加
+	fs.file.Close()
+	file, err := os.OpenFile(fs.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		fs.file = nil
+		return err
+	}
+	fs.file = file
+	fs.writer = bufio.NewWriter(file)
+	fs.entries = len(fs.records)
+	return nil
+}
+
+func (fs *FilePlayerStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.file == nil {
+		return nil
+	}
+	err := fs.file.Close()
+	fs.file = nil
+	return err
+}
+
+// storeOp 异步存储操作
+type storeOp struct {
+	record  PlayerRecord
+	deleted bool
+}
+
+// asyncPlayerStore 异步写入包装
+// 写操作先进入待写集合并立即返回，同一玩家的多次写入只保留最新一次，
+// 后台协程负责写入底层存储，慢存储不会阻塞仲裁
+type asyncPlayerStore struct {
+	backend  PlayerStore
+	pending  map[string]storeOp
+	order    []string
+	inflight map[string]storeOp // 正在写入底层存储的操作
+	closed   bool
+	mu       sync.Mutex
+	wake     chan struct{}
+	done     chan struct{}
+}
+
+// newAsyncPlayerStore 创建异步写入包装
+func newAsyncPlayerStore(backend PlayerStore) *asyncPlayerStore {
+	as := &asyncPlayerStore{
+		backend: backend,
+		pending: make(map[string]storeOp),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go as.writeLoop()
+	return as
+}
+
+// Load 优先返回尚未写入的最新状态
+func (as *asyncPlayerStore) Load(playerID string) (PlayerRecord, bool, error) {
+	as.mu.Lock()
+	op, pending := as.pending[playerID]
+	if !pending {
+		op, pending = as.inflight[playerID]
+	}
+	as.mu.Unlock()
+
+	if pending {
+		return op.record, !op.deleted, nil
+	}
+	return as.backend.Load(playerID)
+}
+
+func (as *asyncPlayerStore) Save(record PlayerRecord) error {
+	as.enqueue(record.PlayerID, storeOp{record: record})
+	return nil
+}
+
+func (as *asyncPlayerStore) Delete(playerID string) error {
+	as.enqueue(playerID, storeOp{deleted: true})
+	return nil
+}
+
+// enqueue 加入待写集合并唤醒写协程
+func (as *asyncPlayerStore) enqueue(playerID string, op storeOp) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.closed {
+		log.Printf("Dropping player store write for %s: store closed", playerID)
+		return
+	}
+
+	if _, exists := as.pending[playerID]; !exists {
+		as.order = append(as.order, playerID)
+	}
+	as.pending[playerID] = op
+
+	select {
+	case as.wake <- struct{}{}:
+	default:
+	}
+}
+
+// writeLoop 后台写入循环
+func (as *asyncPlayerStore) writeLoop() {
+	for {
+		_, ok := <-as.wake
+		as.flush()
+		if !ok {
+			close(as.done)
+			return
+		}
+	}
+}
+
+// flush 写入所有待写操作
+func (as *asyncPlayerStore) flush() {
+	as.mu.Lock()
+	pending := as.pending
+	order := as.order
+	as.pending = make(map[string]storeOp)
+	as.order = nil
+	as.inflight = pending
+	as.mu.Unlock()
+
+	for _, playerID := range order {
+		op := pending[playerID]
+		var err error
+		if op.deleted {
+			err = as.backend.Delete(playerID)
+		} else {
+			err = as.backend.Save(op.record)
+		}
+		if err != nil {
+			log.Printf("Error writing player store for %s: %v", playerID, err)
+		}
+	}
+
+	as.mu.Lock()
+	as.inflight = nil
+	as.mu.Unlock()
+}
+
+// Close 写完剩余操作后关闭底层存储
+func (as *asyncPlayerStore) Close() error {
+	as.mu.Lock()
+	if as.closed {
+		as.mu.Unlock()
+		return nil
+	}
+	as.closed = true
+	close(as.wake)
+	as.mu.Unlock()
+
+	<-as.done
+	return as.backend.Close()
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openFileStore 打开文件存储，测试结束时关闭
+func openFileStore(t *testing.T, path string) *FilePlayerStore {
+	t.Helper()
+	fs, err := NewFilePlayerStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fs.Close() })
+	return fs
+}
+
+// expectRecord 检查存储中玩家的状态，want 为 nil 表示不存在
+func expectRecord(t *testing.T, store PlayerStore, playerID string, want *PlayerRecord) {
+	t.Helper()
+	record, exists, err := store.Load(playerID)
+	switch {
+	case err != nil:
+		t.Errorf("Load(%s): %v", playerID, err)
+	case want == nil && exists:
+		t.Errorf("Load(%s) = %+v, want no record", playerID, record)
+	case want != nil && (!exists || record != *want):
+		t.Errorf("Load(%s) = %+v, %v; want %+v", playerID, record, exists, *want)
+	}
+}
+
+func TestFilePlayerStoreReplayTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "players.log")
+	fs := openFileStore(t, path)
+	alice := PlayerRecord{PlayerID: "alice", RoomID: "default", X: 1, Y: 2, LastSync: 100}
+	fs.Save(PlayerRecord{PlayerID: "alice", X: 0})
+	fs.Save(PlayerRecord{PlayerID: "bob", X: 5})
+	fs.Delete("bob")
+	fs.Save(alice)
+	fs.Close()
+
+	// 崩溃时最后一行只写了一半
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"op":"save","record":{"player_id":"bob","x":`)
+	file.Close()
+
+	fs = openFileStore(t, path)
+	expectRecord(t, fs, "alice", &alice)
+	expectRecord(t, fs, "bob", nil)
+
+	// 之后的追加不会被截断的记录拖累
+	charlie := PlayerRecord{PlayerID: "charlie", X: 3}
+	if err := fs.Save(charlie); err != nil {
+		t.Fatal(err)
+	}
+	fs.Close()
+
+	fs = openFileStore(t, path)
+	expectRecord(t, fs, "alice", &alice)
+	expectRecord(t, fs, "charlie", &charlie)
+	if fs.entries != 5 {
+		t.Errorf("%d entries replayed, want 5", fs.entries)
+	}
+}
+
+func TestFilePlayerStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "players.log")
+	fs := openFileStore(t, path)
+	fs.Save(PlayerRecord{PlayerID: "charlie"})
+	fs.Delete("charlie")
+	for i := 1; i <= 40; i++ {
+		fs.Save(PlayerRecord{PlayerID: "alice", X: float64(i)})
+		fs.Save(PlayerRecord{PlayerID: "bob", Y: float64(i)})
+	}
+
+	// 第 65 条记录触发压缩，只剩两条存活记录，之后又追加了 17 条
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != 19 || fs.entries != lines {
+		t.Errorf("%d lines, %d entries after compaction; want 19", lines, fs.entries)
+	}
+
+	// 手动压缩只保留最新状态，重放后与压缩前一致
+	if err := fs.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	fs.Close()
+	fs = openFileStore(t, path)
+	if fs.entries != 2 {
+		t.Errorf("%d entries after Compact, want 2", fs.entries)
+	}
+	expectRecord(t, fs, "alice", &PlayerRecord{PlayerID: "alice", X: 40})
+	expectRecord(t, fs, "bob", &PlayerRecord{PlayerID: "bob", Y: 40})
+	expectRecord(t, fs, "charlie", nil)
+}
+
+// gatedStore 每次写入前等待放行的存储，用于观察写入过程中的状态
+type gatedStore struct {
+	*MemoryPlayerStore
+	started chan string
+	release chan struct{}
+	closed  bool
+}
+
+func newGatedStore() *gatedStore {
+	return &gatedStore{
+		MemoryPlayerStore: NewMemoryPlayerStore(),
+		started:           make(chan string, 10),
+		release:           make(chan struct{}),
+	}
+}
+
+func (g *gatedStore) Save(record PlayerRecord) error {
+	g.started <- record.PlayerID
+	<-g.release
+	return g.MemoryPlayerStore.Save(record)
+}
+
+func (g *gatedStore) Delete(playerID string) error {
+	g.started <- playerID
+	<-g.release
+	return g.MemoryPlayerStore.Delete(playerID)
+}
+
+func (g *gatedStore) Close() error {
+	g.closed = true
+	return nil
+}
+
+func TestAsyncPlayerStore(t *testing.T) {
+	backend := newGatedStore()
+	backend.MemoryPlayerStore.Save(PlayerRecord{PlayerID: "bob", X: 9})
+	as := newAsyncPlayerStore(backend)
+
+	// alice 的第一次写入进行中
+	first := PlayerRecord{PlayerID: "alice", X: 1}
+	as.Save(first)
+	select {
+	case <-backend.started:
+	case <-time.After(time.Second):
+		t.Fatal("write did not start")
+	}
+	expectRecord(t, as, "alice", &first)
+
+	// 写入进行中的新操作进入待写集合，读取优先返回最新状态
+	second := PlayerRecord{PlayerID: "alice", X: 2}
+	as.Save(second)
+	as.Delete("bob")
+	expectRecord(t, as, "alice", &second)
+	expectRecord(t, as, "bob", nil)
+	expectRecord(t, backend, "bob", &PlayerRecord{PlayerID: "bob", X: 9})
+
+	// 关闭时写完剩余操作再关闭底层存储
+	close(backend.release)
+	if err := as.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !backend.closed {
+		t.Error("backend not closed")
+	}
+	expectRecord(t, backend, "alice", &second)
+	expectRecord(t, backend, "bob", nil)
+
+	as.Save(PlayerRecord{PlayerID: "charlie"})
+	expectRecord(t, backend, "charlie", nil)
+}
@@ -0,0 +1,326 @@
+// Package sim 在单个进程内确定性地运行服务器和多个客户端。
+//
+// Harness 把 GameServer、多个 GameClient、手动时钟和模拟网络连在一起，所有定时任务
+// （时间同步、位置仲裁、客户端上报）和消息投递都是调度器中的事件，按虚拟时间依次执行，
+// 不启动任何 goroutine，也不依赖真实的 sleep。相同的种子和脚本得到完全相同的消息序列，
+// 可以在 go test 中断言收敛、仲裁结果和消息数量。
+//
+// 心跳和会话过期不在模拟范围内：断线由 Drop 显式触发，保留期内用 Reconnect 恢复。
+package sim
+
+import (
+	"fmt"
+	"sort"
+	"syncServerDemo/client"
+	"syncServerDemo/consistency"
+	"syncServerDemo/gamesync"
+	"syncServerDemo/protocol"
+	"syncServerDemo/server"
+	"syncServerDemo/transport"
+	"time"
+)
+
+// Epoch 模拟开始的时间
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Config 模拟配置
+type Config struct {
+	Seed    int64                       // 网络随机种子
+	Network transport.NetworkConditions // 默认网络条件，零值为无延迟无丢包
+	Server  []server.Option             // 服务器配置项，时钟由模拟器设置
+	Client  []client.Option             // 所有客户端共用的配置项，时钟由模拟器设置
+	Record  bool                        // 录制服务器收发的消息，通过 Recording 获取
+}
+
+// Harness 确定性模拟器
+type Harness struct {
+	clock   *gamesync.ManualClock
+	sched   *scheduler
+	network *Network
+	server  *server.GameServer
+
+	clientOpts []client.Option
+	clients    map[string]*client.GameClient
+	joinOrder  []string
+	online     map[string]bool // 已加入且链路正常的客户端
+	left       map[string]bool // 已主动离开的客户端
+}
+
+// New 创建模拟器，服务器的定时任务从 Epoch 开始调度
+func New(cfg Config) (*Harness, error) {
+	serverCfg := server.DefaultConfig()
+	for _, opt := range cfg.Server {
+		opt(&serverCfg)
+	}
+	if err := serverCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid server config: %w", err)
+	}
+
+	h := &Harness{
+		clock:   gamesync.NewManualClock(Epoch),
+		sched:   newScheduler(),
+		clients: make(map[string]*client.GameClient),
+		online:  make(map[string]bool),
+		left:    make(map[string]bool),
+	}
+	h.network = newNetwork(h.sched, h.clock.Now, cfg.Seed, cfg.Network)
+	h.network.recording = cfg.Record
+
+	serverOpts := append(append([]server.Option(nil), cfg.Server...), server.WithClock(h.clock))
+	h.server = server.NewGameServer(h.network, serverOpts...)
+	h.network.server = h.server
+	h.clientOpts = append(append([]client.Option(nil), cfg.Client...), client.WithClock(h.clock))
+
+	h.every("server/time_sync", func() time.Duration {
+		return h.server.Config().TimeSyncInterval
+	}, h.server.SyncTimeNow)
+	h.every("server/arbitration", func() time.Duration {
+		return h.server.Config().ArbitrationInterval
+	}, h.server.ArbitrateNow)
+
+	return h, nil
+}
+
+// every 从现在起每隔 interval 执行一次 fn，每次重新读取间隔以跟随运行时配置变化
+func (h *Harness) every(key string, interval func() time.Duration, fn func()) {
+	var tick func()
+	tick = func() {
+		fn()
+		h.sched.schedule(h.clock.Now().Add(interval()), key, tick)
+	}
+	h.sched.schedule(h.clock.Now().Add(interval()), key, tick)
+}
+
+// At 安排在模拟开始后 offset 时执行 fn，同一时间的多个动作按安排顺序执行
+func (h *Harness) At(offset time.Duration, fn func()) {
+	h.sched.schedule(Epoch.Add(offset), "script", fn)
+}
+
+// RunFor 推进虚拟时间 d，依次执行期间到期的所有事件
+func (h *Harness) RunFor(d time.Duration) {
+	h.RunUntil(h.Elapsed() + d)
+}
+
+// RunUntil 推进到模拟开始后 offset 时，早于当前时间时什么也不做
+func (h *Harness) RunUntil(offset time.Duration) {
+	deadline := Epoch.Add(offset)
+	for {
+		ev, ok := h.sched.next(deadline)
+		if !ok {
+			break
+		}
+		h.clock.Set(ev.at)
+		ev.fn()
+	}
+	h.clock.Set(deadline)
+}
+
+// Elapsed 模拟开始后经过的虚拟时间
+func (h *Harness) Elapsed() time.Duration {
+	return h.clock.Now().Sub(Epoch)
+}
+
+// Join 创建客户端并加入默认房间，客户端ID与玩家ID相同
+func (h *Harness) Join(playerID string) error {
+	if _, exists := h.clients[playerID]; exists {
+		return fmt.Errorf("player %s already joined", playerID)
+	}
+
+	c := client.NewGameClient(playerID, playerID, h.network, h.clientOpts...)
+	h.network.Register(playerID)
+	h.network.attach(playerID, c)
+	if err := c.Attach(); err != nil {
+		h.network.Unregister(playerID)
+		return err
+	}
+
+	h.clients[playerID] = c
+	h.joinOrder = append(h.joinOrder, playerID)
+	h.online[playerID] = true
+
+	// 上报间隔以服务器下发的同步参数为准，加入前使用客户端配置
+	h.every("client/"+playerID+"/report", func() time.Duration {
+		if params := c.GetSyncParams(); params.ReportInterval > 0 {
+			return time.Duration(params.ReportInterval) * time.Millisecond
+		}
+		return c.Config().ReportInterval
+	}, func() {
+		if !h.left[playerID] {
+			c.ReportNow()
+		}
+	})
+	return nil
+}
+
+// Move 玩家发起移动
+func (h *Harness) Move(playerID string, vectorX, vectorY float64) error {
+	c, err := h.client(playerID)
+	if err != nil {
+		return err
+	}
+	c.Move(vectorX, vectorY)
+	return nil
+}
+
+// Leave 玩家主动离开，之后不再上报位置
+func (h *Harness) Leave(playerID string) error {
+	c, err := h.client(playerID)
+	if err != nil {
+		return err
+	}
+	if err := c.Leave(); err != nil {
+		return err
+	}
+	h.left[playerID] = true
+	h.online[playerID] = false
+	return nil
+}
+
+// Drop 断开玩家的链路，服务器立即感知断线，在途消息全部丢失
+func (h *Harness) Drop(playerID string) error {
+	if _, err := h.client(playerID); err != nil {
+		return err
+	}
+	h.network.setOffline(playerID, true)
+	h.online[playerID] = false
+	h.server.Disconnect(playerID)
+	return nil
+}
+
+// Reconnect 恢复玩家的链路并凭会话令牌重连
+func (h *Harness) Reconnect(playerID string) error {
+	c, err := h.client(playerID)
+	if err != nil {
+		return err
+	}
+	h.network.setOffline(playerID, false)
+	if err := c.Reconnect(); err != nil {
+		return err
+	}
+	h.online[playerID] = true
+	return nil
+}
+
+// Cheat 让玩家上报的所有位置都偏移 (dx, dy)，dx 和 dy 都为 0 时恢复诚实上报
+func (h *Harness) Cheat(playerID string, dx, dy float64) error {
+	if _, err := h.client(playerID); err != nil {
+		return err
+	}
+	if dx == 0 && dy == 0 {
+		h.network.setTamper(playerID, nil)
+		return nil
+	}
+	h.network.setTamper(playerID, func(msg transport.Message) transport.Message {
+		syncData, ok := msg.GetData().(protocol.PositionSyncData)
+		if !ok {
+			return msg
+		}
+		positions := make([]protocol.PositionData, len(syncData.Positions))
+		for i, pos := range syncData.Positions {
+			pos.X += dx
+			pos.Y += dy
+			positions[i] = pos
+		}
+		syncData.Positions = positions
+		return transport.NewMessage(msg.GetType(), syncData)
+	})
+	return nil
+}
+
+// SetConditions 设置单个玩家的网络条件
+func (h *Harness) SetConditions(playerID string, cond transport.NetworkConditions) {
+	h.network.SetClientConditions(playerID, cond)
+}
+
+// client 查找客户端
+func (h *Harness) client(playerID string) (*client.GameClient, error) {
+	c, exists := h.clients[playerID]
+	if !exists {
+		return nil, fmt.Errorf("unknown player %s", playerID)
+	}
+	return c, nil
+}
+
+// Client 获取玩家的客户端，未加入时返回 nil
+func (h *Harness) Client(playerID string) *client.GameClient {
+	return h.clients[playerID]
+}
+
+// Clients 获取在线的客户端，按加入顺序
+func (h *Harness) Clients() []*client.GameClient {
+	playerIDs := h.OnlinePlayers()
+	clients := make([]*client.GameClient, len(playerIDs))
+	for i, playerID := range playerIDs {
+		clients[i] = h.clients[playerID]
+	}
+	return clients
+}
+
+// OnlinePlayers 获取在线的玩家，按加入顺序
+func (h *Harness) OnlinePlayers() []string {
+	playerIDs := make([]string, 0, len(h.joinOrder))
+	for _, playerID := range h.joinOrder {
+		if h.online[playerID] {
+			playerIDs = append(playerIDs, playerID)
+		}
+	}
+	return playerIDs
+}
+
+// Server 获取服务器
+func (h *Harness) Server() *server.GameServer {
+	return h.server
+}
+
+// Network 获取模拟网络
+func (h *Harness) Network() *Network {
+	return h.network
+}
+
+// Recording 获取录制的服务器收发消息，需要在配置中开启 Record
+func (h *Harness) Recording() []transport.RecordEntry {
+	return h.network.Recording()
+}
+
+// GameTime 服务器当前的游戏时间
+func (h *Harness) GameTime() int64 {
+	return h.server.GetGameTime()
+}
+
+// Consistency 在服务器当前游戏时间对所有在线客户端采样一次
+func (h *Harness) Consistency() consistency.Sample {
+	return consistency.Measure(consistency.Clients(h.Clients()), h.GameTime())
+}
+
+// ServerPosition 服务器记录的玩家位置和对应的游戏时间
+func (h *Harness) ServerPosition(playerID string) (x, y float64, gameTime int64, ok bool) {
+	for _, p := range h.server.Snapshot().Players {
+		if p.PlayerID == playerID {
+			return p.X, p.Y, p.LastSync, true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// LastArbitration 服务器最后一次发出的该玩家仲裁结果
+func (h *Harness) LastArbitration(playerID string) (protocol.PositionUpdateData, bool) {
+	arbitrations := h.network.Arbitrations()
+	for i := len(arbitrations) - 1; i >= 0; i-- {
+		if arbitrations[i].PlayerID == playerID {
+			return arbitrations[i], true
+		}
+	}
+	return protocol.PositionUpdateData{}, false
+}
+
+// Players 已加入过的玩家，按玩家ID排序
+func (h *Harness) Players() []string {
+	playerIDs := append([]string(nil), h.joinOrder...)
+	sort.Strings(playerIDs)
+	return playerIDs
+}
+
+// Close 关闭模拟网络
+func (h *Harness) Close() {
+	h.network.Close()
+}
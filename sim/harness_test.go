@@ -0,0 +1,355 @@
+package sim
+
+import (
+	"io"
+	"log"
+	"math"
+	"os"
+	"reflect"
+	"syncServerDemo/consistency"
+	"syncServerDemo/protocol"
+	"syncServerDemo/server"
+	"syncServerDemo/transport"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// 服务器和客户端的日志在模拟中没有意义，失败信息由断言给出
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// instantMovement 速度立即生效的移动模型，便于计算期望位置
+var instantMovement = protocol.MovementParams{
+	Model:    protocol.MovementModelInstant,
+	MaxSpeed: 10,
+}
+
+func newHarness(t *testing.T, cfg Config) *Harness {
+	t.Helper()
+	h, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(h.Close)
+	return h
+}
+
+func mustJoin(t *testing.T, h *Harness, playerIDs ...string) {
+	t.Helper()
+	for _, playerID := range playerIDs {
+		if err := h.Join(playerID); err != nil {
+			t.Fatalf("Join %s: %v", playerID, err)
+		}
+	}
+}
+
+func TestConvergence(t *testing.T) {
+	h := newHarness(t, Config{
+		Server: []server.Option{server.WithMovement(instantMovement)},
+	})
+	mustJoin(t, h, "alice", "bob", "charlie")
+
+	h.At(1*time.Second, func() { h.Move("alice", 1, 0) })
+	h.At(2*time.Second, func() { h.Move("bob", 0, 1) })
+	h.At(3*time.Second, func() { h.Move("alice", 0, 0) })
+	h.At(4*time.Second, func() { h.Move("bob", 0, 0) })
+	h.RunUntil(6 * time.Second)
+
+	consistency.AssertConsistent(t, consistency.Clients(h.Clients()), h.GameTime(), 1e-6)
+
+	want := map[string][2]float64{
+		"alice":   {20, 0},
+		"bob":     {0, 20},
+		"charlie": {0, 0},
+	}
+	for playerID, pos := range want {
+		x, y, _, ok := h.ServerPosition(playerID)
+		if !ok {
+			t.Fatalf("server has no position for %s", playerID)
+		}
+		if math.Hypot(x-pos[0], y-pos[1]) > 1e-6 {
+			t.Errorf("server position of %s = (%.4f, %.4f), want (%.1f, %.1f)", playerID, x, y, pos[0], pos[1])
+		}
+		for _, c := range h.Clients() {
+			cx, cy, ok := c.GetPlayerPosition(playerID)
+			if !ok || math.Hypot(cx-pos[0], cy-pos[1]) > 1e-6 {
+				t.Errorf("client %s sees %s at (%.4f, %.4f, %v), want (%.1f, %.1f)",
+					c.GetRoomID(), playerID, cx, cy, ok, pos[0], pos[1])
+			}
+		}
+	}
+}
+
+// lossyScenario 有延迟、抖动、丢包和乱序的场景，返回运行后的模拟器
+// 加入阶段的消息没有重传，丢失后无法恢复，因此在所有玩家加入后才开始丢包
+func lossyScenario(t *testing.T, seed int64) *Harness {
+	jittery := transport.LinkConditions{
+		Latency: 30 * time.Millisecond,
+		Jitter:  20 * time.Millisecond,
+	}
+	h := newHarness(t, Config{
+		Seed:    seed,
+		Network: transport.Symmetric(jittery),
+	})
+	players := []string{"alice", "bob", "charlie", "dave"}
+	mustJoin(t, h, players...)
+
+	lossy := jittery
+	lossy.LossRate = 0.05
+	lossy.ReorderRate = 0.05
+	h.At(300*time.Millisecond, func() {
+		for _, playerID := range players {
+			h.SetConditions(playerID, transport.Symmetric(lossy))
+		}
+	})
+
+	h.At(500*time.Millisecond, func() { h.Move("alice", 1, 0) })
+	h.At(1200*time.Millisecond, func() { h.Move("bob", 0, -1) })
+	h.At(1700*time.Millisecond, func() { h.Move("charlie", 0.6, 0.8) })
+	h.At(2500*time.Millisecond, func() { h.Move("alice", 0, 0) })
+	h.At(3000*time.Millisecond, func() { h.Move("bob", 0, 0) })
+	h.At(3500*time.Millisecond, func() { h.Move("charlie", 0, 0) })
+	h.RunUntil(8 * time.Second)
+	return h
+}
+
+func TestDeterminism(t *testing.T) {
+	first := lossyScenario(t, 42)
+	second := lossyScenario(t, 42)
+
+	if !reflect.DeepEqual(first.Network().AllStats(), second.Network().AllStats()) {
+		t.Errorf("message stats differ between runs with the same seed:\n%v\n%v",
+			first.Network().AllStats(), second.Network().AllStats())
+	}
+	if !reflect.DeepEqual(first.Network().Arbitrations(), second.Network().Arbitrations()) {
+		t.Errorf("arbitration results differ between runs with the same seed")
+	}
+	for _, playerID := range first.Players() {
+		x1, y1, _, _ := first.ServerPosition(playerID)
+		x2, y2, _, _ := second.ServerPosition(playerID)
+		if x1 != x2 || y1 != y2 {
+			t.Errorf("server position of %s differs: (%v, %v) vs (%v, %v)", playerID, x1, y1, x2, y2)
+		}
+	}
+
+	// 换一个种子，丢包位置不同
+	other := lossyScenario(t, 7)
+	if reflect.DeepEqual(first.Network().AllStats(), other.Network().AllStats()) {
+		t.Errorf("different seeds produced identical message stats")
+	}
+}
+
+func TestLossyNetworkConverges(t *testing.T) {
+	h := lossyScenario(t, 42)
+
+	if dropped := h.Network().Stats(Up, protocol.MsgTypePositionSync).Dropped; dropped == 0 {
+		t.Errorf("expected some position reports to be dropped")
+	}
+	// 停止移动后经过多轮仲裁，各客户端应该收敛
+	consistency.AssertConsistent(t, consistency.Clients(h.Clients()), h.GameTime(), 0.5)
+}
+
+func TestCheaterOutvoted(t *testing.T) {
+	h := newHarness(t, Config{
+		Server: []server.Option{server.WithMovement(instantMovement)},
+	})
+	mustJoin(t, h, "alice", "bob", "charlie", "mallory")
+
+	h.At(500*time.Millisecond, func() { h.Cheat("mallory", 50, 50) })
+	h.At(1*time.Second, func() { h.Move("alice", 1, 0) })
+	h.At(2*time.Second, func() { h.Move("alice", 0, 0) })
+	h.RunUntil(4 * time.Second)
+
+	// 作弊者的上报是少数，所有玩家（包括作弊者自己）的仲裁结果都以诚实上报为准
+	want := map[string][2]float64{
+		"alice":   {10, 0},
+		"bob":     {0, 0},
+		"charlie": {0, 0},
+		"mallory": {0, 0},
+	}
+	for playerID, pos := range want {
+		update, ok := h.LastArbitration(playerID)
+		if !ok {
+			t.Fatalf("no arbitration for %s", playerID)
+		}
+		if math.Hypot(update.X-pos[0], update.Y-pos[1]) > 1e-6 {
+			t.Errorf("arbitration for %s = (%.4f, %.4f), want (%.1f, %.1f)", playerID, update.X, update.Y, pos[0], pos[1])
+		}
+	}
+	consistency.AssertConsistent(t, consistency.Clients(h.Clients()), h.GameTime(), 1e-6)
+}
+
+func TestDropAndReconnect(t *testing.T) {
+	h := newHarness(t, Config{
+		Server: []server.Option{server.WithMovement(instantMovement)},
+	})
+	mustJoin(t, h, "alice", "bob", "charlie")
+
+	h.At(1*time.Second, func() { h.Drop("bob") })
+	h.At(1500*time.Millisecond, func() { h.Move("alice", 1, 0) })
+	h.At(2500*time.Millisecond, func() { h.Move("alice", 0, 0) })
+	h.RunUntil(2 * time.Second)
+
+	for _, c := range h.Clients() {
+		if status, _ := c.GetPlayerStatus("bob"); status != protocol.PlayerStatusReconnecting {
+			t.Errorf("bob status = %q, want %q", status, protocol.PlayerStatusReconnecting)
+		}
+	}
+
+	// bob 断线期间错过了 alice 的移动，重连后应从欢迎快照中恢复
+	h.At(3*time.Second, func() {
+		if err := h.Reconnect("bob"); err != nil {
+			t.Errorf("Reconnect: %v", err)
+		}
+	})
+	h.RunUntil(5 * time.Second)
+
+	if len(h.Clients()) != 3 {
+		t.Fatalf("online clients = %d, want 3", len(h.Clients()))
+	}
+	for _, c := range h.Clients() {
+		if status, _ := c.GetPlayerStatus("bob"); status != protocol.PlayerStatusOnline {
+			t.Errorf("bob status = %q after reconnect, want %q", status, protocol.PlayerStatusOnline)
+		}
+	}
+	x, y, ok := h.Client("bob").GetPlayerPosition("alice")
+	if !ok || math.Hypot(x-10, y) > 1e-6 {
+		t.Errorf("bob sees alice at (%.4f, %.4f, %v), want (10, 0)", x, y, ok)
+	}
+	consistency.AssertConsistent(t, consistency.Clients(h.Clients()), h.GameTime(), 1e-6)
+}
+
+// assertReturningPosition 让 alice 在 bob、charlie 之后加入，检查她保持恢复的位置 (50, 0)
+// 其他客户端从加入通知中得到她的位置，仲裁不会把她拉回原点
+func assertReturningPosition(t *testing.T, h *Harness) {
+	t.Helper()
+	mustJoin(t, h, "bob", "charlie")
+	h.At(500*time.Millisecond, func() {
+		if err := h.Join("alice"); err != nil {
+			t.Errorf("Join alice: %v", err)
+		}
+	})
+	h.RunUntil(4 * time.Second)
+
+	if x, y, _, ok := h.ServerPosition("alice"); !ok || math.Hypot(x-50, y) > 1e-6 {
+		t.Errorf("server has alice at (%.4f, %.4f, %v), want (50, 0)", x, y, ok)
+	}
+	for _, playerID := range []string{"alice", "bob", "charlie"} {
+		if x, y, ok := h.Client(playerID).GetPlayerPosition("alice"); !ok || math.Hypot(x-50, y) > 1e-6 {
+			t.Errorf("%s sees alice at (%.4f, %.4f, %v), want (50, 0)", playerID, x, y, ok)
+		}
+	}
+}
+
+func TestReturningPlayerKeepsPosition(t *testing.T) {
+	h := newHarness(t, Config{
+		Server: []server.Option{server.WithMovement(instantMovement)},
+	})
+	err := h.Server().RestoreSnapshot(&server.WorldSnapshot{
+		Version: server.SnapshotVersion,
+		Players: []server.PlayerSnapshot{{PlayerID: "alice", RoomID: server.DefaultRoomID, X: 50}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReturningPosition(t, h)
+}
+
+func TestStoredPlayerKeepsPosition(t *testing.T) {
+	h := newHarness(t, Config{
+		Server: []server.Option{server.WithMovement(instantMovement)},
+	})
+	store := server.NewMemoryPlayerStore()
+	store.Save(server.PlayerRecord{PlayerID: "alice", RoomID: server.DefaultRoomID, X: 50})
+	h.Server().SetPlayerStore(store)
+	assertReturningPosition(t, h)
+
+	// 主动离开后记录被删除，之后的仲裁不会再写回
+	h.At(4500*time.Millisecond, func() { h.Leave("alice") })
+	h.RunUntil(6 * time.Second)
+	h.Server().SetPlayerStore(server.NewMemoryPlayerStore())
+	if record, ok, _ := store.Load("alice"); ok {
+		t.Errorf("store still has alice at (%.2f, %.2f) after leave", record.X, record.Y)
+	}
+}
+
+func TestSetPlayerMovementLeadTime(t *testing.T) {
+	h := newHarness(t, Config{
+		Server: []server.Option{server.WithMovement(instantMovement)},
+	})
+	mustJoin(t, h, "alice", "bob")
+	h.RunUntil(1 * time.Second)
+
+	mounted := protocol.MovementParams{Model: protocol.MovementModelInstant, MaxSpeed: 20}
+	if err := h.Server().SetPlayerMovement("alice", mounted, h.GameTime()-1); err == nil {
+		t.Error("SetPlayerMovement accepted an effective time in the past")
+	}
+	if err := h.Server().SetPlayerMovement("alice", mounted, h.GameTime()+200); err != nil {
+		t.Fatalf("SetPlayerMovement: %v", err)
+	}
+	h.RunUntil(2 * time.Second)
+
+	for _, playerID := range []string{"alice", "bob"} {
+		if params, ok := h.Client(playerID).GetPlayerMovement("alice"); !ok || params != mounted {
+			t.Errorf("%s has alice movement %+v, want %+v", playerID, params, mounted)
+		}
+	}
+}
+
+func TestMessageCounts(t *testing.T) {
+	h := newHarness(t, Config{
+		Server: []server.Option{
+			server.WithTickIntervals(time.Second, 500*time.Millisecond),
+			server.WithReportInterval(100 * time.Millisecond),
+		},
+	})
+	mustJoin(t, h, "alice", "bob", "charlie")
+
+	h.At(1*time.Second, func() { h.Move("alice", 1, 0) })
+	h.At(2*time.Second, func() { h.Move("alice", 0, 0) })
+	h.RunUntil(10 * time.Second)
+
+	// 每个客户端每 100ms 上报一次；加入后第一次上报前使用客户端默认的 200ms 间隔
+	reports := h.Network().Stats(Up, protocol.MsgTypePositionSync).Sent
+	if reports < 3*95 || reports > 3*100 {
+		t.Errorf("position reports = %d, want about %d", reports, 3*100)
+	}
+
+	// 两条移动指令各广播给三个客户端
+	if moves := h.Network().Stats(Down, protocol.MsgTypeMoveCommand); moves.Sent != 6 || moves.Delivered != 6 {
+		t.Errorf("move commands = %+v, want 6 sent and delivered", moves)
+	}
+
+	// 每秒一次时间同步广播
+	if syncs := h.Network().Stats(Down, protocol.MsgTypeTimeSync).Sent; syncs != 3*10 {
+		t.Errorf("time syncs = %d, want %d", syncs, 3*10)
+	}
+
+	// 每轮仲裁对每个玩家发出一次结果（首轮仲裁前可能还没有上报）
+	arbitrations := len(h.Network().Arbitrations())
+	if arbitrations < 3*19 || arbitrations > 3*20 {
+		t.Errorf("arbitrations = %d, want about %d", arbitrations, 3*20)
+	}
+
+	// 每轮仲裁的结果合并为一条消息，每个客户端每轮只收到一条
+	ticks := int(10 * time.Second / (500 * time.Millisecond))
+	batches := h.Network().Stats(Down, protocol.MsgTypePositionBatch).Sent
+	if want := len(h.OnlinePlayers()) * ticks; batches != want {
+		t.Errorf("position batches = %d, want one per client per tick (%d)", batches, want)
+	}
+	if updates := h.Network().Stats(Down, protocol.MsgTypePositionUpdate).Sent; updates != 0 {
+		t.Errorf("position updates = %d, want 0 with batching", updates)
+	}
+
+	if stats := h.Network().AllStats(); len(stats) == 0 {
+		t.Fatal("no messages recorded")
+	} else {
+		for key, s := range stats {
+			if s.Dropped != 0 || s.Delivered != s.Sent {
+				t.Errorf("%s: %+v, want every message delivered on a perfect network", key, s)
+			}
+		}
+	}
+}
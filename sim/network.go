@@ -0,0 +1,392 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"syncServerDemo/client"
+	"syncServerDemo/protocol"
+	"syncServerDemo/server"
+	"syncServerDemo/transport"
+	"time"
+)
+
+// Direction 消息方向
+type Direction string
+
+const (
+	Up   Direction = "up"   // 客户端 -> 服务器
+	Down Direction = "down" // 服务器 -> 客户端
+)
+
+// MessageStats 某一方向某类消息的计数
+type MessageStats struct {
+	Sent      int // 发出的消息数，广播按接收者展开
+	Delivered int // 送达的次数，重复送达计入多次
+	Dropped   int // 丢包或链路断开而未送达的消息数
+}
+
+// Network 模拟网络
+// 同时实现服务器侧的 transport.Transport 和客户端侧的 transport.ClientTransport。
+// 消息按 transport.LinkModel 计算送达时间后放入调度器，到时直接调用服务器或客户端的 HandleMessage，
+// 全程不启动 goroutine。消息以 JSON 编码后再投递，与真实传输层一样不共享内存。
+type Network struct {
+	sched *scheduler
+	clock func() time.Time
+	seed  int64
+
+	server  *server.GameServer
+	clients map[string]*client.GameClient
+
+	defaults   transport.NetworkConditions
+	perClient  map[string]transport.NetworkConditions
+	models     map[string]*transport.LinkModel // 每条链路独立的随机序列
+	registered map[string]bool
+	offline    map[string]bool                                      // 链路断开的客户端
+	tamper     map[string]func(transport.Message) transport.Message // 篡改客户端上行消息
+
+	stats        map[Direction]map[string]*MessageStats
+	arbitrations []protocol.PositionUpdateData
+	lastUpdate   map[string]int64 // 每个玩家最后记录的仲裁结果的游戏时间
+
+	recording bool                    // 是否按 transport.RecordingTransport 的格式录制服务器收发的消息
+	recorded  []transport.RecordEntry // 录制的消息
+
+	closed chan struct{}
+	mu     sync.Mutex
+}
+
+func newNetwork(sched *scheduler, clock func() time.Time, seed int64, defaults transport.NetworkConditions) *Network {
+	return &Network{
+		sched:      sched,
+		clock:      clock,
+		seed:       seed,
+		clients:    make(map[string]*client.GameClient),
+		defaults:   defaults,
+		perClient:  make(map[string]transport.NetworkConditions),
+		models:     make(map[string]*transport.LinkModel),
+		registered: make(map[string]bool),
+		offline:    make(map[string]bool),
+		tamper:     make(map[string]func(transport.Message) transport.Message),
+		stats:      map[Direction]map[string]*MessageStats{Up: {}, Down: {}},
+		lastUpdate: make(map[string]int64),
+		closed:     make(chan struct{}),
+	}
+}
+
+// SetClientConditions 设置单个客户端的网络条件
+func (n *Network) SetClientConditions(clientID string, cond transport.NetworkConditions) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.perClient[clientID] = cond
+}
+
+// conditions 获取客户端链路条件（调用方需持有 n.mu）
+func (n *Network) conditions(clientID string, dir Direction) transport.LinkConditions {
+	cond, ok := n.perClient[clientID]
+	if !ok {
+		cond = n.defaults
+	}
+	if dir == Up {
+		return cond.Up
+	}
+	return cond.Down
+}
+
+// model 获取链路模型（调用方需持有 n.mu）
+// 每条链路的种子由全局种子和链路名派生，某个客户端多发一条消息不会改变其他链路的命运
+func (n *Network) model(link string) *transport.LinkModel {
+	if m, ok := n.models[link]; ok {
+		return m
+	}
+	h := fnv.New64a()
+	h.Write([]byte(link))
+	m := transport.NewLinkModel(n.seed ^ int64(h.Sum64()))
+	n.models[link] = m
+	return m
+}
+
+// attach 关联客户端，下行消息投递给它
+func (n *Network) attach(clientID string, c *client.GameClient) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.clients[clientID] = c
+}
+
+// setOffline 断开或恢复客户端链路，断开期间两个方向的消息（包括在途消息）都会丢弃
+func (n *Network) setOffline(clientID string, offline bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.offline[clientID] = offline
+}
+
+// setTamper 设置客户端上行消息的篡改函数，为空时取消
+func (n *Network) setTamper(clientID string, fn func(transport.Message) transport.Message) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if fn == nil {
+		delete(n.tamper, clientID)
+		return
+	}
+	n.tamper[clientID] = fn
+}
+
+// counter 获取消息计数（调用方需持有 n.mu）
+func (n *Network) counter(dir Direction, msgType string) *MessageStats {
+	stats, ok := n.stats[dir][msgType]
+	if !ok {
+		stats = &MessageStats{}
+		n.stats[dir][msgType] = stats
+	}
+	return stats
+}
+
+// encode 以 JSON 编码消息，返回的消息数据为原始 JSON
+func encode(msg transport.Message) (transport.Message, int, error) {
+	data, err := json.Marshal(msg.GetData())
+	if err != nil {
+		return nil, 0, err
+	}
+	wire := transport.NewMessage(msg.GetType(), json.RawMessage(data))
+	return wire, transport.MessageSize(wire), nil
+}
+
+// transmit 计划一条消息的送达
+func (n *Network) transmit(dir Direction, clientID string, msg transport.Message) error {
+	wire, size, err := encode(msg)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	link := string(dir) + ":" + clientID
+	stats := n.counter(dir, msg.GetType())
+	stats.Sent++
+	if n.offline[clientID] {
+		stats.Dropped++
+		return nil
+	}
+
+	deliveries := n.model(link).Plan(link, n.conditions(clientID, dir), size, n.clock())
+	if len(deliveries) == 0 {
+		stats.Dropped++
+		return nil
+	}
+	for _, at := range deliveries {
+		n.sched.schedule(at, link, func() {
+			n.deliver(dir, clientID, wire)
+		})
+	}
+	return nil
+}
+
+// deliver 到达送达时间，交给服务器或客户端处理
+func (n *Network) deliver(dir Direction, clientID string, msg transport.Message) {
+	n.mu.Lock()
+	stats := n.counter(dir, msg.GetType())
+	if n.offline[clientID] {
+		stats.Dropped++
+		n.mu.Unlock()
+		return
+	}
+	stats.Delivered++
+	c := n.clients[clientID]
+	n.mu.Unlock()
+
+	if dir == Up {
+		n.record(transport.RecordIn, clientID, msg)
+		n.server.HandleMessage(clientID, msg)
+		return
+	}
+	if c != nil {
+		c.HandleMessage(msg)
+	}
+}
+
+// recordArbitration 记录服务器发出的仲裁结果，同一结果发给多个客户端只记录一次（调用方需持有 n.mu）
+func (n *Network) recordArbitration(msg transport.Message) {
+	switch data := msg.GetData().(type) {
+	case protocol.PositionUpdateData:
+		n.recordUpdate(data)
+	case protocol.PositionBatchData:
+		for _, update := range data.Positions {
+			n.recordUpdate(update)
+		}
+	}
+}
+
+// recordUpdate 记录一个玩家的仲裁结果，已记录过的跳过（调用方需持有 n.mu）
+func (n *Network) recordUpdate(update protocol.PositionUpdateData) {
+	if last, seen := n.lastUpdate[update.PlayerID]; seen && last == update.GameTime {
+		return
+	}
+	n.lastUpdate[update.PlayerID] = update.GameTime
+	n.arbitrations = append(n.arbitrations, update)
+}
+
+// record 录制一条服务器收发的消息，与 RecordingTransport 一样在服务器发送或处理时记录
+func (n *Network) record(direction, clientID string, msg transport.Message) {
+	n.mu.Lock()
+	recording := n.recording
+	n.mu.Unlock()
+	if !recording {
+		return
+	}
+
+	entry := transport.RecordEntry{
+		Direction: direction,
+		ClientID:  clientID,
+		GameTime:  n.server.GetGameTime(),
+		WallTime:  n.clock(),
+	}
+	if msg != nil {
+		data, err := json.Marshal(msg.GetData())
+		if err != nil {
+			return
+		}
+		entry.Type = msg.GetType()
+		entry.Data = data
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	entry.Seq = uint64(len(n.recorded) + 1)
+	n.recorded = append(n.recorded, entry)
+}
+
+// Recording 获取录制的消息，可以交给 replay.Run 回放
+func (n *Network) Recording() []transport.RecordEntry {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]transport.RecordEntry(nil), n.recorded...)
+}
+
+// Send 服务器发送消息到指定客户端
+func (n *Network) Send(clientID string, msg transport.Message) error {
+	n.mu.Lock()
+	if !n.registered[clientID] {
+		n.mu.Unlock()
+		return &transport.SendError{ClientID: clientID, Err: transport.ErrClientNotFound}
+	}
+	n.recordArbitration(msg)
+	n.mu.Unlock()
+
+	n.record(transport.RecordOut, clientID, msg)
+
+	return n.transmit(Down, clientID, msg)
+}
+
+// Broadcast 服务器广播消息，按客户端ID顺序展开为逐个发送
+func (n *Network) Broadcast(msg transport.Message, excludeID string) error {
+	n.mu.Lock()
+	clientIDs := make([]string, 0, len(n.registered))
+	for clientID := range n.registered {
+		if clientID != excludeID {
+			clientIDs = append(clientIDs, clientID)
+		}
+	}
+	n.mu.Unlock()
+	sort.Strings(clientIDs)
+
+	var failures []*transport.SendError
+	for _, clientID := range clientIDs {
+		if err := n.Send(clientID, msg); err != nil {
+			sendErr, ok := err.(*transport.SendError)
+			if !ok {
+				return err
+			}
+			failures = append(failures, sendErr)
+		}
+	}
+	if len(failures) > 0 {
+		return &transport.BroadcastError{Failures: failures}
+	}
+	return nil
+}
+
+// Receive 模拟网络直接投递消息，不经过接收队列；阻塞到网络关闭
+func (n *Network) Receive() (string, transport.Message, error) {
+	<-n.closed
+	return "", nil, fmt.Errorf("network closed")
+}
+
+// Register 注册客户端
+func (n *Network) Register(clientID string) error {
+	n.record(transport.RecordRegister, clientID, nil)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.registered[clientID] = true
+	return nil
+}
+
+// Unregister 注销客户端
+func (n *Network) Unregister(clientID string) error {
+	n.record(transport.RecordUnregister, clientID, nil)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.registered, clientID)
+	return nil
+}
+
+// Close 关闭网络
+func (n *Network) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	select {
+	case <-n.closed:
+	default:
+		close(n.closed)
+	}
+	return nil
+}
+
+// SendToServer 客户端发送消息到服务器
+func (n *Network) SendToServer(clientID string, msg transport.Message) error {
+	n.mu.Lock()
+	tamper := n.tamper[clientID]
+	n.mu.Unlock()
+
+	if tamper != nil {
+		msg = tamper(msg)
+	}
+	return n.transmit(Up, clientID, msg)
+}
+
+// GetClientChannel 模拟网络直接投递消息，不提供接收通道
+func (n *Network) GetClientChannel(clientID string) (chan transport.Message, error) {
+	return nil, fmt.Errorf("simulated network delivers messages directly, use GameClient.Attach")
+}
+
+// Stats 获取某一方向某类消息的计数
+func (n *Network) Stats(dir Direction, msgType string) MessageStats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if stats, ok := n.stats[dir][msgType]; ok {
+		return *stats
+	}
+	return MessageStats{}
+}
+
+// AllStats 获取全部消息计数，键为 "方向/消息类型"
+func (n *Network) AllStats() map[string]MessageStats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	result := make(map[string]MessageStats)
+	for dir, byType := range n.stats {
+		for msgType, stats := range byType {
+			result[string(dir)+"/"+msgType] = *stats
+		}
+	}
+	return result
+}
+
+// Arbitrations 获取服务器发出的全部仲裁结果，按发出顺序
+func (n *Network) Arbitrations() []protocol.PositionUpdateData {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]protocol.PositionUpdateData(nil), n.arbitrations...)
+}
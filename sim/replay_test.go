@@ -0,0 +1,39 @@
+package sim
+
+import (
+	"syncServerDemo/replay"
+	"syncServerDemo/server"
+	"testing"
+	"time"
+)
+
+func TestRecordReplayRoundTrip(t *testing.T) {
+	h := newHarness(t, Config{
+		Server: []server.Option{server.WithMovement(instantMovement)},
+		Record: true,
+	})
+	mustJoin(t, h, "alice", "bob", "charlie")
+
+	h.At(1*time.Second, func() { h.Move("alice", 1, 0) })
+	h.At(1500*time.Millisecond, func() { h.Drop("bob") })
+	h.At(2*time.Second, func() { h.Move("charlie", 0, 1) })
+	h.At(3*time.Second, func() { h.Reconnect("bob") })
+	h.At(4*time.Second, func() { h.Move("alice", 0, 0) })
+	h.RunUntil(6 * time.Second)
+
+	result, err := replay.Run(h.Recording(), replay.Options{Tolerance: 1e-6})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	ticks := int(6 * time.Second / server.DefaultConfig().ArbitrationInterval)
+	if len(result.Recorded) != ticks || len(result.Replayed) != ticks {
+		t.Errorf("recorded %d / replayed %d ticks, want %d", len(result.Recorded), len(result.Replayed), ticks)
+	}
+	for _, diff := range result.Diffs {
+		t.Errorf("replay diverged: %v", diff)
+	}
+	if bob := result.Clients["bob"]; bob == nil || len(bob.Received) == 0 {
+		t.Error("replay server sent nothing to bob")
+	}
+}
@@ -0,0 +1,501 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"syncServerDemo/protocol"
+	"syncServerDemo/server"
+	"syncServerDemo/transport"
+	"time"
+)
+
+// Duration 场景文件中以 "500ms"、"1s" 形式书写的时间长度
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"500ms\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// 场景动作
+const (
+	ActionMove      = "move"      // 按 (x, y) 方向移动
+	ActionStop      = "stop"      // 停止移动
+	ActionDrop      = "drop"      // 断线
+	ActionReconnect = "reconnect" // 凭会话令牌重连
+	ActionLeave     = "leave"     // 主动离开
+	ActionCheat     = "cheat"     // 上报的位置偏移 (x, y)，都为 0 时恢复诚实
+	ActionNetwork   = "network"   // 修改该玩家的网络条件
+)
+
+// Scenario 声明式的同步场景
+// 列出客户端及其加入时间、按时间排列的动作、网络条件、作弊者，以及运行结束时的期望状态
+type Scenario struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Seed        int64            `json:"seed"`
+	Duration    Duration         `json:"duration"` // 运行时长，期望在结束时检查
+	Server      ServerSpec       `json:"server"`
+	Network     NetworkSpec      `json:"network"` // 默认网络条件
+	Clients     []ClientSpec     `json:"clients"`
+	Actions     []ActionSpec     `json:"actions"`
+	Expect      ExpectationsSpec `json:"expect"`
+}
+
+// ServerSpec 服务器配置，未填写的字段使用默认值
+type ServerSpec struct {
+	TimeSyncInterval    Duration                 `json:"time_sync_interval,omitempty"`
+	ArbitrationInterval Duration                 `json:"arbitration_interval,omitempty"`
+	ReportInterval      Duration                 `json:"report_interval,omitempty"`
+	Epsilon             float64                  `json:"epsilon,omitempty"`
+	Movement            *protocol.MovementParams `json:"movement,omitempty"`
+}
+
+// LinkSpec 单向链路条件
+type LinkSpec struct {
+	Latency      Duration `json:"latency,omitempty"`
+	Jitter       Duration `json:"jitter,omitempty"`
+	Loss         float64  `json:"loss,omitempty"`
+	Duplicate    float64  `json:"duplicate,omitempty"`
+	Reorder      float64  `json:"reorder,omitempty"`
+	ReorderDelay Duration `json:"reorder_delay,omitempty"`
+	Bandwidth    int      `json:"bandwidth,omitempty"` // 字节/秒，0 表示不限
+}
+
+// NetworkSpec 网络条件，直接写的链路字段同时作用于上下行，up/down 可以单独覆盖一个方向
+type NetworkSpec struct {
+	LinkSpec
+	Up   *LinkSpec `json:"up,omitempty"`
+	Down *LinkSpec `json:"down,omitempty"`
+}
+
+// ClientSpec 客户端
+type ClientSpec struct {
+	ID      string       `json:"id"` // 同时作为玩家ID
+	Join    Duration     `json:"join"`
+	Network *NetworkSpec `json:"network,omitempty"` // 为空时使用默认网络条件
+	Cheat   *OffsetSpec  `json:"cheat,omitempty"`   // 从加入起就是作弊者
+}
+
+// OffsetSpec 作弊者上报位置的偏移
+type OffsetSpec struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// ActionSpec 定时动作
+type ActionSpec struct {
+	At      Duration     `json:"at"`
+	Player  string       `json:"player"`
+	Action  string       `json:"action"`
+	X       float64      `json:"x,omitempty"` // move 的方向或 cheat 的偏移
+	Y       float64      `json:"y,omitempty"`
+	Network *NetworkSpec `json:"network,omitempty"` // network 动作的新条件
+}
+
+// ExpectationsSpec 运行结束时的期望
+type ExpectationsSpec struct {
+	Consistent *float64           `json:"consistent,omitempty"` // 在线客户端之间的最大偏差，且没有缺失的实体
+	Positions  []PositionExpect   `json:"positions,omitempty"`
+	Statuses   []StatusExpect     `json:"statuses,omitempty"`
+	Messages   []MessageCountSpec `json:"messages,omitempty"`
+}
+
+// PositionExpect 玩家的最终位置，服务器仲裁结果和每个在线客户端看到的位置都要符合
+type PositionExpect struct {
+	Player    string  `json:"player"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Tolerance float64 `json:"tolerance,omitempty"` // 为 0 时使用 0.01
+}
+
+// StatusExpect 其他在线客户端看到的玩家状态
+type StatusExpect struct {
+	Player string `json:"player"`
+	Status string `json:"status"` // online 或 reconnecting
+}
+
+// MessageCountSpec 某类消息的数量范围
+type MessageCountSpec struct {
+	Direction string `json:"direction"`       // up 或 down
+	Type      string `json:"type"`            // 消息类型，如 position_sync
+	Count     string `json:"count,omitempty"` // sent（默认）、delivered 或 dropped
+	Min       *int   `json:"min,omitempty"`
+	Max       *int   `json:"max,omitempty"`
+}
+
+// defaultPositionTolerance 未指定容差时的位置误差
+const defaultPositionTolerance = 0.01
+
+// LoadScenario 读取场景文件，未知字段视为错误
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scenario, err := ParseScenario(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return scenario, nil
+}
+
+// ParseScenario 解析并校验场景
+func ParseScenario(data []byte) (*Scenario, error) {
+	var scenario Scenario
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&scenario); err != nil {
+		return nil, err
+	}
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+// Validate 校验场景
+func (s *Scenario) Validate() error {
+	if s.Duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	if err := s.Network.validate(); err != nil {
+		return fmt.Errorf("network: %w", err)
+	}
+	if _, err := s.serverConfig(); err != nil {
+		return fmt.Errorf("server: %w", err)
+	}
+
+	clients := make(map[string]bool)
+	for i, c := range s.Clients {
+		if c.ID == "" {
+			return fmt.Errorf("client %d: id is required", i)
+		}
+		if clients[c.ID] {
+			return fmt.Errorf("client %s: duplicate id", c.ID)
+		}
+		clients[c.ID] = true
+		if c.Join < 0 || c.Join > s.Duration {
+			return fmt.Errorf("client %s: join time %v outside scenario duration", c.ID, c.Join)
+		}
+		if c.Network != nil {
+			if err := c.Network.validate(); err != nil {
+				return fmt.Errorf("client %s network: %w", c.ID, err)
+			}
+		}
+	}
+
+	for i, a := range s.Actions {
+		if !clients[a.Player] {
+			return fmt.Errorf("action %d: unknown player %q", i, a.Player)
+		}
+		if a.At < 0 || a.At > s.Duration {
+			return fmt.Errorf("action %d: time %v outside scenario duration", i, a.At)
+		}
+		switch a.Action {
+		case ActionMove, ActionStop, ActionDrop, ActionReconnect, ActionLeave, ActionCheat:
+		case ActionNetwork:
+			if a.Network == nil {
+				return fmt.Errorf("action %d: network action requires network conditions", i)
+			}
+			if err := a.Network.validate(); err != nil {
+				return fmt.Errorf("action %d network: %w", i, err)
+			}
+		default:
+			return fmt.Errorf("action %d: unknown action %q", i, a.Action)
+		}
+	}
+
+	if s.Expect.Consistent != nil && *s.Expect.Consistent < 0 {
+		return fmt.Errorf("expect: consistent tolerance must not be negative")
+	}
+	for _, p := range s.Expect.Positions {
+		if !clients[p.Player] {
+			return fmt.Errorf("expect: unknown player %q in positions", p.Player)
+		}
+	}
+	for _, st := range s.Expect.Statuses {
+		if !clients[st.Player] {
+			return fmt.Errorf("expect: unknown player %q in statuses", st.Player)
+		}
+		if st.Status != protocol.PlayerStatusOnline && st.Status != protocol.PlayerStatusReconnecting {
+			return fmt.Errorf("expect: unknown status %q", st.Status)
+		}
+	}
+	for _, m := range s.Expect.Messages {
+		if m.Direction != string(Up) && m.Direction != string(Down) {
+			return fmt.Errorf("expect: message direction must be up or down, got %q", m.Direction)
+		}
+		if m.Type == "" {
+			return fmt.Errorf("expect: message type is required")
+		}
+		switch m.Count {
+		case "", "sent", "delivered", "dropped":
+		default:
+			return fmt.Errorf("expect: unknown message count %q", m.Count)
+		}
+		if m.Min == nil && m.Max == nil {
+			return fmt.Errorf("expect: %s %s needs min or max", m.Direction, m.Type)
+		}
+	}
+	return nil
+}
+
+// validate 校验链路条件
+func (l LinkSpec) validate() error {
+	rates := []struct {
+		name  string
+		value float64
+	}{{"loss", l.Loss}, {"duplicate", l.Duplicate}, {"reorder", l.Reorder}}
+	for _, rate := range rates {
+		if rate.value < 0 || rate.value > 1 {
+			return fmt.Errorf("%s must be between 0 and 1, got %v", rate.name, rate.value)
+		}
+	}
+	if l.Latency < 0 || l.Jitter < 0 || l.ReorderDelay < 0 || l.Bandwidth < 0 {
+		return fmt.Errorf("latency, jitter, reorder delay and bandwidth must not be negative")
+	}
+	return nil
+}
+
+func (n NetworkSpec) validate() error {
+	for _, link := range []*LinkSpec{&n.LinkSpec, n.Up, n.Down} {
+		if link == nil {
+			continue
+		}
+		if err := link.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l LinkSpec) conditions() transport.LinkConditions {
+	return transport.LinkConditions{
+		Latency:       time.Duration(l.Latency),
+		Jitter:        time.Duration(l.Jitter),
+		LossRate:      l.Loss,
+		DuplicateRate: l.Duplicate,
+		ReorderRate:   l.Reorder,
+		ReorderDelay:  time.Duration(l.ReorderDelay),
+		Bandwidth:     l.Bandwidth,
+	}
+}
+
+// Conditions 转换为网络条件
+func (n NetworkSpec) Conditions() transport.NetworkConditions {
+	cond := transport.Symmetric(n.LinkSpec.conditions())
+	if n.Up != nil {
+		cond.Up = n.Up.conditions()
+	}
+	if n.Down != nil {
+		cond.Down = n.Down.conditions()
+	}
+	return cond
+}
+
+// serverConfig 在默认配置上应用场景中填写的字段
+func (s *Scenario) serverConfig() (server.Config, error) {
+	cfg := server.DefaultConfig()
+	if s.Server.TimeSyncInterval > 0 {
+		cfg.TimeSyncInterval = time.Duration(s.Server.TimeSyncInterval)
+	}
+	if s.Server.ArbitrationInterval > 0 {
+		cfg.ArbitrationInterval = time.Duration(s.Server.ArbitrationInterval)
+	}
+	if s.Server.ReportInterval > 0 {
+		cfg.ReportInterval = time.Duration(s.Server.ReportInterval)
+	}
+	if s.Server.Epsilon > 0 {
+		cfg.ArbitrationEpsilon = s.Server.Epsilon
+	}
+	if s.Server.Movement != nil {
+		cfg.Movement = *s.Server.Movement
+	}
+	return cfg, cfg.Validate()
+}
+
+// Result 场景运行结果
+type Result struct {
+	Scenario *Scenario
+	Harness  *Harness // 运行结束时的模拟器，可继续查询状态
+	Failures []string // 未满足的期望
+}
+
+// Passed 是否满足所有期望
+func (r *Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Err 未满足期望时返回汇总的错误
+func (r *Result) Err() error {
+	if r.Passed() {
+		return nil
+	}
+	return fmt.Errorf("scenario %s failed:\n  %s", r.Scenario.Name, strings.Join(r.Failures, "\n  "))
+}
+
+// Run 运行场景并检查期望
+// 场景本身无法执行（如对未加入的玩家执行动作）时返回错误；期望未满足记录在 Result.Failures 中
+func (s *Scenario) Run() (*Result, error) {
+	cfg, err := s.serverConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid server config: %w", err)
+	}
+	h, err := New(Config{
+		Seed:    s.Seed,
+		Network: s.Network.Conditions(),
+		Server:  []server.Option{server.WithConfig(cfg)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []string
+	do := func(what string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s at %v: %v", what, h.Elapsed(), err))
+		}
+	}
+
+	// 加入和动作按时间排序，同一时间先加入再执行动作，其余保持文件中的顺序
+	clients := append([]ClientSpec(nil), s.Clients...)
+	sort.SliceStable(clients, func(i, j int) bool { return clients[i].Join < clients[j].Join })
+	actions := append([]ActionSpec(nil), s.Actions...)
+	sort.SliceStable(actions, func(i, j int) bool { return actions[i].At < actions[j].At })
+
+	for _, c := range clients {
+		h.At(time.Duration(c.Join), func() {
+			if c.Network != nil {
+				h.SetConditions(c.ID, c.Network.Conditions())
+			}
+			do("join "+c.ID, h.Join(c.ID))
+			if c.Cheat != nil {
+				do("cheat "+c.ID, h.Cheat(c.ID, c.Cheat.X, c.Cheat.Y))
+			}
+		})
+	}
+	for _, a := range actions {
+		h.At(time.Duration(a.At), func() {
+			do(a.Action+" "+a.Player, s.apply(h, a))
+		})
+	}
+
+	h.RunUntil(time.Duration(s.Duration))
+	if len(errs) > 0 {
+		h.Close()
+		return nil, fmt.Errorf("scenario %s: %s", s.Name, strings.Join(errs, "; "))
+	}
+
+	return &Result{
+		Scenario: s,
+		Harness:  h,
+		Failures: s.check(h),
+	}, nil
+}
+
+// apply 执行一个动作
+func (s *Scenario) apply(h *Harness, a ActionSpec) error {
+	switch a.Action {
+	case ActionMove:
+		return h.Move(a.Player, a.X, a.Y)
+	case ActionStop:
+		return h.Move(a.Player, 0, 0)
+	case ActionDrop:
+		return h.Drop(a.Player)
+	case ActionReconnect:
+		return h.Reconnect(a.Player)
+	case ActionLeave:
+		return h.Leave(a.Player)
+	case ActionCheat:
+		return h.Cheat(a.Player, a.X, a.Y)
+	case ActionNetwork:
+		h.SetConditions(a.Player, a.Network.Conditions())
+		return nil
+	}
+	return fmt.Errorf("unknown action %q", a.Action)
+}
+
+// check 检查运行结束时的期望
+func (s *Scenario) check(h *Harness) []string {
+	var failures []string
+
+	if s.Expect.Consistent != nil {
+		sample := h.Consistency()
+		if err := sample.Check(*s.Expect.Consistent); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	for _, p := range s.Expect.Positions {
+		tolerance := p.Tolerance
+		if tolerance <= 0 {
+			tolerance = defaultPositionTolerance
+		}
+		if x, y, _, ok := h.ServerPosition(p.Player); !ok {
+			failures = append(failures, fmt.Sprintf("server has no position for %s", p.Player))
+		} else if d := math.Hypot(x-p.X, y-p.Y); d > tolerance {
+			failures = append(failures, fmt.Sprintf("server position of %s is (%.3f, %.3f), want (%.3f, %.3f) ±%.3f",
+				p.Player, x, y, p.X, p.Y, tolerance))
+		}
+		for _, observer := range h.OnlinePlayers() {
+			x, y, ok := h.Client(observer).GetPlayerPosition(p.Player)
+			if !ok {
+				failures = append(failures, fmt.Sprintf("%s does not see %s", observer, p.Player))
+			} else if d := math.Hypot(x-p.X, y-p.Y); d > tolerance {
+				failures = append(failures, fmt.Sprintf("%s sees %s at (%.3f, %.3f), want (%.3f, %.3f) ±%.3f",
+					observer, p.Player, x, y, p.X, p.Y, tolerance))
+			}
+		}
+	}
+
+	for _, st := range s.Expect.Statuses {
+		for _, observer := range h.OnlinePlayers() {
+			if observer == st.Player {
+				continue
+			}
+			if status, _ := h.Client(observer).GetPlayerStatus(st.Player); status != st.Status {
+				failures = append(failures, fmt.Sprintf("%s sees %s as %q, want %q", observer, st.Player, status, st.Status))
+			}
+		}
+	}
+
+	for _, m := range s.Expect.Messages {
+		stats := h.Network().Stats(Direction(m.Direction), m.Type)
+		count, name := stats.Sent, "sent"
+		switch m.Count {
+		case "delivered":
+			count, name = stats.Delivered, "delivered"
+		case "dropped":
+			count, name = stats.Dropped, "dropped"
+		}
+		if m.Min != nil && count < *m.Min {
+			failures = append(failures, fmt.Sprintf("%s %s %s = %d, want at least %d", m.Direction, m.Type, name, count, *m.Min))
+		}
+		if m.Max != nil && count > *m.Max {
+			failures = append(failures, fmt.Sprintf("%s %s %s = %d, want at most %d", m.Direction, m.Type, name, count, *m.Max))
+		}
+	}
+	return failures
+}
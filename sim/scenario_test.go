@@ -0,0 +1,62 @@
+package sim
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestScenarioFiles 运行 scenarios 目录下的所有场景文件
+func TestScenarioFiles(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "scenarios", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no scenario files found")
+	}
+
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			scenario, err := LoadScenario(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := scenario.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer result.Harness.Close()
+			for _, failure := range result.Failures {
+				t.Error(failure)
+			}
+		})
+	}
+}
+
+func TestParseScenarioErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"unknown field", `{"name":"x","duration":"1s","speed":1}`, "unknown field"},
+		{"bad duration", `{"name":"x","duration":1}`, "duration must be a string"},
+		{"no duration", `{"name":"x"}`, "duration must be positive"},
+		{"duplicate client", `{"duration":"1s","clients":[{"id":"a"},{"id":"a"}]}`, "duplicate id"},
+		{"unknown player", `{"duration":"1s","clients":[{"id":"a"}],"actions":[{"at":"0s","player":"b","action":"move"}]}`, "unknown player"},
+		{"unknown action", `{"duration":"1s","clients":[{"id":"a"}],"actions":[{"at":"0s","player":"a","action":"jump"}]}`, "unknown action"},
+		{"action after end", `{"duration":"1s","clients":[{"id":"a"}],"actions":[{"at":"2s","player":"a","action":"stop"}]}`, "outside scenario duration"},
+		{"bad loss", `{"duration":"1s","network":{"loss":2}}`, "loss must be between 0 and 1"},
+		{"bad server", `{"duration":"1s","server":{"report_interval":"1s","arbitration_interval":"500ms"}}`, "server"},
+		{"bad count", `{"duration":"1s","expect":{"messages":[{"direction":"up","type":"move","count":"lost","min":1}]}}`, "unknown message count"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScenario([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseScenario error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package sim
+
+import (
+	"container/heap"
+	"time"
+)
+
+// event 计划在虚拟时间执行的动作
+// 同一时间的事件先按 key 再按 key 内的序号排序，
+// 这样不同来源的事件即使插入顺序不同，执行顺序也只取决于时间和来源
+type event struct {
+	at  time.Time
+	key string
+	seq uint64
+	fn  func()
+}
+
+// eventQueue 按执行顺序排列的最小堆
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+func (q eventQueue) Less(i, j int) bool {
+	if !q[i].at.Equal(q[j].at) {
+		return q[i].at.Before(q[j].at)
+	}
+	if q[i].key != q[j].key {
+		return q[i].key < q[j].key
+	}
+	return q[i].seq < q[j].seq
+}
+func (q eventQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// scheduler 单线程的离散事件调度器
+type scheduler struct {
+	queue eventQueue
+	seqs  map[string]uint64
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{seqs: make(map[string]uint64)}
+}
+
+// schedule 安排在 at 执行 fn
+func (s *scheduler) schedule(at time.Time, key string, fn func()) {
+	s.seqs[key]++
+	heap.Push(&s.queue, &event{at: at, key: key, seq: s.seqs[key], fn: fn})
+}
+
+// next 取出不晚于 deadline 的下一个事件
+func (s *scheduler) next(deadline time.Time) (*event, bool) {
+	if s.queue.Len() == 0 || s.queue[0].at.After(deadline) {
+		return nil, false
+	}
+	return heap.Pop(&s.queue).(*event), true
+}
@@ -0,0 +1,136 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 发送失败的原因，通过 errors.Is 判断
+var (
+	ErrClosed         = errors.New("transport is closed")
+	ErrClientNotFound = errors.New("client not found")
+	ErrDisconnected   = errors.New("client disconnected")
+	ErrQueueFull      = errors.New("send queue full")
+	ErrSlowConsumer   = errors.New("slow consumer disconnected")
+)
+
+// SendError 发往单个客户端的消息未能放入发送队列
+type SendError struct {
+	ClientID string
+	Err      error
+}
+
+func (e *SendError) Error() string {
+	return fmt.Sprintf("client %s: %v", e.ClientID, e.Err)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// BroadcastError 广播中部分接收者发送失败，其余接收者已正常入队
+type BroadcastError struct {
+	Failures []*SendError // 按客户端ID排序
+}
+
+// newBroadcastError 汇总广播失败，没有失败时返回 nil
+func newBroadcastError(failures []*SendError) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].ClientID < failures[j].ClientID
+	})
+	return &BroadcastError{Failures: failures}
+}
+
+func (e *BroadcastError) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		parts[i] = failure.Error()
+	}
+	return fmt.Sprintf("broadcast failed for %d recipients: %s", len(e.Failures), strings.Join(parts, "; "))
+}
+
+// Unwrap 使 errors.Is 可以匹配任一接收者的失败原因
+func (e *BroadcastError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure
+	}
+	return errs
+}
+
+// FailedIDs 发送失败的客户端ID
+func (e *BroadcastError) FailedIDs() []string {
+	ids := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		ids[i] = failure.ClientID
+	}
+	return ids
+}
+
+// SendFailures 将 Send 或 Broadcast 返回的错误展开为逐个接收者的失败
+// 不是按接收者区分的错误（如传输层已关闭）返回空
+func SendFailures(err error) []*SendError {
+	var broadcastErr *BroadcastError
+	if errors.As(err, &broadcastErr) {
+		return broadcastErr.Failures
+	}
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return []*SendError{sendErr}
+	}
+	return nil
+}
+
+// OverflowPolicy 客户端发送队列满时的处理策略
+// CoalesceByType 只作用于带合并键的消息：没有合并键的可靠事件（如不同玩家的移动指令）之间不能互相取代，
+// 队列满时新的可靠事件与 DropNewest 一样被丢弃，已入队的可靠事件也不会被替换
+type OverflowPolicy int
+
+const (
+	DropNewest     OverflowPolicy = iota // 丢弃新消息，Send 返回 ErrQueueFull（默认）
+	DropOldest                           // 丢弃队列中最旧的消息，为新消息腾出位置
+	DisconnectSlow                       // 断开跟不上的客户端，Send 返回 ErrSlowConsumer
+	CoalesceByType                       // 用新消息替换队列中最旧的带合并键的同类型消息，没有时丢弃新消息
+)
+
+var overflowPolicyNames = map[OverflowPolicy]string{
+	DropNewest:     "drop-newest",
+	DropOldest:     "drop-oldest",
+	DisconnectSlow: "disconnect",
+	CoalesceByType: "coalesce",
+}
+
+func (p OverflowPolicy) String() string {
+	if name, ok := overflowPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
+// ParseOverflowPolicy 解析策略名：drop-newest、drop-oldest、disconnect 或 coalesce
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	for policy, policyName := range overflowPolicyNames {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+	return DropNewest, fmt.Errorf("unknown overflow policy %q", name)
+}
+
+// OverflowHandler 队列满时已入队的消息被丢弃或替换时调用，Send 本身不会报告这类丢失
+// 回调在发送路径上同步执行，不能再调用传输层的方法
+type OverflowHandler func(clientID string, dropped Message, policy OverflowPolicy)
+
+// OverflowController 支持按客户端设置溢出策略的传输层
+type OverflowController interface {
+	// SetOverflowPolicy 设置客户端的溢出策略，clientID 为空时设置默认策略
+	SetOverflowPolicy(clientID string, policy OverflowPolicy)
+
+	// SetOverflowHandler 设置已入队消息被丢弃时的回调
+	SetOverflowHandler(handler OverflowHandler)
+}
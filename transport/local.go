@@ -3,12 +3,15 @@ package transport
 import (
 	"fmt"
 	"sync"
+	"time"
 )
 
 // LocalTransport 本地内存实现的传输层，用于测试和演示
+// 每个客户端有一个有界发送队列，队列满时按溢出策略处理
 type LocalTransport struct {
-	channels map[string]chan Message // 每个客户端的消息通道
+	clients  map[string]*localClient // 每个客户端的发送队列和接收通道
 	incoming chan MessageWithSender  // 服务器接收通道
+	policies queuePolicies
 	mu       sync.RWMutex
 	closed   bool
 }
@@ -18,82 +21,164 @@ type MessageWithSender struct {
 	Message  Message
 }
 
+// localDrainTimeout 客户端关闭后等待读取剩余消息的最长时间，超时后丢弃未读取的消息
+const localDrainTimeout = time.Second
+
+// localClient 一个客户端的发送队列，由 pump 搬运到接收通道
+type localClient struct {
+	queue     *sendQueue
+	ch        chan Message
+	done      chan struct{}
+	once      sync.Once
+	remaining []queueItem // 关闭时尚未取出的消息
+}
+
 // NewLocalTransport 创建本地传输层
 func NewLocalTransport() *LocalTransport {
 	return &LocalTransport{
-		channels: make(map[string]chan Message),
-		incoming: make(chan MessageWithSender, 100),
+		clients:  make(map[string]*localClient),
+		incoming: make(chan MessageWithSender, defaultQueueSize),
 		closed:   false,
 	}
 }
 
+// pump 将发送队列中的消息按顺序放入接收通道，客户端关闭并送完剩余消息后关闭接收通道
+func (c *localClient) pump() {
+	defer close(c.ch)
+	for {
+		item, ok := c.queue.pop()
+		if !ok {
+			select {
+			case <-c.queue.ready:
+				continue
+			case <-c.done:
+				c.drain(c.remaining)
+				return
+			}
+		}
+		select {
+		case c.ch <- item.msg:
+		case <-c.done:
+			c.drain(append([]queueItem{item}, c.remaining...))
+			return
+		}
+	}
+}
+
+// drain 按顺序送出关闭前已入队的消息，读取方在 localDrainTimeout 内没有读完时丢弃其余消息
+func (c *localClient) drain(items []queueItem) {
+	timeout := time.NewTimer(localDrainTimeout)
+	defer timeout.Stop()
+	for _, item := range items {
+		select {
+		case c.ch <- item.msg:
+		case <-timeout.C:
+			return
+		}
+	}
+}
+
+// close 关闭客户端，之后的发送返回 ErrDisconnected，已入队的消息仍会交给读取方，可重复调用
+func (c *localClient) close() {
+	c.once.Do(func() {
+		c.remaining = c.queue.close()
+		close(c.done)
+	})
+}
+
 func (t *LocalTransport) Register(clientID string) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
 	if t.closed {
-		return fmt.Errorf("transport is closed")
+		return ErrClosed
 	}
 
-	if _, exists := t.channels[clientID]; exists {
+	if _, exists := t.clients[clientID]; exists {
 		return fmt.Errorf("client %s already registered", clientID)
 	}
 
-	t.channels[clientID] = make(chan Message, 100)
+	c := &localClient{
+		queue: newSendQueue(defaultQueueSize),
+		ch:    make(chan Message),
+		done:  make(chan struct{}),
+	}
+	t.clients[clientID] = c
+	go c.pump()
 	return nil
 }
 
+// Unregister 注销客户端，已入队的消息仍会送达，读完后关闭接收通道
 func (t *LocalTransport) Unregister(clientID string) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if ch, exists := t.channels[clientID]; exists {
-		close(ch)
-		delete(t.channels, clientID)
+	if c, exists := t.clients[clientID]; exists {
+		c.close()
+		delete(t.clients, clientID)
 	}
 	return nil
 }
 
+// SetOverflowPolicy 设置客户端发送队列满时的处理策略，clientID 为空时设置默认策略
+func (t *LocalTransport) SetOverflowPolicy(clientID string, policy OverflowPolicy) {
+	t.policies.set(clientID, policy)
+}
+
+// SetOverflowHandler 设置已入队消息因溢出被丢弃时的回调
+func (t *LocalTransport) SetOverflowHandler(handler OverflowHandler) {
+	t.policies.setHandler(handler)
+}
+
 func (t *LocalTransport) Send(clientID string, msg Message) error {
 	t.mu.RLock()
-	defer t.mu.RUnlock()
-
 	if t.closed {
-		return fmt.Errorf("transport is closed")
+		t.mu.RUnlock()
+		return ErrClosed
 	}
 
-	ch, exists := t.channels[clientID]
+	c, exists := t.clients[clientID]
 	if !exists {
-		return fmt.Errorf("client %s not found", clientID)
+		t.mu.RUnlock()
+		return &SendError{ClientID: clientID, Err: ErrClientNotFound}
 	}
+	err := t.policies.enqueue(clientID, c.queue, newQueueItem(msg, nil))
+	t.mu.RUnlock()
 
-	select {
-	case ch <- msg:
-		return nil
-	default:
-		return fmt.Errorf("client %s channel full", clientID)
-	}
+	t.dropSlowConsumers(err)
+	return err
 }
 
 func (t *LocalTransport) Broadcast(msg Message, excludeID string) error {
 	t.mu.RLock()
-	defer t.mu.RUnlock()
-
 	if t.closed {
-		return fmt.Errorf("transport is closed")
+		t.mu.RUnlock()
+		return ErrClosed
 	}
 
-	for id, ch := range t.channels {
+	var failures []*SendError
+	for id, c := range t.clients {
 		if id == excludeID {
 			continue
 		}
-		select {
-		case ch <- msg:
-		default:
-			// 如果通道满了，跳过这个客户端
+		if err := t.policies.enqueue(id, c.queue, newQueueItem(msg, nil)); err != nil {
+			failures = append(failures, err.(*SendError))
+		}
+	}
+	t.mu.RUnlock()
+
+	err := newBroadcastError(failures)
+	t.dropSlowConsumers(err)
+	return err
+}
+
+// dropSlowConsumers 注销因 DisconnectSlow 策略被断开的客户端
+func (t *LocalTransport) dropSlowConsumers(err error) {
+	for _, failure := range SendFailures(err) {
+		if failure.Err == ErrSlowConsumer {
+			t.Unregister(failure.ClientID)
 		}
 	}
-	return nil
 }
 
 func (t *LocalTransport) Receive() (string, Message, error) {
@@ -104,6 +189,7 @@ func (t *LocalTransport) Receive() (string, Message, error) {
 	return msg.ClientID, msg.Message, nil
 }
 
+// Close 关闭传输层，各客户端已入队的消息仍会送达，读完后关闭接收通道
 func (t *LocalTransport) Close() error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -113,8 +199,8 @@ func (t *LocalTransport) Close() error {
 	}
 
 	t.closed = true
-	for _, ch := range t.channels {
-		close(ch)
+	for _, c := range t.clients {
+		c.close()
 	}
 	close(t.incoming)
 	return nil
@@ -125,23 +211,26 @@ func (t *LocalTransport) GetClientChannel(clientID string) (chan Message, error)
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
-	ch, exists := t.channels[clientID]
+	c, exists := t.clients[clientID]
 	if !exists {
 		return nil, fmt.Errorf("client %s not found", clientID)
 	}
-	return ch, nil
+	return c.ch, nil
 }
 
-// SendToServer 客户端发送消息到服务器
+// SendToServer 客户端发送消息到服务器，服务器接收队列满时返回 ErrQueueFull
 func (t *LocalTransport) SendToServer(clientID string, msg Message) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	if t.closed {
-		return fmt.Errorf("transport is closed")
+		return ErrClosed
 	}
 
 	select {
 	case t.incoming <- MessageWithSender{ClientID: clientID, Message: msg}:
 		return nil
 	default:
-		return fmt.Errorf("server incoming channel full")
+		return &SendError{ClientID: clientID, Err: ErrQueueFull}
 	}
 }
@@ -0,0 +1,432 @@
+package transport
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// LinkConditions 单向链路条件
+type LinkConditions struct {
+	Latency       time.Duration // 基础延迟
+	Jitter        time.Duration // 延迟抖动，在 [-Jitter, +Jitter] 内均匀分布
+	LossRate      float64       // 丢包率 [0, 1]
+	DuplicateRate float64       // 重复率 [0, 1]
+	ReorderRate   float64       // 乱序率 [0, 1]，被选中的消息额外延迟 ReorderDelay
+	ReorderDelay  time.Duration // 乱序消息的额外延迟，为 0 时使用 Latency+Jitter
+	Bandwidth     int           // 带宽（字节/秒），0 表示不限
+}
+
+// NetworkConditions 双向网络条件
+type NetworkConditions struct {
+	Up   LinkConditions // 客户端 -> 服务器
+	Down LinkConditions // 服务器 -> 客户端
+}
+
+// Symmetric 创建上下行相同的网络条件
+func Symmetric(link LinkConditions) NetworkConditions {
+	return NetworkConditions{Up: link, Down: link}
+}
+
+// LinkModel 链路模型
+// 根据链路条件和种子确定每条消息的命运（丢弃、延迟、重复），相同种子和输入得到相同结果
+type LinkModel struct {
+	rng       *rand.Rand
+	busyUntil map[string]time.Time // 每条链路带宽占用到的时间
+	mu        sync.Mutex
+}
+
+// NewLinkModel 创建链路模型
+func NewLinkModel(seed int64) *LinkModel {
+	return &LinkModel{
+		rng:       rand.New(rand.NewSource(seed)),
+		busyUntil: make(map[string]time.Time),
+	}
+}
+
+// Plan 计算一条消息在链路上的送达时间，返回空表示丢弃，多个时间表示重复送达
+// 每次调用消耗固定数量的随机数，保证结果只取决于种子和调用顺序
+func (m *LinkModel) Plan(link string, cond LinkConditions, size int, now time.Time) []time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lossRoll := m.rng.Float64()
+	dupRoll := m.rng.Float64()
+	reorderRoll := m.rng.Float64()
+	jitterRoll := m.rng.Float64()
+	dupJitterRoll := m.rng.Float64()
+
+	if lossRoll < cond.LossRate {
+		return nil
+	}
+
+	// 带宽：消息依次占用链路
+	sent := now
+	if cond.Bandwidth > 0 {
+		start := now
+		if busy, ok := m.busyUntil[link]; ok && busy.After(start) {
+			start = busy
+		}
+		sent = start.Add(time.Duration(float64(size) / float64(cond.Bandwidth) * float64(time.Second)))
+		m.busyUntil[link] = sent
+	}
+
+	delay := cond.Latency + time.Duration((jitterRoll*2-1)*float64(cond.Jitter))
+	if reorderRoll < cond.ReorderRate {
+		extra := cond.ReorderDelay
+		if extra <= 0 {
+			extra = cond.Latency + cond.Jitter
+		}
+		delay += extra
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	deliveries := []time.Time{sent.Add(delay)}
+	if dupRoll < cond.DuplicateRate {
+		dupDelay := delay + time.Duration(dupJitterRoll*float64(cond.Jitter+time.Millisecond))
+		deliveries = append(deliveries, sent.Add(dupDelay))
+	}
+	return deliveries
+}
+
+// MessageSize 估算消息编码后的字节数
+func MessageSize(msg Message) int {
+	data, err := json.Marshal(&BaseMessage{Type: msg.GetType(), Data: msg.GetData()})
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// NetSimStats 网络模拟统计
+type NetSimStats struct {
+	UpSent         int // 客户端 -> 服务器
+	UpDropped      int
+	UpDuplicated   int
+	UpFailed       int // 到达时服务器接收队列已满
+	DownSent       int // 服务器 -> 客户端
+	DownDropped    int
+	DownDuplicated int
+	DownFailed     int // 到达时底层传输层拒绝发送
+}
+
+// NetworkSimulator 网络条件模拟传输层
+// 包装任意 Transport，在上下行两个方向注入延迟、抖动、丢包、重复、乱序和带宽限制。
+// 广播会展开为逐个客户端发送，因此客户端需要通过模拟器注册。
+type NetworkSimulator struct {
+	inner Transport
+	model *LinkModel
+
+	defaults  NetworkConditions
+	perClient map[string]NetworkConditions
+	clients   map[string]bool
+	stats     NetSimStats
+	mu        sync.RWMutex
+
+	incoming chan MessageWithSender
+	sched    *deliveryScheduler
+	closed   bool
+}
+
+// NewNetworkSimulator 创建网络模拟传输层
+func NewNetworkSimulator(inner Transport, seed int64) *NetworkSimulator {
+	ns := &NetworkSimulator{
+		inner:     inner,
+		model:     NewLinkModel(seed),
+		perClient: make(map[string]NetworkConditions),
+		clients:   make(map[string]bool),
+		incoming:  make(chan MessageWithSender, 100),
+		sched:     newDeliveryScheduler(),
+	}
+
+	go ns.sched.run()
+	go ns.pumpIncoming()
+	return ns
+}
+
+// SetDefaultConditions 设置默认网络条件
+func (ns *NetworkSimulator) SetDefaultConditions(cond NetworkConditions) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.defaults = cond
+}
+
+// SetClientConditions 设置指定客户端的网络条件
+func (ns *NetworkSimulator) SetClientConditions(clientID string, cond NetworkConditions) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.perClient[clientID] = cond
+}
+
+// conditionsFor 获取客户端的网络条件（调用方需持有 ns.mu 读锁）
+func (ns *NetworkSimulator) conditionsFor(clientID string) NetworkConditions {
+	if cond, ok := ns.perClient[clientID]; ok {
+		return cond
+	}
+	return ns.defaults
+}
+
+// Stats 获取统计
+func (ns *NetworkSimulator) Stats() NetSimStats {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+	return ns.stats
+}
+
+// pumpIncoming 从底层传输层读取上行消息并按链路条件延迟投递
+func (ns *NetworkSimulator) pumpIncoming() {
+	for {
+		clientID, msg, err := ns.inner.Receive()
+		if err != nil {
+			ns.mu.Lock()
+			ns.closed = true
+			close(ns.incoming)
+			ns.mu.Unlock()
+			return
+		}
+
+		ns.mu.Lock()
+		cond := ns.conditionsFor(clientID).Up
+		deliveries := ns.model.Plan("up:"+clientID, cond, MessageSize(msg), time.Now())
+		ns.stats.UpSent++
+		if len(deliveries) == 0 {
+			ns.stats.UpDropped++
+		}
+		if len(deliveries) > 1 {
+			ns.stats.UpDuplicated++
+		}
+		ns.mu.Unlock()
+
+		item := MessageWithSender{ClientID: clientID, Message: msg}
+		for _, at := range deliveries {
+			ns.sched.schedule(at, func() {
+				ns.mu.RLock()
+				if ns.closed {
+					ns.mu.RUnlock()
+					return
+				}
+				delivered := false
+				select {
+				case ns.incoming <- item:
+					delivered = true
+				default:
+				}
+				ns.mu.RUnlock()
+
+				if !delivered {
+					// 服务器接收队列满，计入统计
+					ns.mu.Lock()
+					ns.stats.UpFailed++
+					ns.mu.Unlock()
+				}
+			})
+		}
+	}
+}
+
+func (ns *NetworkSimulator) Send(clientID string, msg Message) error {
+	ns.mu.Lock()
+	if ns.closed {
+		ns.mu.Unlock()
+		return ErrClosed
+	}
+
+	cond := ns.conditionsFor(clientID).Down
+	deliveries := ns.model.Plan("down:"+clientID, cond, MessageSize(msg), time.Now())
+	ns.stats.DownSent++
+	if len(deliveries) == 0 {
+		ns.stats.DownDropped++
+	}
+	if len(deliveries) > 1 {
+		ns.stats.DownDuplicated++
+	}
+	ns.mu.Unlock()
+
+	// 消息延迟到达后才交给底层传输层，那时的失败无法返回给调用方，只计入统计
+	for _, at := range deliveries {
+		ns.sched.schedule(at, func() {
+			if err := ns.inner.Send(clientID, msg); err != nil {
+				ns.mu.Lock()
+				ns.stats.DownFailed++
+				ns.mu.Unlock()
+			}
+		})
+	}
+	return nil
+}
+
+func (ns *NetworkSimulator) Broadcast(msg Message, excludeID string) error {
+	ns.mu.RLock()
+	clientIDs := make([]string, 0, len(ns.clients))
+	for clientID := range ns.clients {
+		if clientID != excludeID {
+			clientIDs = append(clientIDs, clientID)
+		}
+	}
+	ns.mu.RUnlock()
+
+	var failures []*SendError
+	for _, clientID := range clientIDs {
+		if err := ns.Send(clientID, msg); err != nil {
+			if sendErr, ok := err.(*SendError); ok {
+				failures = append(failures, sendErr)
+				continue
+			}
+			return err
+		}
+	}
+	return newBroadcastError(failures)
+}
+
+// SetOverflowPolicy 转发给底层传输层，底层不支持溢出策略时忽略
+func (ns *NetworkSimulator) SetOverflowPolicy(clientID string, policy OverflowPolicy) {
+	if oc, ok := ns.inner.(OverflowController); ok {
+		oc.SetOverflowPolicy(clientID, policy)
+	}
+}
+
+// SetOverflowHandler 转发给底层传输层，底层不支持溢出策略时忽略
+func (ns *NetworkSimulator) SetOverflowHandler(handler OverflowHandler) {
+	if oc, ok := ns.inner.(OverflowController); ok {
+		oc.SetOverflowHandler(handler)
+	}
+}
+
+func (ns *NetworkSimulator) Receive() (string, Message, error) {
+	item, ok := <-ns.incoming
+	if !ok {
+		return "", nil, fmt.Errorf("transport closed")
+	}
+	return item.ClientID, item.Message, nil
+}
+
+func (ns *NetworkSimulator) Register(clientID string) error {
+	if err := ns.inner.Register(clientID); err != nil {
+		return err
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.clients[clientID] = true
+	return nil
+}
+
+func (ns *NetworkSimulator) Unregister(clientID string) error {
+	ns.mu.Lock()
+	delete(ns.clients, clientID)
+	ns.mu.Unlock()
+
+	return ns.inner.Unregister(clientID)
+}
+
+func (ns *NetworkSimulator) Close() error {
+	ns.sched.stop()
+	return ns.inner.Close()
+}
+
+// scheduledDelivery 待投递的消息
+type scheduledDelivery struct {
+	at  time.Time
+	seq uint64
+	fn  func()
+}
+
+// deliveryQueue 按送达时间排序的最小堆
+type deliveryQueue []*scheduledDelivery
+
+func (q deliveryQueue) Len() int { return len(q) }
+func (q deliveryQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+func (q deliveryQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *deliveryQueue) Push(x interface{}) { *q = append(*q, x.(*scheduledDelivery)) }
+func (q *deliveryQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// deliveryScheduler 定时投递器
+type deliveryScheduler struct {
+	queue   deliveryQueue
+	seq     uint64
+	mu      sync.Mutex
+	wake    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newDeliveryScheduler() *deliveryScheduler {
+	return &deliveryScheduler{
+		wake:    make(chan struct{}, 1),
+		stopped: make(chan struct{}),
+	}
+}
+
+// schedule 安排在指定时间执行投递
+func (d *deliveryScheduler) schedule(at time.Time, fn func()) {
+	d.mu.Lock()
+	d.seq++
+	heap.Push(&d.queue, &scheduledDelivery{at: at, seq: d.seq, fn: fn})
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run 投递循环
+func (d *deliveryScheduler) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		d.mu.Lock()
+		var due []*scheduledDelivery
+		now := time.Now()
+		for d.queue.Len() > 0 && !d.queue[0].at.After(now) {
+			due = append(due, heap.Pop(&d.queue).(*scheduledDelivery))
+		}
+		wait := time.Hour
+		if d.queue.Len() > 0 {
+			wait = d.queue[0].at.Sub(now)
+		}
+		d.mu.Unlock()
+
+		for _, item := range due {
+			item.fn()
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-timer.C:
+		case <-d.wake:
+		case <-d.stopped:
+			return
+		}
+	}
+}
+
+// stop 停止投递，未送达的消息被丢弃
+func (d *deliveryScheduler) stop() {
+	d.once.Do(func() {
+		close(d.stopped)
+	})
+}
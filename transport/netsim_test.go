@@ -0,0 +1,239 @@
+package transport
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// planMany 在同一时刻对同一链路规划 n 条消息
+func planMany(m *LinkModel, cond LinkConditions, n int, now time.Time) [][]time.Time {
+	plans := make([][]time.Time, n)
+	for i := range plans {
+		plans[i] = m.Plan("down:alice", cond, 100, now)
+	}
+	return plans
+}
+
+func TestLinkModelLatencyJitter(t *testing.T) {
+	now := time.Unix(0, 0)
+	cond := LinkConditions{Latency: 50 * time.Millisecond, Jitter: 10 * time.Millisecond}
+
+	delays := make(map[time.Duration]bool)
+	for _, plan := range planMany(NewLinkModel(1), cond, 1000, now) {
+		if len(plan) != 1 {
+			t.Fatalf("%d deliveries, want 1 without loss or duplication", len(plan))
+		}
+		delay := plan[0].Sub(now)
+		if delay < 40*time.Millisecond || delay > 60*time.Millisecond {
+			t.Fatalf("delay %v outside latency ± jitter", delay)
+		}
+		delays[delay] = true
+	}
+	if len(delays) < 100 {
+		t.Errorf("only %d distinct delays, want jitter to spread them", len(delays))
+	}
+
+	// 没有抖动时延迟固定
+	for _, plan := range planMany(NewLinkModel(1), LinkConditions{Latency: 30 * time.Millisecond}, 10, now) {
+		if delay := plan[0].Sub(now); delay != 30*time.Millisecond {
+			t.Errorf("delay %v without jitter, want 30ms", delay)
+		}
+	}
+}
+
+func TestLinkModelLossAndDuplication(t *testing.T) {
+	now := time.Unix(0, 0)
+	tests := []struct {
+		name          string
+		cond          LinkConditions
+		wantDropped   float64
+		wantDuplicate float64
+	}{
+		{"perfect", LinkConditions{}, 0, 0},
+		{"loss", LinkConditions{LossRate: 0.3}, 0.3, 0},
+		{"total loss", LinkConditions{LossRate: 1}, 1, 0},
+		{"duplicate", LinkConditions{DuplicateRate: 0.2, Jitter: 5 * time.Millisecond}, 0, 0.2},
+	}
+	const n = 10000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dropped, duplicated := 0, 0
+			for _, plan := range planMany(NewLinkModel(1), tt.cond, n, now) {
+				switch len(plan) {
+				case 0:
+					dropped++
+				case 2:
+					duplicated++
+					if plan[1].Before(plan[0]) {
+						t.Fatalf("duplicate at %v arrives before the original at %v", plan[1], plan[0])
+					}
+				}
+			}
+			if rate := float64(dropped) / n; math.Abs(rate-tt.wantDropped) > 0.02 {
+				t.Errorf("dropped rate = %.3f, want %.2f", rate, tt.wantDropped)
+			}
+			if rate := float64(duplicated) / n; math.Abs(rate-tt.wantDuplicate) > 0.02 {
+				t.Errorf("duplicated rate = %.3f, want %.2f", rate, tt.wantDuplicate)
+			}
+		})
+	}
+}
+
+func TestLinkModelReorder(t *testing.T) {
+	start := time.Unix(0, 0)
+	cond := LinkConditions{Latency: 10 * time.Millisecond, ReorderRate: 1, ReorderDelay: 100 * time.Millisecond}
+	if plan := NewLinkModel(1).Plan("down:alice", cond, 100, start); plan[0].Sub(start) != 110*time.Millisecond {
+		t.Errorf("reordered delay = %v, want latency plus reorder delay", plan[0].Sub(start))
+	}
+
+	// 每毫秒发出一条，部分消息被额外延迟，送达顺序与发送顺序不同
+	cond.ReorderRate = 0.2
+	model := NewLinkModel(1)
+	var arrivals []time.Time
+	for i := 0; i < 100; i++ {
+		arrivals = append(arrivals, model.Plan("down:alice", cond, 100, start.Add(time.Duration(i)*time.Millisecond))[0])
+	}
+	if sort.SliceIsSorted(arrivals, func(i, j int) bool { return arrivals[i].Before(arrivals[j]) }) {
+		t.Error("all messages arrived in order with a 20% reorder rate")
+	}
+}
+
+func TestLinkModelBandwidth(t *testing.T) {
+	now := time.Unix(0, 0)
+	cond := LinkConditions{Bandwidth: 1000} // 100 字节的消息占用链路 100ms
+	model := NewLinkModel(1)
+
+	for i, plan := range planMany(model, cond, 3, now) {
+		if want := time.Duration(i+1) * 100 * time.Millisecond; plan[0].Sub(now) != want {
+			t.Errorf("message %d arrives after %v, want %v", i, plan[0].Sub(now), want)
+		}
+	}
+
+	// 各链路的带宽相互独立
+	if plan := model.Plan("down:bob", cond, 100, now); plan[0].Sub(now) != 100*time.Millisecond {
+		t.Errorf("bob's first message arrives after %v, want 100ms", plan[0].Sub(now))
+	}
+	// 链路空闲后不再排队
+	later := now.Add(time.Second)
+	if plan := model.Plan("down:alice", cond, 100, later); plan[0].Sub(later) != 100*time.Millisecond {
+		t.Errorf("message on an idle link arrives after %v, want 100ms", plan[0].Sub(later))
+	}
+}
+
+func TestLinkModelDeterministic(t *testing.T) {
+	now := time.Unix(0, 0)
+	cond := LinkConditions{
+		Latency:       40 * time.Millisecond,
+		Jitter:        20 * time.Millisecond,
+		LossRate:      0.1,
+		DuplicateRate: 0.1,
+		ReorderRate:   0.1,
+		Bandwidth:     50000,
+	}
+	run := func(seed int64) [][]time.Time {
+		model := NewLinkModel(seed)
+		var plans [][]time.Time
+		for i := 0; i < 500; i++ {
+			link := []string{"down:alice", "down:bob", "up:alice"}[i%3]
+			plans = append(plans, model.Plan(link, cond, 100+i, now.Add(time.Duration(i)*time.Millisecond)))
+		}
+		return plans
+	}
+
+	if !reflect.DeepEqual(run(7), run(7)) {
+		t.Error("same seed produced different plans")
+	}
+	if reflect.DeepEqual(run(7), run(8)) {
+		t.Error("different seeds produced identical plans")
+	}
+}
+
+// simulate 通过网络模拟器向 alice 发送 n 条消息，返回收到的序号（已排序）
+// 送达的先后取决于每条消息的实际发送时刻，同一种子下确定的是哪些消息丢失、哪些重复
+func simulate(t *testing.T, seed int64, cond LinkConditions, n int) ([]int, NetSimStats) {
+	t.Helper()
+	lt := NewLocalTransport()
+	ns := NewNetworkSimulator(lt, seed)
+	defer ns.Close()
+	ns.SetDefaultConditions(Symmetric(cond))
+	ns.Register("alice")
+	ch, _ := lt.GetClientChannel("alice")
+
+	for i := 0; i < n; i++ {
+		if err := ns.Send("alice", NewMessage("seq", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := ns.Stats()
+	want := stats.DownSent - stats.DownDropped + stats.DownDuplicated
+
+	var got []int
+	timeout := time.After(cond.Latency + time.Second)
+	for len(got) < want {
+		select {
+		case msg := <-ch:
+			got = append(got, msg.GetData().(int))
+		case <-timeout:
+			t.Fatalf("received %d of %d messages", len(got), want)
+		}
+	}
+	sort.Ints(got)
+	return got, stats
+}
+
+func TestNetworkSimulatorSeeded(t *testing.T) {
+	cond := LinkConditions{LossRate: 0.3, DuplicateRate: 0.2}
+	first, stats := simulate(t, 42, cond, 80)
+	second, _ := simulate(t, 42, cond, 80)
+	other, _ := simulate(t, 43, cond, 80)
+
+	if stats.DownSent != 80 || stats.DownDropped == 0 || stats.DownDuplicated == 0 || stats.DownFailed != 0 {
+		t.Errorf("stats = %+v, want 80 sent with drops and duplicates", stats)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("same seed delivered %v then %v", first, second)
+	}
+	if reflect.DeepEqual(first, other) {
+		t.Error("different seeds delivered the same messages")
+	}
+}
+
+func TestNetworkSimulatorLatency(t *testing.T) {
+	lt := NewLocalTransport()
+	ns := NewNetworkSimulator(lt, 1)
+	defer ns.Close()
+	ns.SetDefaultConditions(NetworkConditions{
+		Up:   LinkConditions{Latency: 30 * time.Millisecond},
+		Down: LinkConditions{Latency: 20 * time.Millisecond},
+	})
+	ns.Register("alice")
+	ch, _ := lt.GetClientChannel("alice")
+
+	start := time.Now()
+	ns.Send("alice", NewMessage("down", nil))
+	<-ch
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("downlink message arrived after %v, want at least 20ms", elapsed)
+	}
+
+	start = time.Now()
+	lt.SendToServer("alice", NewMessage("up", nil))
+	clientID, msg, err := ns.Receive()
+	if err != nil || clientID != "alice" || msg.GetType() != "up" {
+		t.Fatalf("Receive = %s, %v, %v", clientID, msg, err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("uplink message arrived after %v, want at least 30ms", elapsed)
+	}
+
+	// 按客户端设置的条件优先于默认条件
+	ns.Register("bob")
+	ns.SetClientConditions("bob", Symmetric(LinkConditions{LossRate: 1}))
+	ns.Send("bob", NewMessage("down", nil))
+	if stats := ns.Stats(); stats.DownDropped != 1 {
+		t.Errorf("down dropped = %d, want bob's message dropped", stats.DownDropped)
+	}
+}
@@ -0,0 +1,219 @@
+package transport
+
+import "sync"
+
+// defaultQueueSize 每个客户端发送队列的默认容量
+const defaultQueueSize = 100
+
+// queueItem 发送队列中的一条消息
+// data 为已编码的消息，内存传输层不需要编码时为空
+type queueItem struct {
+	msg   Message // 已去掉合并键的原始消息
+	data  []byte
+	key   string    // 合并键，为空表示不合并
+	merge MergeFunc // 取代同键消息时用于合并，为空表示直接丢弃旧消息
+}
+
+// newQueueItem 创建队列项，拆出消息的合并键
+func newQueueItem(msg Message, data []byte) queueItem {
+	return queueItem{msg: unwrapKey(msg), data: data, key: CoalesceKey(msg), merge: coalesceMerge(msg)}
+}
+
+// mergedWith 将被取代的同键消息并入新消息，已编码的队列项重新编码，编码失败时只保留新消息
+func (item queueItem) mergedWith(pending queueItem) queueItem {
+	merged := item
+	merged.msg = unwrapKey(item.merge(pending.msg, item.msg))
+	if item.data != nil {
+		data, err := encodeMessage(merged.msg)
+		if err != nil {
+			return item
+		}
+		merged.data = data
+	}
+	return merged
+}
+
+// sendQueue 单个客户端的有界发送队列
+// 由发送方调用 push，由唯一的写循环调用 pop；带合并键的消息取代队列中的同键消息，队列满时按溢出策略处理
+type sendQueue struct {
+	items    []queueItem
+	capacity int
+	ready    chan struct{} // 有新消息时发出信号，容量为 1
+	closed   bool
+	mu       sync.Mutex
+}
+
+func newSendQueue(capacity int) *sendQueue {
+	return &sendQueue{
+		capacity: capacity,
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// push 放入队列，队列满时按 policy 处理
+// 返回因溢出被丢弃的已入队消息（DropOldest、CoalesceByType），以及新消息未能入队的原因
+func (q *sendQueue) push(item queueItem, policy OverflowPolicy) (*queueItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, ErrDisconnected
+	}
+
+	// 同键的旧消息已经过时（或已并入新消息），先移除再按顺序放到队尾
+	if item.key != "" {
+		if i := q.indexOfKey(item.key); i >= 0 {
+			if item.merge != nil {
+				item = item.mergedWith(q.items[i])
+			}
+			q.remove(i)
+		}
+	}
+
+	var dropped *queueItem
+	if len(q.items) >= q.capacity {
+		switch policy {
+		case DropOldest:
+			oldest := q.items[0]
+			q.items = q.items[1:]
+			dropped = &oldest
+		case DisconnectSlow:
+			return nil, ErrSlowConsumer
+		case CoalesceByType:
+			// 没有合并键的可靠事件既不替换别的消息，也不会被替换
+			i := -1
+			if item.key != "" {
+				i = q.indexOfType(item.msg.GetType())
+			}
+			if i < 0 {
+				return nil, ErrQueueFull
+			}
+			replaced := q.items[i]
+			q.remove(i)
+			dropped = &replaced
+		default:
+			return nil, ErrQueueFull
+		}
+	}
+
+	q.items = append(q.items, item)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return dropped, nil
+}
+
+// indexOfType 队列中最旧的带合并键的同类型消息的位置（调用方需持有 q.mu）
+func (q *sendQueue) indexOfType(msgType string) int {
+	for i, queued := range q.items {
+		if queued.key != "" && queued.msg.GetType() == msgType {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexOfKey 队列中同键消息的位置，合并保证同一个键最多只有一条（调用方需持有 q.mu）
+func (q *sendQueue) indexOfKey(key string) int {
+	for i, queued := range q.items {
+		if queued.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// remove 移除指定位置的消息（调用方需持有 q.mu）
+func (q *sendQueue) remove(i int) {
+	copy(q.items[i:], q.items[i+1:])
+	q.items[len(q.items)-1] = queueItem{}
+	q.items = q.items[:len(q.items)-1]
+}
+
+// pop 取出最旧的消息，队列为空时返回 false
+func (q *sendQueue) pop() (queueItem, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return queueItem{}, false
+	}
+	item := q.items[0]
+	q.items[0] = queueItem{}
+	q.items = q.items[1:]
+	return item, true
+}
+
+// len 队列中的消息数
+func (q *sendQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// close 关闭队列，之后的 push 返回 ErrDisconnected，返回尚未发出的消息
+func (q *sendQueue) close() []queueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	items := q.items
+	q.items = nil
+	return items
+}
+
+// queuePolicies 默认和按客户端的溢出策略，以及溢出回调
+type queuePolicies struct {
+	defaultPolicy OverflowPolicy
+	perClient     map[string]OverflowPolicy
+	handler       OverflowHandler
+	mu            sync.RWMutex
+}
+
+// policyFor 获取客户端的溢出策略
+func (p *queuePolicies) policyFor(clientID string) OverflowPolicy {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if policy, ok := p.perClient[clientID]; ok {
+		return policy
+	}
+	return p.defaultPolicy
+}
+
+// set 记录策略，clientID 为空时修改默认策略
+func (p *queuePolicies) set(clientID string, policy OverflowPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if clientID == "" {
+		p.defaultPolicy = policy
+		return
+	}
+	if p.perClient == nil {
+		p.perClient = make(map[string]OverflowPolicy)
+	}
+	p.perClient[clientID] = policy
+}
+
+func (p *queuePolicies) setHandler(handler OverflowHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handler = handler
+}
+
+// enqueue 按客户端的溢出策略放入发送队列，失败时返回 *SendError
+func (p *queuePolicies) enqueue(clientID string, q *sendQueue, item queueItem) error {
+	policy := p.policyFor(clientID)
+	dropped, err := q.push(item, policy)
+	if err != nil {
+		return &SendError{ClientID: clientID, Err: err}
+	}
+	if dropped != nil {
+		p.mu.RLock()
+		handler := p.handler
+		p.mu.RUnlock()
+		if handler != nil {
+			handler(clientID, dropped.msg, policy)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"syncServerDemo/protocol"
+	"testing"
+)
+
+// fillQueues 向不读取消息的客户端广播 n 条时间同步，返回第一次广播失败的错误
+// 每条消息的合并键不同，不会互相取代
+func fillQueues(lt *LocalTransport, n int) error {
+	var first error
+	for i := 0; i < n; i++ {
+		msg := NewMessage(protocol.MsgTypeTimeSync, protocol.TimeSyncData{GameTime: int64(i)})
+		err := lt.Broadcast(WithCoalesceKey(msg, fmt.Sprintf("%s:%d", protocol.MsgTypeTimeSync, i)), "")
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		policy  OverflowPolicy
+		wantErr error
+		dropped bool // 已入队的消息是否被丢弃
+	}{
+		{DropNewest, ErrQueueFull, false},
+		{DropOldest, nil, true},
+		{DisconnectSlow, ErrSlowConsumer, false},
+		{CoalesceByType, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			lt := NewLocalTransport()
+			defer lt.Close()
+			lt.Register("fast")
+			lt.Register("slow")
+			lt.SetOverflowPolicy("slow", tt.policy)
+
+			// 两个客户端都不读取，fast 使用 DropOldest，不应出现在失败列表中
+			lt.SetOverflowPolicy("fast", DropOldest)
+			dropped := 0
+			lt.SetOverflowHandler(func(clientID string, msg Message, policy OverflowPolicy) {
+				if clientID != "slow" {
+					return
+				}
+				if policy != tt.policy {
+					t.Errorf("overflow handler policy = %v, want %v", policy, tt.policy)
+				}
+				dropped++
+			})
+
+			// 队列容量加上正在投递的一条，150 条一定会溢出
+			err := fillQueues(lt, 150)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("broadcast error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var broadcastErr *BroadcastError
+				if !errors.As(err, &broadcastErr) || !reflect.DeepEqual(broadcastErr.FailedIDs(), []string{"slow"}) {
+					t.Errorf("broadcast error = %v, want failure for slow only", err)
+				}
+			}
+			if (dropped > 0) != tt.dropped {
+				t.Errorf("overflow handler called %d times, want dropped = %v", dropped, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestCoalesceKeepsReliableEvents(t *testing.T) {
+	move := func(playerID string) queueItem {
+		return newQueueItem(NewMessage(protocol.MsgTypeMoveCommand, protocol.MoveData{PlayerID: playerID}), nil)
+	}
+	batch := func(key string) queueItem {
+		return newQueueItem(WithCoalesceKey(NewMessage(protocol.MsgTypePositionBatch, protocol.PositionBatchData{}), key), nil)
+	}
+	queued := func(q *sendQueue) []string {
+		var got []string
+		for _, item := range q.items {
+			if data, ok := item.msg.GetData().(protocol.MoveData); ok {
+				got = append(got, "move "+data.PlayerID)
+			} else {
+				got = append(got, "batch "+item.key)
+			}
+		}
+		return got
+	}
+
+	q := newSendQueue(3)
+	for _, item := range []queueItem{move("alice"), move("bob"), batch("a")} {
+		if _, err := q.push(item, CoalesceByType); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 新的可靠事件不能取代其他玩家的移动指令
+	if dropped, err := q.push(move("carol"), CoalesceByType); !errors.Is(err, ErrQueueFull) || dropped != nil {
+		t.Errorf("push move = %v, %v, want ErrQueueFull", dropped, err)
+	}
+
+	// 带合并键的消息取代最旧的带合并键的同类型消息
+	dropped, err := q.push(batch("b"), CoalesceByType)
+	if err != nil || dropped == nil || dropped.key != "a" {
+		t.Errorf("push batch = %v, %v, want batch a replaced", dropped, err)
+	}
+	if got, want := queued(q), []string{"move alice", "move bob", "batch b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+
+	// 队列中只有可靠事件时，带合并键的消息也无处可放
+	q = newSendQueue(2)
+	q.push(move("alice"), CoalesceByType)
+	q.push(move("bob"), CoalesceByType)
+	for _, item := range []queueItem{move("alice"), batch("a")} {
+		if dropped, err := q.push(item, CoalesceByType); !errors.Is(err, ErrQueueFull) || dropped != nil {
+			t.Errorf("push %s = %v, %v, want ErrQueueFull", item.msg.GetType(), dropped, err)
+		}
+	}
+	if got, want := queued(q), []string{"move alice", "move bob"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+}
+
+func TestCoalesceKeys(t *testing.T) {
+	lt := NewLocalTransport()
+	defer lt.Close()
+	lt.Register("slow")
+
+	batch := func(gameTime int64) Message {
+		return WithCoalesceKey(NewMessage(protocol.MsgTypePositionBatch,
+			protocol.PositionBatchData{GameTime: gameTime}), protocol.MsgTypePositionBatch)
+	}
+	timeSync := func(gameTime int64) Message {
+		return WithCoalesceKey(NewMessage(protocol.MsgTypeTimeSync,
+			protocol.TimeSyncData{GameTime: gameTime}), protocol.MsgTypeTimeSync)
+	}
+	move := func(seq uint32) Message {
+		return NewMessage(protocol.MsgTypeMoveCommand, protocol.MoveData{PlayerID: "alice", Seq: seq})
+	}
+
+	// 客户端在全部发送完之前不读取
+	for _, msg := range []Message{
+		move(1), batch(1), timeSync(1), move(2),
+		batch(2), timeSync(2), move(3), batch(3),
+	} {
+		if err := lt.Send("slow", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ch, _ := lt.GetClientChannel("slow")
+	var got []string
+	for i := 0; i < 5; i++ {
+		msg := <-ch
+		if CoalesceKey(msg) != "" {
+			t.Errorf("delivered message still carries its coalesce key")
+		}
+		switch data := msg.GetData().(type) {
+		case protocol.PositionBatchData:
+			got = append(got, fmt.Sprintf("position_batch %d", data.GameTime))
+		case protocol.TimeSyncData:
+			got = append(got, fmt.Sprintf("time_sync %d", data.GameTime))
+		case protocol.MoveData:
+			got = append(got, fmt.Sprintf("move %d", data.Seq))
+		}
+	}
+	want := []string{"move 1", "move 2", "time_sync 2", "move 3", "position_batch 3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered %v, want %v", got, want)
+	}
+}
+
+// mergeBatches 按玩家合并两批位置结果，每个玩家保留游戏时间最新的一条
+func mergeBatches(pending, newer Message) Message {
+	merged := pending.GetData().(protocol.PositionBatchData)
+	for _, update := range newer.GetData().(protocol.PositionBatchData).Positions {
+		replaced := false
+		for i, prev := range merged.Positions {
+			if prev.PlayerID == update.PlayerID {
+				if update.GameTime >= prev.GameTime {
+					merged.Positions[i] = update
+				}
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged.Positions = append(merged.Positions, update)
+		}
+	}
+	merged.GameTime = newer.GetData().(protocol.PositionBatchData).GameTime
+	return NewMessage(protocol.MsgTypePositionBatch, merged)
+}
+
+// positionBatch 创建按玩家合并的批量位置消息
+func positionBatch(gameTime int64, playerIDs ...string) Message {
+	data := protocol.PositionBatchData{GameTime: gameTime}
+	for _, playerID := range playerIDs {
+		data.Positions = append(data.Positions, protocol.PositionUpdateData{PlayerID: playerID, X: float64(gameTime), GameTime: gameTime})
+	}
+	return WithCoalesceMerge(NewMessage(protocol.MsgTypePositionBatch, data), protocol.MsgTypePositionBatch, mergeBatches)
+}
+
+func TestCoalesceMerge(t *testing.T) {
+	lt := NewLocalTransport()
+	defer lt.Close()
+	lt.Register("slow")
+
+	// 第一条消息由投递协程取出后阻塞，之后的批量结果留在队列中；bob 只出现在被取代的一批中
+	for _, msg := range []Message{
+		NewMessage(protocol.MsgTypeMoveCommand, protocol.MoveData{PlayerID: "alice", Seq: 1}),
+		positionBatch(500, "alice", "bob"),
+		positionBatch(1000, "alice"),
+	} {
+		if err := lt.Send("slow", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ch, _ := lt.GetClientChannel("slow")
+	if msg := <-ch; msg.GetType() != protocol.MsgTypeMoveCommand {
+		t.Fatalf("first message = %s, want move_command", msg.GetType())
+	}
+	msg := <-ch
+	if CoalesceKey(msg) != "" {
+		t.Error("delivered message still carries its coalesce key")
+	}
+	want := protocol.PositionBatchData{GameTime: 1000, Positions: []protocol.PositionUpdateData{
+		{PlayerID: "alice", X: 1000, GameTime: 1000},
+		{PlayerID: "bob", X: 500, GameTime: 500},
+	}}
+	if got := msg.GetData(); !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered %+v, want %+v", got, want)
+	}
+	select {
+	case extra := <-ch:
+		t.Errorf("unexpected extra message %s", extra.GetType())
+	default:
+	}
+}
+
+func TestCoalesceMergeEncoded(t *testing.T) {
+	encoded := func(msg Message) queueItem {
+		data, err := encodeMessage(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return newQueueItem(msg, data)
+	}
+
+	// TCP 的队列项是已编码的，合并后要重新编码
+	q := newSendQueue(4)
+	q.push(encoded(positionBatch(500, "alice", "bob")), DropNewest)
+	q.push(encoded(positionBatch(1000, "alice")), DropNewest)
+	if q.len() != 1 {
+		t.Fatalf("queue holds %d items, want 1", q.len())
+	}
+
+	item, _ := q.pop()
+	var wire struct {
+		Data protocol.PositionBatchData `json:"data"`
+	}
+	if err := json.Unmarshal(item.data, &wire); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(wire.Data, item.msg.GetData()) || len(wire.Data.Positions) != 2 {
+		t.Errorf("encoded batch %+v does not match merged %+v", wire.Data, item.msg.GetData())
+	}
+}
+
+func TestCloseDeliversQueuedMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(lt *LocalTransport)
+	}{
+		{"unregister", func(lt *LocalTransport) { lt.Unregister("alice") }},
+		{"close", func(lt *LocalTransport) { lt.Close() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lt := NewLocalTransport()
+			defer lt.Close()
+			lt.Register("alice")
+			ch, _ := lt.GetClientChannel("alice")
+
+			// 第一条由投递协程持有，其余留在队列中
+			for i := 0; i < 3; i++ {
+				if err := lt.Send("alice", NewMessage("seq", i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tt.close(lt)
+			if err := lt.Send("alice", NewMessage("seq", 3)); err == nil {
+				t.Error("send after close succeeded")
+			}
+
+			var got []int
+			for msg := range ch {
+				got = append(got, msg.GetData().(int))
+			}
+			if !reflect.DeepEqual(got, []int{0, 1, 2}) {
+				t.Errorf("received %v after close, want the queued [0 1 2]", got)
+			}
+		})
+	}
+}
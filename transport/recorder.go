@@ -0,0 +1,183 @@
+package transport
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// 录制记录的方向
+const (
+	RecordIn         = "in"         // 客户端 -> 服务器
+	RecordOut        = "out"        // 服务器 -> 客户端
+	RecordRegister   = "register"   // 客户端注册
+	RecordUnregister = "unregister" // 客户端注销
+)
+
+// RecordEntry 录制的一条消息
+type RecordEntry struct {
+	Seq       uint64          `json:"seq"`
+	Direction string          `json:"dir"`
+	ClientID  string          `json:"client_id,omitempty"` // 发送方（in）或接收方（out）
+	Broadcast bool            `json:"broadcast,omitempty"`
+	ExcludeID string          `json:"exclude_id,omitempty"` // 广播时排除的客户端
+	Type      string          `json:"type,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	GameTime  int64           `json:"game_time"`
+	WallTime  time.Time       `json:"wall_time"`
+}
+
+// Message 还原为可处理的消息
+func (e *RecordEntry) Message() Message {
+	return NewMessage(e.Type, e.Data)
+}
+
+// RecordingTransport 录制传输层
+// 包装任意 Transport，将所有收发的消息连同游戏时间和真实时间写入文件（每行一条 JSON）
+type RecordingTransport struct {
+	inner     Transport
+	gameClock func() int64
+
+	file   *os.File
+	writer *bufio.Writer
+	seq    uint64
+	mu     sync.Mutex
+}
+
+// NewRecordingTransport 创建录制传输层
+func NewRecordingTransport(inner Transport, path string) (*RecordingTransport, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecordingTransport{
+		inner:     inner,
+		gameClock: func() int64 { return 0 },
+		file:      file,
+		writer:    bufio.NewWriter(file),
+	}, nil
+}
+
+// SetGameClock 设置游戏时间来源，通常为服务器的 GetGameTime
+func (r *RecordingTransport) SetGameClock(clock func() int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gameClock = clock
+}
+
+// record 写入一条记录
+func (r *RecordingTransport) record(entry RecordEntry, msg Message) {
+	if msg != nil {
+		entry.Type = msg.GetType()
+		data, err := json.Marshal(msg.GetData())
+		if err != nil {
+			data, _ = json.Marshal(fmt.Sprintf("unencodable data: %v", err))
+		}
+		entry.Data = data
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return
+	}
+
+	r.seq++
+	entry.Seq = r.seq
+	entry.GameTime = r.gameClock()
+	entry.WallTime = time.Now()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	r.writer.Write(append(line, '\n'))
+}
+
+func (r *RecordingTransport) Send(clientID string, msg Message) error {
+	r.record(RecordEntry{Direction: RecordOut, ClientID: clientID}, msg)
+	return r.inner.Send(clientID, msg)
+}
+
+func (r *RecordingTransport) Broadcast(msg Message, excludeID string) error {
+	r.record(RecordEntry{Direction: RecordOut, Broadcast: true, ExcludeID: excludeID}, msg)
+	return r.inner.Broadcast(msg, excludeID)
+}
+
+// SetOverflowPolicy 转发给底层传输层，底层不支持溢出策略时忽略
+func (r *RecordingTransport) SetOverflowPolicy(clientID string, policy OverflowPolicy) {
+	if oc, ok := r.inner.(OverflowController); ok {
+		oc.SetOverflowPolicy(clientID, policy)
+	}
+}
+
+// SetOverflowHandler 转发给底层传输层，底层不支持溢出策略时忽略
+func (r *RecordingTransport) SetOverflowHandler(handler OverflowHandler) {
+	if oc, ok := r.inner.(OverflowController); ok {
+		oc.SetOverflowHandler(handler)
+	}
+}
+
+func (r *RecordingTransport) Receive() (string, Message, error) {
+	clientID, msg, err := r.inner.Receive()
+	if err == nil {
+		r.record(RecordEntry{Direction: RecordIn, ClientID: clientID}, msg)
+	}
+	return clientID, msg, err
+}
+
+func (r *RecordingTransport) Register(clientID string) error {
+	r.record(RecordEntry{Direction: RecordRegister, ClientID: clientID}, nil)
+	return r.inner.Register(clientID)
+}
+
+func (r *RecordingTransport) Unregister(clientID string) error {
+	r.record(RecordEntry{Direction: RecordUnregister, ClientID: clientID}, nil)
+	return r.inner.Unregister(clientID)
+}
+
+// Close 关闭底层传输层并写完录制文件
+func (r *RecordingTransport) Close() error {
+	err := r.inner.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return err
+	}
+	r.writer.Flush()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file = nil
+	return err
+}
+
+// ReadRecording 读取录制文件
+func ReadRecording(path string) ([]RecordEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []RecordEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var entry RecordEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return entries, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
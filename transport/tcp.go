@@ -0,0 +1,506 @@
+package transport
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// TCP 线路格式：每行一条 JSON 消息 {"type": ..., "data": ...}。
+// 客户端连接后先发送一条 hello 消息声明自己的客户端ID，之后双方自由收发。
+const (
+	tcpHelloType      = "hello"
+	tcpHelloTimeout   = 5 * time.Second
+	tcpWriteTimeout   = 5 * time.Second
+	tcpSendQueueSize  = 256
+	tcpMaxMessageSize = 4 * 1024 * 1024
+)
+
+// tcpHello 握手数据
+type tcpHello struct {
+	ClientID string `json:"client_id"`
+}
+
+// wireMessage 线路上的消息
+type wireMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// encodeMessage 编码为一行 JSON
+func encodeMessage(msg Message) ([]byte, error) {
+	data, err := json.Marshal(&BaseMessage{Type: msg.GetType(), Data: msg.GetData()})
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// decodeMessage 解码一行 JSON，消息数据保留为原始 JSON
+func decodeMessage(line []byte) (Message, error) {
+	var wire wireMessage
+	if err := json.Unmarshal(line, &wire); err != nil {
+		return nil, err
+	}
+	if wire.Type == "" {
+		return nil, fmt.Errorf("message without type")
+	}
+	return NewMessage(wire.Type, wire.Data), nil
+}
+
+// newLineScanner 创建按行读取消息的扫描器
+func newLineScanner(conn net.Conn) *bufio.Scanner {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), tcpMaxMessageSize)
+	return scanner
+}
+
+// tcpConn 服务器侧的一条客户端连接
+type tcpConn struct {
+	clientID string
+	conn     net.Conn
+	queue    *sendQueue
+	done     chan struct{}
+	once     sync.Once
+}
+
+// close 关闭连接，可重复调用
+func (c *tcpConn) close() {
+	c.once.Do(func() {
+		c.queue.close()
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// writeLoop 发送队列写入循环
+func (c *tcpConn) writeLoop() {
+	writer := bufio.NewWriter(c.conn)
+	for {
+		item, ok := c.queue.pop()
+		if !ok {
+			// 队列空了再刷新，合并连续的小消息
+			if err := writer.Flush(); err != nil {
+				c.close()
+				return
+			}
+			select {
+			case <-c.queue.ready:
+				continue
+			case <-c.done:
+				return
+			}
+		}
+		c.conn.SetWriteDeadline(time.Now().Add(tcpWriteTimeout))
+		if _, err := writer.Write(item.data); err != nil {
+			c.close()
+			return
+		}
+	}
+}
+
+// TCPServerTransport 服务器侧 TCP 传输层
+// 客户端通过连接和握手自行注册，已有活动连接的客户端ID再次握手会被拒绝，防止冒用他人的连接；
+// 断线重连在旧连接断开后重新握手，再凭会话令牌恢复玩家
+type TCPServerTransport struct {
+	listener net.Listener
+	conns    map[string]*tcpConn
+	incoming chan MessageWithSender
+	policies queuePolicies
+	done     chan struct{}
+	closed   bool
+	mu       sync.RWMutex
+
+	onDisconnect func(clientID string)
+}
+
+// ListenTCP 在指定地址监听客户端连接
+func ListenTCP(addr string) (*TCPServerTransport, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &TCPServerTransport{
+		listener: listener,
+		conns:    make(map[string]*tcpConn),
+		incoming: make(chan MessageWithSender, 1024),
+		done:     make(chan struct{}),
+	}
+	go t.acceptLoop()
+	return t, nil
+}
+
+// Addr 获取实际监听的地址
+func (t *TCPServerTransport) Addr() net.Addr {
+	return t.listener.Addr()
+}
+
+// SetDisconnectHandler 设置连接断开回调，通常为服务器的 Disconnect
+func (t *TCPServerTransport) SetDisconnectHandler(handler func(clientID string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onDisconnect = handler
+}
+
+// SetOverflowPolicy 设置客户端发送队列满时的处理策略，clientID 为空时设置默认策略
+// DisconnectSlow 策略会关闭连接，随后照常触发断开回调
+func (t *TCPServerTransport) SetOverflowPolicy(clientID string, policy OverflowPolicy) {
+	t.policies.set(clientID, policy)
+}
+
+// SetOverflowHandler 设置已入队消息因溢出被丢弃时的回调
+func (t *TCPServerTransport) SetOverflowHandler(handler OverflowHandler) {
+	t.policies.setHandler(handler)
+}
+
+// acceptLoop 接受连接
+func (t *TCPServerTransport) acceptLoop() {
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			select {
+			case <-t.done:
+				return
+			default:
+			}
+			log.Printf("Error accepting connection: %v", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		go t.serveConn(conn)
+	}
+}
+
+// serveConn 完成握手后读取客户端消息
+func (t *TCPServerTransport) serveConn(conn net.Conn) {
+	scanner := newLineScanner(conn)
+
+	conn.SetReadDeadline(time.Now().Add(tcpHelloTimeout))
+	clientID, err := readHello(scanner)
+	if err != nil {
+		log.Printf("Rejected connection from %s: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	c := &tcpConn{
+		clientID: clientID,
+		conn:     conn,
+		queue:    newSendQueue(tcpSendQueueSize),
+		done:     make(chan struct{}),
+	}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		conn.Close()
+		return
+	}
+	if _, live := t.conns[clientID]; live {
+		t.mu.Unlock()
+		log.Printf("Rejected connection from %s: client %s is already connected", conn.RemoteAddr(), clientID)
+		conn.Close()
+		return
+	}
+	t.conns[clientID] = c
+	t.mu.Unlock()
+
+	go c.writeLoop()
+
+	for scanner.Scan() {
+		msg, err := decodeMessage(scanner.Bytes())
+		if err != nil {
+			log.Printf("Dropping malformed message from %s: %v", clientID, err)
+			continue
+		}
+		select {
+		case t.incoming <- MessageWithSender{ClientID: clientID, Message: msg}:
+		case <-t.done:
+			c.close()
+			return
+		}
+	}
+	c.close()
+
+	// 只有当前连接断开才算客户端断开，已被注销的连接不通知
+	t.mu.Lock()
+	current := t.conns[clientID] == c
+	if current {
+		delete(t.conns, clientID)
+	}
+	handler := t.onDisconnect
+	closed := t.closed
+	t.mu.Unlock()
+
+	if current && !closed && handler != nil {
+		handler(clientID)
+	}
+}
+
+// readHello 读取握手消息
+func readHello(scanner *bufio.Scanner) (string, error) {
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("connection closed before hello")
+	}
+
+	var wire wireMessage
+	if err := json.Unmarshal(scanner.Bytes(), &wire); err != nil {
+		return "", err
+	}
+	if wire.Type != tcpHelloType {
+		return "", fmt.Errorf("expected hello, got %q", wire.Type)
+	}
+	var hello tcpHello
+	if err := json.Unmarshal(wire.Data, &hello); err != nil {
+		return "", err
+	}
+	if hello.ClientID == "" {
+		return "", fmt.Errorf("empty client id")
+	}
+	return hello.ClientID, nil
+}
+
+func (t *TCPServerTransport) Send(clientID string, msg Message) error {
+	data, err := encodeMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	t.mu.RLock()
+	if t.closed {
+		t.mu.RUnlock()
+		return ErrClosed
+	}
+	c, exists := t.conns[clientID]
+	t.mu.RUnlock()
+	if !exists {
+		return &SendError{ClientID: clientID, Err: ErrClientNotFound}
+	}
+	return t.enqueue(c, newQueueItem(msg, data))
+}
+
+func (t *TCPServerTransport) Broadcast(msg Message, excludeID string) error {
+	data, err := encodeMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	t.mu.RLock()
+	if t.closed {
+		t.mu.RUnlock()
+		return ErrClosed
+	}
+	conns := make([]*tcpConn, 0, len(t.conns))
+	for id, c := range t.conns {
+		if id != excludeID {
+			conns = append(conns, c)
+		}
+	}
+	t.mu.RUnlock()
+
+	var failures []*SendError
+	for _, c := range conns {
+		if err := t.enqueue(c, newQueueItem(msg, data)); err != nil {
+			failures = append(failures, err.(*SendError))
+		}
+	}
+	return newBroadcastError(failures)
+}
+
+// enqueue 放入连接的发送队列，DisconnectSlow 策略下跟不上的连接被关闭
+func (t *TCPServerTransport) enqueue(c *tcpConn, item queueItem) error {
+	err := t.policies.enqueue(c.clientID, c.queue, item)
+	if err != nil && err.(*SendError).Err == ErrSlowConsumer {
+		log.Printf("Closing slow connection for %s", c.clientID)
+		c.close()
+	}
+	return err
+}
+
+func (t *TCPServerTransport) Receive() (string, Message, error) {
+	select {
+	case item := <-t.incoming:
+		return item.ClientID, item.Message, nil
+	case <-t.done:
+		return "", nil, fmt.Errorf("transport closed")
+	}
+}
+
+// Register 客户端通过连接自行注册，这里无需操作
+func (t *TCPServerTransport) Register(clientID string) error {
+	return nil
+}
+
+// Unregister 断开客户端连接
+func (t *TCPServerTransport) Unregister(clientID string) error {
+	t.mu.Lock()
+	c, exists := t.conns[clientID]
+	delete(t.conns, clientID)
+	t.mu.Unlock()
+
+	if exists {
+		c.close()
+	}
+	return nil
+}
+
+func (t *TCPServerTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	close(t.done)
+	conns := t.conns
+	t.conns = make(map[string]*tcpConn)
+	t.mu.Unlock()
+
+	err := t.listener.Close()
+	for _, c := range conns {
+		c.close()
+	}
+	return err
+}
+
+// TCPClientTransport 客户端侧 TCP 传输层
+type TCPClientTransport struct {
+	addr     string
+	clientID string
+
+	conn   net.Conn
+	writer *bufio.Writer
+	inbox  chan Message
+	done   chan struct{} // 当前连接关闭时关闭
+	mu     sync.Mutex
+}
+
+// DialTCP 连接服务器并完成握手
+func DialTCP(addr, clientID string) (*TCPClientTransport, error) {
+	t := &TCPClientTransport{
+		addr:     addr,
+		clientID: clientID,
+	}
+	if err := t.Redial(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Redial 重新连接服务器，用于断线后恢复会话
+// 先关闭旧连接，服务器在旧连接断开前会拒绝同一客户端ID的握手；之后 GetClientChannel 返回新连接的接收通道
+func (t *TCPClientTransport) Redial() error {
+	t.mu.Lock()
+	t.closeConn()
+	t.mu.Unlock()
+
+	conn, err := net.DialTimeout("tcp", t.addr, tcpHelloTimeout)
+	if err != nil {
+		return err
+	}
+
+	hello, err := encodeMessage(NewMessage(tcpHelloType, tcpHello{ClientID: t.clientID}))
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetWriteDeadline(time.Now().Add(tcpWriteTimeout))
+	if _, err := conn.Write(hello); err != nil {
+		conn.Close()
+		return err
+	}
+
+	inbox := make(chan Message, 256)
+	done := make(chan struct{})
+
+	t.mu.Lock()
+	t.closeConn()
+	t.conn = conn
+	t.writer = bufio.NewWriter(conn)
+	t.inbox = inbox
+	t.done = done
+	t.mu.Unlock()
+
+	go t.readLoop(conn, inbox, done)
+	return nil
+}
+
+// closeConn 关闭当前连接（调用方需持有 t.mu）
+func (t *TCPClientTransport) closeConn() error {
+	if t.conn == nil {
+		return nil
+	}
+	close(t.done)
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
+
+// readLoop 读取服务器消息，连接断开时关闭接收通道
+func (t *TCPClientTransport) readLoop(conn net.Conn, inbox chan Message, done chan struct{}) {
+	defer close(inbox)
+
+	scanner := newLineScanner(conn)
+	for scanner.Scan() {
+		msg, err := decodeMessage(scanner.Bytes())
+		if err != nil {
+			log.Printf("Dropping malformed message from server: %v", err)
+			continue
+		}
+		select {
+		case inbox <- msg:
+		case <-done:
+			return
+		}
+	}
+}
+
+// SendToServer 发送消息到服务器
+func (t *TCPClientTransport) SendToServer(clientID string, msg Message) error {
+	data, err := encodeMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn == nil {
+		return fmt.Errorf("transport is closed")
+	}
+	t.conn.SetWriteDeadline(time.Now().Add(tcpWriteTimeout))
+	if _, err := t.writer.Write(data); err != nil {
+		return err
+	}
+	return t.writer.Flush()
+}
+
+// GetClientChannel 获取当前连接的接收通道
+func (t *TCPClientTransport) GetClientChannel(clientID string) (chan Message, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if clientID != t.clientID {
+		return nil, fmt.Errorf("client %s not found", clientID)
+	}
+	if t.inbox == nil {
+		return nil, fmt.Errorf("transport is closed")
+	}
+	return t.inbox, nil
+}
+
+// Close 关闭连接
+func (t *TCPClientTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closeConn()
+}
@@ -0,0 +1,57 @@
+package transport
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTCPRejectsDuplicateHello(t *testing.T) {
+	server, err := ListenTCP("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	alice, err := DialTCP(server.Addr().String(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	if err := alice.SendToServer("alice", NewMessage("ping", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if clientID, _, err := server.Receive(); err != nil || clientID != "alice" {
+		t.Fatalf("Receive = %q, %v; want alice", clientID, err)
+	}
+
+	// 另一个连接冒用 alice 的ID，服务器直接关闭
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	hello, _ := encodeMessage(NewMessage(tcpHelloType, tcpHello{ClientID: "alice"}))
+	conn.Write(hello)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := bufio.NewReader(conn).ReadByte(); err == nil {
+		t.Error("duplicate hello was accepted")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Error("duplicate hello connection left open")
+	}
+
+	// 原连接不受影响
+	if err := server.Send("alice", NewMessage("pong", nil)); err != nil {
+		t.Fatal(err)
+	}
+	ch, _ := alice.GetClientChannel("alice")
+	select {
+	case msg := <-ch:
+		if msg.GetType() != "pong" {
+			t.Errorf("alice received %q, want pong", msg.GetType())
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("original connection no longer receives messages")
+	}
+}
@@ -27,6 +27,15 @@ type Transport interface {
 	Close() error
 }
 
+// ClientTransport 客户端侧传输接口
+type ClientTransport interface {
+	// SendToServer 发送消息到服务器
+	SendToServer(clientID string, msg Message) error
+
+	// GetClientChannel 获取客户端的接收通道，连接断开时通道关闭
+	GetClientChannel(clientID string) (chan Message, error)
+}
+
 // BaseMessage 基础消息结构
 type BaseMessage struct {
 	Type string      `json:"type"`
@@ -48,3 +57,57 @@ func NewMessage(msgType string, data interface{}) Message {
 		Data: data,
 	}
 }
+
+// keyedMessage 附带合并键的消息
+type keyedMessage struct {
+	Message
+	key   string
+	merge MergeFunc // 为空时同键的旧消息直接丢弃
+}
+
+// MergeFunc 合并同键的两条消息，pending 为发送队列中尚未发出的旧消息，返回取代两者的消息
+type MergeFunc func(pending, newer Message) Message
+
+// WithCoalesceKey 为消息附加合并键，用于只有最新值有意义的状态消息（如时间同步）
+// 发送队列中尚未发出的同键消息会被移除，新消息排在队尾，因此它与其他消息的相对顺序和不合并时相同。
+// 没有合并键的消息（如移动指令、玩家加入）不受影响，按发送顺序送达
+func WithCoalesceKey(msg Message, key string) Message {
+	if key == "" {
+		return msg
+	}
+	return &keyedMessage{Message: unwrapKey(msg), key: key}
+}
+
+// WithCoalesceMerge 为消息附加合并键和合并函数
+// 发送队列中尚未发出的同键消息不直接丢弃，而是由 merge 并入新消息，
+// 用于每条消息只携带部分状态的情况（如只包含部分玩家的批量仲裁结果）
+func WithCoalesceMerge(msg Message, key string, merge MergeFunc) Message {
+	if key == "" {
+		return msg
+	}
+	return &keyedMessage{Message: unwrapKey(msg), key: key, merge: merge}
+}
+
+// coalesceMerge 获取消息的合并函数，没有时返回空
+func coalesceMerge(msg Message) MergeFunc {
+	if keyed, ok := msg.(*keyedMessage); ok {
+		return keyed.merge
+	}
+	return nil
+}
+
+// CoalesceKey 获取消息的合并键，没有时返回空
+func CoalesceKey(msg Message) string {
+	if keyed, ok := msg.(*keyedMessage); ok {
+		return keyed.key
+	}
+	return ""
+}
+
+// unwrapKey 去掉合并键，返回原始消息
+func unwrapKey(msg Message) Message {
+	if keyed, ok := msg.(*keyedMessage); ok {
+		return keyed.Message
+	}
+	return msg
+}